@@ -50,6 +50,14 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
 		},
+		cli.IntFlag{
+			Name:  "auto-userns",
+			Usage: "map container ids 0 to N-1 to a slice of N subordinate ids not used by any other container",
+		},
+		cli.BoolFlag{
+			Name:  "device-audit",
+			Usage: "record denied device accesses, to be listed by 'runc device audit' (cgroup v2 only)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -0,0 +1,219 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/ebpf"
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+)
+
+var deviceCommand = cli.Command{
+	Name:  "device",
+	Usage: "manage device nodes of a running container",
+	Subcommands: []cli.Command{
+		{
+			Name:  "add",
+			Usage: "add a host device node to a running container",
+			ArgsUsage: `<container-id> <path>
+
+Where "<container-id>" is the name for the instance of the container and
+"<path>" is the path of the device node on the host. The device node is
+created at the same path inside the container.`,
+			Description: `The device add command creates the device node inside the container's
+mount namespace (or bind-mounts it from the host, for containers with a user
+namespace), allows access to it in the container's device cgroup, and records
+it in the container's state.`,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "permissions",
+					Value: "rwm",
+					Usage: "device cgroup permissions (a combination of r, w and m)",
+				},
+				cli.StringFlag{
+					Name:  "mode",
+					Usage: "file mode of the device node inside the container, in octal (default: same as on the host)",
+				},
+			},
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 2, exactArgs); err != nil {
+					return err
+				}
+				container, err := getContainer(context)
+				if err != nil {
+					return err
+				}
+				device, err := devices.DeviceFromPath(context.Args().Get(1), context.String("permissions"))
+				if err != nil {
+					return err
+				}
+				if !device.Permissions.IsValid() {
+					return fmt.Errorf("invalid device permissions %q", device.Permissions)
+				}
+				if mode := context.String("mode"); mode != "" {
+					m, err := strconv.ParseUint(mode, 8, 32)
+					if err != nil {
+						return fmt.Errorf("invalid value for mode: %w", err)
+					}
+					device.FileMode = os.FileMode(m)
+				}
+				// Like other device nodes, make it owned by the container's root.
+				config := container.Config()
+				uid, err := config.HostRootUID()
+				if err != nil {
+					return err
+				}
+				gid, err := config.HostRootGID()
+				if err != nil {
+					return err
+				}
+				device.Uid, device.Gid = uint32(uid), uint32(gid)
+				return container.AddDevice(device)
+			},
+		},
+		{
+			Name:  "remove",
+			Usage: "remove a device node previously added to a running container",
+			ArgsUsage: `<container-id> <path>
+
+Where "<container-id>" is the name for the instance of the container and
+"<path>" is the path of the device node inside the container.`,
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 2, exactArgs); err != nil {
+					return err
+				}
+				container, err := getContainer(context)
+				if err != nil {
+					return err
+				}
+				return container.RemoveDevice(context.Args().Get(1))
+			},
+		},
+		{
+			Name:  "audit",
+			Usage: "list the device accesses denied to a container",
+			ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+			Description: `The device audit command lists the device accesses denied to a container
+which was created with --device-audit, along with the number of times each of
+them was denied. This requires cgroup v2. The counters are reset whenever the
+container's device rules are changed.`,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format, f",
+					Value: "table",
+					Usage: `select one of: ` + formatOptions,
+				},
+			},
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 1, exactArgs); err != nil {
+					return err
+				}
+				container, err := getContainer(context)
+				if err != nil {
+					return err
+				}
+				if !cgroups.IsCgroup2UnifiedMode() {
+					return errors.New("device audit requires cgroup v2")
+				}
+				if !container.Config().Cgroups.Resources.DevicesAudit {
+					return errors.New("device audit is not enabled for the container")
+				}
+				state, err := container.State()
+				if err != nil {
+					return err
+				}
+				denied, err := readDeniedDevices(state.CgroupPaths[""])
+				if err != nil {
+					return err
+				}
+				switch context.String("format") {
+				case "table":
+					w := tabwriter.NewWriter(os.Stdout, 8, 1, 3, ' ', 0)
+					fmt.Fprint(w, "TYPE\tMAJOR\tMINOR\tACCESS\tCOUNT\n")
+					for _, d := range denied {
+						fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\n", d.Type, d.Major, d.Minor, d.Permissions, d.Count)
+					}
+					return w.Flush()
+				case "json":
+					return json.NewEncoder(os.Stdout).Encode(denied)
+				default:
+					return errors.New("invalid format option")
+				}
+			},
+		},
+	},
+}
+
+// deniedDevice is a device access which was denied to a container.
+type deniedDevice struct {
+	Type        string `json:"type"`
+	Major       uint32 `json:"major"`
+	Minor       uint32 `json:"minor"`
+	Permissions string `json:"permissions"`
+	Count       uint64 `json:"count"`
+}
+
+// readDeniedDevices returns the denied device accesses recorded by the device
+// filter attached to the given cgroup, sorted by device.
+func readDeniedDevices(path string) ([]deniedDevice, error) {
+	dirFd, err := unix.Open(path, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(dirFd)
+	audit, err := ebpf.ReadDeviceAudit(dirFd)
+	if err != nil {
+		return nil, err
+	}
+	denied := []deniedDevice{}
+	for key, count := range audit {
+		d := deniedDevice{
+			Type:  "?",
+			Major: key.Major,
+			Minor: key.Minor,
+			Count: count,
+		}
+		switch key.Type {
+		case unix.BPF_DEVCG_DEV_CHAR:
+			d.Type = string(devices.CharDevice)
+		case unix.BPF_DEVCG_DEV_BLOCK:
+			d.Type = string(devices.BlockDevice)
+		}
+		if key.Access&unix.BPF_DEVCG_ACC_READ != 0 {
+			d.Permissions += "r"
+		}
+		if key.Access&unix.BPF_DEVCG_ACC_WRITE != 0 {
+			d.Permissions += "w"
+		}
+		if key.Access&unix.BPF_DEVCG_ACC_MKNOD != 0 {
+			d.Permissions += "m"
+		}
+		denied = append(denied, d)
+	}
+	sort.Slice(denied, func(i, j int) bool {
+		a, b := denied[i], denied[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Major != b.Major {
+			return a.Major < b.Major
+		}
+		if a.Minor != b.Minor {
+			return a.Minor < b.Minor
+		}
+		return a.Permissions < b.Permissions
+	})
+	return denied, nil
+}
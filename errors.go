@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// errorCode is a class of fatal errors, as reported in the "code" field of
+// errors logged in the json log format, along with the exit status of runc.
+type errorCode struct {
+	name     string
+	exitCode int
+}
+
+var (
+	errCodeUnknown       = errorCode{"unknown", 1}
+	errCodeNotFound      = errorCode{"container-not-found", 2}
+	errCodeExists        = errorCode{"container-exists", 3}
+	errCodeInvalidID     = errorCode{"invalid-id", 4}
+	errCodeInvalidState  = errorCode{"invalid-state", 5}
+	errCodeInvalidConfig = errorCode{"invalid-config", 6}
+	errCodeCgroup        = errorCode{"cgroup-error", 7}
+	errCodeSeccomp       = errorCode{"seccomp-error", 8}
+	errCodeCriu          = errorCode{"criu-error", 9}
+	errCodeHook          = errorCode{"hook-failed", 10}
+	errCodeFileNotFound  = errorCode{"file-not-found", 11}
+	errCodePermission    = errorCode{"permission-denied", 12}
+)
+
+// errorCodes lists the sentinel errors checked for by getErrorCode, in order.
+// The container related errors come first, as these are the most specific.
+var errorCodes = []struct {
+	err  error
+	code errorCode
+}{
+	{libcontainer.ErrNotExist, errCodeNotFound},
+	{libcontainer.ErrExist, errCodeExists},
+	{libcontainer.ErrInvalidID, errCodeInvalidID},
+	{libcontainer.ErrPaused, errCodeInvalidState},
+	{libcontainer.ErrRunning, errCodeInvalidState},
+	{libcontainer.ErrNotRunning, errCodeInvalidState},
+	{libcontainer.ErrNotPaused, errCodeInvalidState},
+	{libcontainer.ErrHook, errCodeHook},
+	{libcontainer.ErrSeccomp, errCodeSeccomp},
+	{libcontainer.ErrCriu, errCodeCriu},
+	{libcontainer.ErrCgroup, errCodeCgroup},
+	{os.ErrNotExist, errCodeFileNotFound},
+	{os.ErrPermission, errCodePermission},
+}
+
+// getErrorCode returns the class of a fatal error.
+func getErrorCode(err error) errorCode {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	var cErr *libcontainer.ConfigError
+	if errors.As(err, &cErr) {
+		return errCodeInvalidConfig
+	}
+	return errCodeUnknown
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+func TestGetErrorCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code errorCode
+	}{
+		{errors.New("something failed"), errCodeUnknown},
+		{fmt.Errorf("container abc: %w", libcontainer.ErrNotExist), errCodeNotFound},
+		{libcontainer.ErrExist, errCodeExists},
+		{libcontainer.ErrInvalidID, errCodeInvalidID},
+		{libcontainer.ErrRunning, errCodeInvalidState},
+		{libcontainer.ErrNotPaused, errCodeInvalidState},
+		{&libcontainer.ConfigError{}, errCodeInvalidConfig},
+		{fmt.Errorf("unable to apply: %w", libcontainer.ErrCgroup), errCodeCgroup},
+		{libcontainer.ErrSeccomp, errCodeSeccomp},
+		{libcontainer.ErrCriu, errCodeCriu},
+		{libcontainer.ErrHook, errCodeHook},
+		{fmt.Errorf("open config.json: %w", os.ErrNotExist), errCodeFileNotFound},
+		{fmt.Errorf("open config.json: %w", os.ErrPermission), errCodePermission},
+	} {
+		if code := getErrorCode(tc.err); code != tc.code {
+			t.Errorf("%v: expected code %v, got %v", tc.err, tc.code, code)
+		}
+	}
+}
@@ -131,6 +131,8 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.CPU.Throttling.Periods = cg.CpuStats.ThrottlingData.Periods
 	s.CPU.Throttling.ThrottledPeriods = cg.CpuStats.ThrottlingData.ThrottledPeriods
 	s.CPU.Throttling.ThrottledTime = cg.CpuStats.ThrottlingData.ThrottledTime
+	s.CPU.Throttling.Bursts = cg.CpuStats.ThrottlingData.Bursts
+	s.CPU.Throttling.BurstTime = cg.CpuStats.ThrottlingData.BurstTime
 
 	s.CPUSet = types.CPUSet(cg.CPUSetStats)
 
@@ -149,6 +151,9 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.Blkio.IoMergedRecursive = convertBlkioEntry(cg.BlkioStats.IoMergedRecursive)
 	s.Blkio.IoTimeRecursive = convertBlkioEntry(cg.BlkioStats.IoTimeRecursive)
 	s.Blkio.SectorsRecursive = convertBlkioEntry(cg.BlkioStats.SectorsRecursive)
+	for _, e := range cg.BlkioStats.IoStat {
+		s.Blkio.IoStat = append(s.Blkio.IoStat, types.IoStatEntry(e))
+	}
 
 	s.Hugetlb = make(map[string]types.Hugetlb)
 	for k, v := range cg.HugetlbStats {
@@ -172,6 +177,14 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 		if intelrdt.IsCMTEnabled() {
 			s.IntelRdt.CMTStats = is.CMTStats
 		}
+		if len(is.CacheInfo) > 0 {
+			s.IntelRdt.CacheInfo = make(map[string]*types.L3CacheInfo)
+			for resource, info := range is.CacheInfo {
+				s.IntelRdt.CacheInfo[resource] = convertL3CacheInfo(info)
+			}
+		}
+		s.IntelRdt.SchemataRoot = is.SchemataRoot
+		s.IntelRdt.Schemata = is.Schemata
 	}
 
 	s.NetworkInterfaces = ls.Interfaces
@@ -180,9 +193,12 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 
 func convertHugtlb(c cgroups.HugetlbStats) types.Hugetlb {
 	return types.Hugetlb{
-		Usage:   c.Usage,
-		Max:     c.MaxUsage,
-		Failcnt: c.Failcnt,
+		Usage:       c.Usage,
+		Max:         c.MaxUsage,
+		Failcnt:     c.Failcnt,
+		RsvdUsage:   c.RsvdUsage,
+		RsvdMax:     c.RsvdMaxUsage,
+		RsvdFailcnt: c.RsvdFailcnt,
 	}
 }
 
@@ -0,0 +1,51 @@
+// +build linux
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+)
+
+// features describes the features supported by runc and the running kernel.
+type features struct {
+	// OCIVersionMin and OCIVersionMax are the range of supported
+	// runtime-spec versions.
+	OCIVersionMin string `json:"ociVersionMin"`
+	OCIVersionMax string `json:"ociVersionMax"`
+
+	// MountOptions are the mount options which are not passed to the
+	// filesystem as data.
+	MountOptions []string `json:"mountOptions"`
+
+	// RecursiveMountAttributes reports whether the recursive mount
+	// options (such as "rro") can be used, i.e. whether the kernel
+	// supports mount_setattr(2).
+	RecursiveMountAttributes bool `json:"recursiveMountAttributes"`
+}
+
+var featuresCommand = cli.Command{
+	Name:  "features",
+	Usage: "show the enabled features",
+	Description: `The features command outputs the features supported by runc and the
+running kernel, in a JSON format.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		feat := features{
+			OCIVersionMin:            "1.0.0",
+			OCIVersionMax:            specs.Version,
+			MountOptions:             specconv.KnownMountOptions(),
+			RecursiveMountAttributes: system.HaveMountSetattr(),
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "    ")
+		return enc.Encode(feat)
+	},
+}
@@ -3,9 +3,19 @@
 package cgroups
 
 import (
+	"errors"
+
 	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
+// ErrKillNotSupported is returned by Manager.Kill when cgroup.kill
+// is not available.
+var ErrKillNotSupported = errors.New("cgroup.kill is not supported")
+
+// ErrHugetlbRsvdNotSupported is returned when setting a hugetlb reservation
+// limit, and hugetlb reservation accounting (Linux 5.7+) is not available.
+var ErrHugetlbRsvdNotSupported = errors.New("hugetlb reservation accounting is not supported")
+
 type Manager interface {
 	// Apply creates a cgroup, if not yet created, and adds a process
 	// with the specified pid into that cgroup.  A special value of -1
@@ -25,6 +35,11 @@ type Manager interface {
 	// Freeze sets the freezer cgroup to the specified state.
 	Freeze(state configs.FreezerState) error
 
+	// Kill kills all the processes inside the cgroup and all its
+	// sub-cgroups, using cgroup.kill (cgroup v2, since Linux 5.14).
+	// It returns ErrKillNotSupported if cgroup.kill is not available.
+	Kill() error
+
 	// Destroy removes cgroup.
 	Destroy() error
 
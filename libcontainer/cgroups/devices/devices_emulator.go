@@ -298,6 +298,19 @@ func EmulatorFromList(list io.Reader) (*Emulator, error) {
 	return e, nil
 }
 
+// EmulatorFromRules returns a new Emulator that represents the state of a
+// devices cgroup after applying the given rules in order, starting from a
+// white-list (deny all) cgroup.
+func EmulatorFromRules(rules []*devices.Rule) (*Emulator, error) {
+	e := new(Emulator)
+	for _, rule := range rules {
+		if err := e.Apply(*rule); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
 // Transition calculates what is the minimally-disruptive set of rules need to
 // be applied to a devices cgroup in order to transition to the given target.
 // This means that any already-existing rules will not be applied, and
@@ -1112,3 +1112,33 @@ func TestDeviceEmulatorTransitionFromBlacklist(t *testing.T) {
 func TestDeviceEmulatorTransitionFromWhitelist(t *testing.T) {
 	testDeviceEmulatorTransition(t, false)
 }
+
+func TestDeviceEmulatorFromRules(t *testing.T) {
+	rules := []*devices.Rule{
+		// Deny everything, then allow a couple of devices.
+		{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm", Allow: false},
+		{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+		{Type: devices.CharDevice, Major: 10, Minor: devices.Wildcard, Permissions: "rw", Allow: true},
+		// Partially revoke a previous rule.
+		{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "m", Allow: false},
+	}
+	expected := &Emulator{
+		defaultAllow: false,
+		rules: deviceRules{
+			{node: devices.CharDevice, major: 1, minor: 3}:                 devices.Permissions("rw"),
+			{node: devices.CharDevice, major: 10, minor: devices.Wildcard}: devices.Permissions("rw"),
+		},
+	}
+
+	emu, err := EmulatorFromRules(rules)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(emu, expected) {
+		t.Errorf("emulator state mismatch: %#v != %#v", emu, expected)
+	}
+
+	if _, err := EmulatorFromRules([]*devices.Rule{{Type: devices.FifoDevice, Permissions: "rwm"}}); err == nil {
+		t.Errorf("expected error for non-cgroup rule type")
+	}
+}
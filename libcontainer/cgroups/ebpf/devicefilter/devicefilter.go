@@ -21,20 +21,47 @@ import (
 const (
 	// license string format is same as kernel MODULE_LICENSE macro
 	license = "Apache"
+
+	// AuditMapName is the name of the map the programs generated by
+	// DeviceFilterAudit record denied accesses to. The instructions loading
+	// the map reference it by this name, and have to be rewritten (using
+	// asm.Instructions.RewriteMapPtr) before the program is loaded.
+	//
+	// The map is a hash map (of any kind) with AuditKey keys and uint64
+	// values counting the denied accesses.
+	AuditMapName = "runc_dev_audit"
 )
 
+// AuditKey is the key of the audit map, describing a denied device access.
+// The fields are in the bpf_cgroup_dev_ctx format, i.e. Type is one of
+// BPF_DEVCG_DEV_* and Access is a mask of BPF_DEVCG_ACC_*.
+type AuditKey struct {
+	Type   uint32
+	Access uint32
+	Major  uint32
+	Minor  uint32
+}
+
 // DeviceFilter returns eBPF device filter program and its license string
 func DeviceFilter(rules []*devices.Rule) (asm.Instructions, string, error) {
+	return deviceFilter(rules, false)
+}
+
+// DeviceFilterAudit is like DeviceFilter, except the returned program also
+// records every denied access in the map named AuditMapName.
+func DeviceFilterAudit(rules []*devices.Rule) (asm.Instructions, string, error) {
+	return deviceFilter(rules, true)
+}
+
+func deviceFilter(rules []*devices.Rule, audit bool) (asm.Instructions, string, error) {
 	// Generate the minimum ruleset for the device rules we are given. While we
 	// don't care about minimum transitions in cgroupv2, using the emulator
 	// gives us a guarantee that the behaviour of devices filtering is the same
 	// as cgroupv1, including security hardenings to avoid misconfiguration
 	// (such as punching holes in wildcard rules).
-	emu := new(devicesemulator.Emulator)
-	for _, rule := range rules {
-		if err := emu.Apply(*rule); err != nil {
-			return nil, "", err
-		}
+	emu, err := devicesemulator.EmulatorFromRules(rules)
+	if err != nil {
+		return nil, "", err
 	}
 	cleanRules, err := emu.Rules()
 	if err != nil {
@@ -43,6 +70,7 @@ func DeviceFilter(rules []*devices.Rule) (asm.Instructions, string, error) {
 
 	p := &program{
 		defaultAllow: emu.IsBlacklist(),
+		audit:        audit,
 	}
 	p.init()
 
@@ -72,6 +100,7 @@ func DeviceFilter(rules []*devices.Rule) (asm.Instructions, string, error) {
 type program struct {
 	insts        asm.Instructions
 	defaultAllow bool
+	audit        bool
 	blockID      int
 }
 
@@ -174,7 +203,7 @@ func (p *program) appendRule(rule *devices.Rule) error {
 			asm.JNE.Imm(asm.R5, int32(rule.Minor), nextBlockSym),
 		)
 	}
-	p.insts = append(p.insts, acceptBlock(rule.Allow)...)
+	p.insts = append(p.insts, p.acceptBlock(rule.Allow)...)
 	// set blockSym to the first instruction we added in this iteration
 	p.insts[prevBlockLastIdx+1] = p.insts[prevBlockLastIdx+1].Sym(blockSym)
 	p.blockID++
@@ -182,21 +211,24 @@ func (p *program) appendRule(rule *devices.Rule) error {
 }
 
 func (p *program) finalize() (asm.Instructions, error) {
-	var v int32
-	if p.defaultAllow {
-		v = 1
-	}
 	blockSym := "block-" + strconv.Itoa(p.blockID)
-	p.insts = append(p.insts,
-		// R0 <- v
-		asm.Mov.Imm32(asm.R0, v).Sym(blockSym),
-		asm.Return(),
-	)
+	block := p.acceptBlock(p.defaultAllow)
+	block[0] = block[0].Sym(blockSym)
+	p.insts = append(p.insts, block...)
+	if p.audit {
+		p.insts = append(p.insts, auditBlock()...)
+	}
 	p.blockID = -1
 	return p.insts, nil
 }
 
-func acceptBlock(accept bool) asm.Instructions {
+func (p *program) acceptBlock(accept bool) asm.Instructions {
+	if !accept && p.audit {
+		return []asm.Instruction{
+			// goto deny
+			asm.Ja.Label(auditSym),
+		}
+	}
 	var v int32
 	if accept {
 		v = 1
@@ -207,3 +239,53 @@ func acceptBlock(accept bool) asm.Instructions {
 		asm.Return(),
 	}
 }
+
+const (
+	auditSym       = "deny"
+	auditInsertSym = "deny-insert"
+	auditReturnSym = "deny-return"
+)
+
+// auditBlock returns the instructions recording the access described by the
+// registers R2-R5 (see init) in the audit map, and rejecting it.
+func auditBlock() asm.Instructions {
+	// The map fd is filled in by RewriteMapPtr; -1 makes loading the
+	// program fail if that did not happen.
+	loadMap := asm.Instruction{
+		OpCode:    asm.LoadImmOp(asm.DWord),
+		Dst:       asm.R1,
+		Src:       asm.PseudoMapFD,
+		Constant:  int64(uint32(math.MaxUint32)),
+		Reference: AuditMapName,
+	}
+	return []asm.Instruction{
+		// struct AuditKey at R10-16
+		asm.StoreMem(asm.R10, -16, asm.R2, asm.Word).Sym(auditSym),
+		asm.StoreMem(asm.R10, -12, asm.R3, asm.Word),
+		asm.StoreMem(asm.R10, -8, asm.R4, asm.Word),
+		asm.StoreMem(asm.R10, -4, asm.R5, asm.Word),
+		// R0 <- map_lookup_elem(map, &key)
+		loadMap,
+		asm.Mov.Reg(asm.R2, asm.R10),
+		asm.Add.Imm(asm.R2, -16),
+		asm.FnMapLookupElem.Call(),
+		// if (R0 == NULL) goto insert
+		asm.JEq.Imm(asm.R0, 0, auditInsertSym),
+		// *R0 += 1 (atomically)
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Ja.Label(auditReturnSym),
+		// map_update_elem(map, &key, &1, BPF_ANY), with value at R10-24
+		asm.StoreImm(asm.R10, -24, 1, asm.DWord).Sym(auditInsertSym),
+		loadMap,
+		asm.Mov.Reg(asm.R2, asm.R10),
+		asm.Add.Imm(asm.R2, -16),
+		asm.Mov.Reg(asm.R3, asm.R10),
+		asm.Add.Imm(asm.R3, -24),
+		asm.Mov.Imm(asm.R4, unix.BPF_ANY),
+		asm.FnMapUpdateElem.Call(),
+		// R0 <- 0
+		asm.Mov.Imm32(asm.R0, 0).Sym(auditReturnSym),
+		asm.Return(),
+	}
+}
@@ -258,3 +258,77 @@ block-1:
 `
 	testDeviceFilter(t, devices, expected)
 }
+
+func TestDeviceFilterAudit(t *testing.T) {
+	devices := []*devices.Rule{
+		{
+			Type:        'a',
+			Major:       -1,
+			Minor:       -1,
+			Permissions: "rwm",
+			Allow:       true,
+		},
+		{
+			Type:        'b',
+			Major:       8,
+			Minor:       2,
+			Permissions: "rwm",
+			Allow:       false,
+		},
+	}
+	expected := `
+// load parameters into registers
+         0: LdXMemW dst: r2 src: r1 off: 0 imm: 0
+         1: And32Imm dst: r2 imm: 65535
+         2: LdXMemW dst: r3 src: r1 off: 0 imm: 0
+         3: RSh32Imm dst: r3 imm: 16
+         4: LdXMemW dst: r4 src: r1 off: 4 imm: 0
+         5: LdXMemW dst: r5 src: r1 off: 8 imm: 0
+block-0:
+// goto deny if type==b && major == 8 && minor == 2
+         6: JNEImm dst: r2 off: -1 imm: 1 <block-1>
+         7: JNEImm dst: r4 off: -1 imm: 8 <block-1>
+         8: JNEImm dst: r5 off: -1 imm: 2 <block-1>
+         9: JaImm dst: r0 off: -1 imm: 0 <deny>
+block-1:
+// return 1 (accept)
+        10: Mov32Imm dst: r0 imm: 1
+        11: Exit
+deny:
+// store the key on the stack
+        12: StXMemW dst: rfp src: r2 off: -16 imm: 0
+        13: StXMemW dst: rfp src: r3 off: -12 imm: 0
+        14: StXMemW dst: rfp src: r4 off: -8 imm: 0
+        15: StXMemW dst: rfp src: r5 off: -4 imm: 0
+// increment the counter if the key is in the map
+        16: LoadMapPtr dst: r1 fd: -1 <runc_dev_audit>
+        18: MovReg dst: r2 src: rfp
+        19: AddImm dst: r2 imm: -16
+        20: Call FnMapLookupElem
+        21: JEqImm dst: r0 off: -1 imm: 0 <deny-insert>
+        22: MovImm dst: r1 imm: 1
+        23: StXXAddDW dst: r0 src: r1
+        24: JaImm dst: r0 off: -1 imm: 0 <deny-return>
+deny-insert:
+// otherwise insert it with a counter of 1
+        25: StMemDW dst: rfp src: r0 off: -24 imm: 1
+        26: LoadMapPtr dst: r1 fd: -1 <runc_dev_audit>
+        28: MovReg dst: r2 src: rfp
+        29: AddImm dst: r2 imm: -16
+        30: MovReg dst: r3 src: rfp
+        31: AddImm dst: r3 imm: -24
+        32: MovImm dst: r4 imm: 0
+        33: Call FnMapUpdateElem
+deny-return:
+// return 0 (reject)
+        34: Mov32Imm dst: r0 imm: 0
+        35: Exit
+`
+	insts, _, err := DeviceFilterAudit(devices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed, expectedHashed := hash(insts.String(), "//"), hash(expected, "//"); hashed != expectedHashed {
+		t.Fatalf("expected:\n%q\ngot\n%q", expectedHashed, hashed)
+	}
+}
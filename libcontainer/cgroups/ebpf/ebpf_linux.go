@@ -11,6 +11,7 @@ import (
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/asm"
 	"github.com/cilium/ebpf/link"
+	"github.com/opencontainers/runc/libcontainer/cgroups/ebpf/devicefilter"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
@@ -74,6 +75,130 @@ func findAttachedCgroupDeviceFilters(dirFd int) ([]*ebpf.Program, error) {
 	return nil, errors.New("could not get complete list of CGROUP_DEVICE programs")
 }
 
+// auditMapMaxEntries is the number of distinct denied accesses kept in the
+// device audit map. The least recently denied ones are evicted first.
+const auditMapMaxEntries = 1024
+
+// programMapIDs returns the IDs of the maps used by the program.
+//
+// TODO: use ProgramInfo.MapIDs once we update to cilium/ebpf >= 0.7.0
+func programMapIDs(prog *ebpf.Program) ([]ebpf.MapID, error) {
+	// struct bpf_prog_info, up to map_ids.
+	type bpfProgInfo struct {
+		Type            uint32
+		ID              uint32
+		Tag             [unix.BPF_TAG_SIZE]byte
+		JitedProgLen    uint32
+		XlatedProgLen   uint32
+		JitedProgInsns  uint64 // __aligned_u64
+		XlatedProgInsns uint64 // __aligned_u64
+		LoadTime        uint64
+		CreatedByUID    uint32
+		NrMapIds        uint32
+		MapIds          uint64 // __aligned_u64
+	}
+	type bpfAttrObjInfo struct {
+		BpfFd   uint32
+		InfoLen uint32
+		Info    uint64 // __aligned_u64
+	}
+
+	getInfo := func(info *bpfProgInfo) error {
+		attr := bpfAttrObjInfo{
+			BpfFd:   uint32(prog.FD()),
+			InfoLen: uint32(unsafe.Sizeof(*info)),
+			Info:    uint64(uintptr(unsafe.Pointer(info))),
+		}
+		_, _, errno := unix.Syscall(unix.SYS_BPF,
+			uintptr(unix.BPF_OBJ_GET_INFO_BY_FD),
+			uintptr(unsafe.Pointer(&attr)),
+			unsafe.Sizeof(attr))
+		runtime.KeepAlive(info)
+		if errno != 0 {
+			return fmt.Errorf("bpf_obj_get_info_by_fd failed: %w", errno)
+		}
+		return nil
+	}
+
+	// The first call only gets the number of maps.
+	var info bpfProgInfo
+	if err := getInfo(&info); err != nil {
+		return nil, err
+	}
+	if info.NrMapIds == 0 {
+		return nil, nil
+	}
+	mapIds := make([]uint32, info.NrMapIds)
+	info = bpfProgInfo{
+		NrMapIds: uint32(len(mapIds)),
+		MapIds:   uint64(uintptr(unsafe.Pointer(&mapIds[0]))),
+	}
+	err := getInfo(&info)
+	runtime.KeepAlive(mapIds)
+	if err != nil {
+		return nil, err
+	}
+	if int(info.NrMapIds) < len(mapIds) {
+		mapIds = mapIds[:info.NrMapIds]
+	}
+	ids := make([]ebpf.MapID, len(mapIds))
+	for i, id := range mapIds {
+		ids[i] = ebpf.MapID(id)
+	}
+	return ids, nil
+}
+
+// ReadDeviceAudit returns the number of times each device access has been
+// denied by the device filter programs attached to the cgroup, as recorded
+// in their audit maps (see devicefilter.DeviceFilterAudit). Programs without
+// an audit map are ignored.
+//
+// The counters are lost whenever a new device filter program is attached.
+func ReadDeviceAudit(dirFd int) (map[devicefilter.AuditKey]uint64, error) {
+	progs, err := findAttachedCgroupDeviceFilters(dirFd)
+	if err != nil {
+		return nil, err
+	}
+	denied := make(map[devicefilter.AuditKey]uint64)
+	for _, prog := range progs {
+		ids, err := programMapIDs(prog)
+		prog.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if err := readAuditMap(id, denied); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return denied, nil
+}
+
+func readAuditMap(id ebpf.MapID, denied map[devicefilter.AuditKey]uint64) error {
+	m, err := ebpf.NewMapFromID(id)
+	if err != nil {
+		return fmt.Errorf("cannot fetch map from id: %w", err)
+	}
+	defer m.Close()
+	info, err := m.Info()
+	if err != nil {
+		return err
+	}
+	if info.Name != devicefilter.AuditMapName {
+		return nil
+	}
+	var (
+		key   devicefilter.AuditKey
+		count uint64
+	)
+	iter := m.Iterate()
+	for iter.Next(&key, &count) {
+		denied[key] += count
+	}
+	return iter.Err()
+}
+
 var (
 	haveBpfProgReplaceBool bool
 	haveBpfProgReplaceOnce sync.Once
@@ -151,6 +276,26 @@ func LoadAttachCgroupDeviceFilter(insts asm.Instructions, license string, dirFd
 	}
 	useReplaceProg := haveBpfProgReplace() && len(oldProgs) == 1
 
+	// Create the audit map if the program records denied accesses. The
+	// program holds a reference to it, so it lives as long as the program.
+	if _, ok := insts.ReferenceOffsets()[devicefilter.AuditMapName]; ok {
+		auditMap, err := ebpf.NewMap(&ebpf.MapSpec{
+			Name:       devicefilter.AuditMapName,
+			Type:       ebpf.LRUHash,
+			KeySize:    uint32(unsafe.Sizeof(devicefilter.AuditKey{})),
+			ValueSize:  uint32(unsafe.Sizeof(uint64(0))),
+			MaxEntries: auditMapMaxEntries,
+		})
+		if err != nil {
+			return nilCloser, fmt.Errorf("failed to create device audit map: %w", err)
+		}
+		defer auditMap.Close()
+		insts = append(asm.Instructions(nil), insts...)
+		if err := insts.RewriteMapPtr(devicefilter.AuditMapName, auditMap.FD()); err != nil {
+			return nilCloser, err
+		}
+	}
+
 	// Generate new program.
 	spec := &ebpf.ProgramSpec{
 		Type:         ebpf.CGroupDevice,
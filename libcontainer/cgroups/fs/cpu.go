@@ -81,6 +81,26 @@ func (s *CpuGroup) Set(path string, r *configs.Resources) error {
 			return err
 		}
 	}
+	if r.CpuBurst != nil {
+		if err := cgroups.WriteFile(path, "cpu.cfs_burst_us", strconv.FormatUint(*r.CpuBurst, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CpuIdle != nil {
+		if err := cgroups.WriteFile(path, "cpu.idle", strconv.FormatInt(*r.CpuIdle, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CpuUclampMin != "" {
+		if err := cgroups.WriteFile(path, "cpu.uclamp.min", r.CpuUclampMin); err != nil {
+			return err
+		}
+	}
+	if r.CpuUclampMax != "" {
+		if err := cgroups.WriteFile(path, "cpu.uclamp.max", r.CpuUclampMax); err != nil {
+			return err
+		}
+	}
 	return s.SetRtSched(path, r)
 }
 
@@ -110,6 +130,12 @@ func (s *CpuGroup) GetStats(path string, stats *cgroups.Stats) error {
 
 		case "throttled_time":
 			stats.CpuStats.ThrottlingData.ThrottledTime = v
+
+		case "nr_bursts":
+			stats.CpuStats.ThrottlingData.Bursts = v
+
+		case "burst_time":
+			stats.CpuStats.ThrottlingData.BurstTime = v
 		}
 	}
 	return nil
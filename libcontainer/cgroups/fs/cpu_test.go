@@ -103,6 +103,45 @@ func TestCpuSetBandWidth(t *testing.T) {
 	}
 }
 
+func TestCpuSetBurstIdleUclamp(t *testing.T) {
+	helper := NewCgroupTestUtil("cpu", t)
+	defer helper.cleanup()
+
+	helper.writeFileContents(map[string]string{
+		"cpu.cfs_burst_us": "0",
+		"cpu.idle":         "0",
+		"cpu.uclamp.min":   "0.00",
+		"cpu.uclamp.max":   "max",
+	})
+
+	burst := uint64(20000)
+	idle := int64(1)
+	r := helper.CgroupData.config.Resources
+	r.CpuBurst = &burst
+	r.CpuIdle = &idle
+	r.CpuUclampMin = "10.50"
+	r.CpuUclampMax = "80"
+	cpu := &CpuGroup{}
+	if err := cpu.Set(helper.CgroupPath, r); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"cpu.cfs_burst_us": "20000",
+		"cpu.idle":         "1",
+		"cpu.uclamp.min":   "10.50",
+		"cpu.uclamp.max":   "80",
+	} {
+		value, err := fscommon.GetCgroupParamString(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %s to be %q, got %q", file, expected, value)
+		}
+	}
+}
+
 func TestCpuStats(t *testing.T) {
 	helper := NewCgroupTestUtil("cpu", t)
 	defer helper.cleanup()
@@ -111,10 +150,12 @@ func TestCpuStats(t *testing.T) {
 		nrPeriods     = 2000
 		nrThrottled   = 200
 		throttledTime = uint64(18446744073709551615)
+		nrBursts      = 20
+		burstTime     = 400000
 	)
 
-	cpuStatContent := fmt.Sprintf("nr_periods %d\nnr_throttled %d\nthrottled_time %d\n",
-		nrPeriods, nrThrottled, throttledTime)
+	cpuStatContent := fmt.Sprintf("nr_periods %d\nnr_throttled %d\nthrottled_time %d\nnr_bursts %d\nburst_time %d\n",
+		nrPeriods, nrThrottled, throttledTime, nrBursts, burstTime)
 	helper.writeFileContents(map[string]string{
 		"cpu.stat": cpuStatContent,
 	})
@@ -130,6 +171,8 @@ func TestCpuStats(t *testing.T) {
 		Periods:          nrPeriods,
 		ThrottledPeriods: nrThrottled,
 		ThrottledTime:    throttledTime,
+		Bursts:           nrBursts,
+		BurstTime:        burstTime,
 	}
 
 	expectThrottlingDataEquals(t, expectedStats, actualStats.CpuStats.ThrottlingData)
@@ -424,6 +424,10 @@ func (m *manager) Exists() bool {
 	return cgroups.PathExists(m.Path("devices"))
 }
 
+func (m *manager) Kill() error {
+	return cgroups.ErrKillNotSupported
+}
+
 func OOMKillCount(path string) (uint64, error) {
 	return fscommon.GetValueByKey(path, "memory.oom_control", "oom_kill")
 }
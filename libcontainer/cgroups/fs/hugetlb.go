@@ -3,6 +3,10 @@
 package fs
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -22,7 +26,17 @@ func (s *HugetlbGroup) Apply(path string, d *cgroupData) error {
 
 func (s *HugetlbGroup) Set(path string, r *configs.Resources) error {
 	for _, hugetlb := range r.HugetlbLimit {
-		if err := cgroups.WriteFile(path, "hugetlb."+hugetlb.Pagesize+".limit_in_bytes", strconv.FormatUint(hugetlb.Limit, 10)); err != nil {
+		prefix := "hugetlb." + hugetlb.Pagesize
+		if err := cgroups.WriteFile(path, prefix+".limit_in_bytes", strconv.FormatUint(hugetlb.Limit, 10)); err != nil {
+			return err
+		}
+		if hugetlb.RsvdLimit == nil {
+			continue
+		}
+		if !hugetlbRsvdSupported(path, hugetlb.Pagesize) {
+			return fmt.Errorf("unable to set %s hugetlb reservation limit: %w", hugetlb.Pagesize, cgroups.ErrHugetlbRsvdNotSupported)
+		}
+		if err := cgroups.WriteFile(path, prefix+".rsvd.limit_in_bytes", strconv.FormatUint(*hugetlb.RsvdLimit, 10)); err != nil {
 			return err
 		}
 	}
@@ -30,33 +44,42 @@ func (s *HugetlbGroup) Set(path string, r *configs.Resources) error {
 	return nil
 }
 
+// hugetlbRsvdSupported checks whether the hugetlb cgroup at path supports
+// reservation accounting (Linux 5.7+).
+func hugetlbRsvdSupported(path, pageSize string) bool {
+	_, err := os.Stat(filepath.Join(path, "hugetlb."+pageSize+".rsvd.limit_in_bytes"))
+	return err == nil
+}
+
 func (s *HugetlbGroup) GetStats(path string, stats *cgroups.Stats) error {
-	hugetlbStats := cgroups.HugetlbStats{}
 	if !cgroups.PathExists(path) {
 		return nil
 	}
 	for _, pageSize := range HugePageSizes {
-		usage := "hugetlb." + pageSize + ".usage_in_bytes"
-		value, err := fscommon.GetCgroupParamUint(path, usage)
-		if err != nil {
-			return err
+		hugetlbStats := cgroups.HugetlbStats{}
+		prefix := "hugetlb." + pageSize
+		for _, f := range []struct {
+			file  string
+			value *uint64
+			rsvd  bool
+		}{
+			{prefix + ".usage_in_bytes", &hugetlbStats.Usage, false},
+			{prefix + ".max_usage_in_bytes", &hugetlbStats.MaxUsage, false},
+			{prefix + ".failcnt", &hugetlbStats.Failcnt, false},
+			{prefix + ".rsvd.usage_in_bytes", &hugetlbStats.RsvdUsage, true},
+			{prefix + ".rsvd.max_usage_in_bytes", &hugetlbStats.RsvdMaxUsage, true},
+			{prefix + ".rsvd.failcnt", &hugetlbStats.RsvdFailcnt, true},
+		} {
+			value, err := fscommon.GetCgroupParamUint(path, f.file)
+			if err != nil {
+				// Reservation accounting is only available since Linux 5.7.
+				if f.rsvd && errors.Is(err, os.ErrNotExist) {
+					break
+				}
+				return err
+			}
+			*f.value = value
 		}
-		hugetlbStats.Usage = value
-
-		maxUsage := "hugetlb." + pageSize + ".max_usage_in_bytes"
-		value, err = fscommon.GetCgroupParamUint(path, maxUsage)
-		if err != nil {
-			return err
-		}
-		hugetlbStats.MaxUsage = value
-
-		failcnt := "hugetlb." + pageSize + ".failcnt"
-		value, err = fscommon.GetCgroupParamUint(path, failcnt)
-		if err != nil {
-			return err
-		}
-		hugetlbStats.Failcnt = value
-
 		stats.HugetlbStats[pageSize] = hugetlbStats
 	}
 
@@ -3,6 +3,7 @@
 package fs
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -23,6 +24,11 @@ const (
 	limit    = "hugetlb.%s.limit_in_bytes"
 	maxUsage = "hugetlb.%s.max_usage_in_bytes"
 	failcnt  = "hugetlb.%s.failcnt"
+
+	rsvdUsage    = "hugetlb.%s.rsvd.usage_in_bytes"
+	rsvdLimit    = "hugetlb.%s.rsvd.limit_in_bytes"
+	rsvdMaxUsage = "hugetlb.%s.rsvd.max_usage_in_bytes"
+	rsvdFailcnt  = "hugetlb.%s.rsvd.failcnt"
 )
 
 func TestHugetlbSetHugetlb(t *testing.T) {
@@ -88,6 +94,73 @@ func TestHugetlbStats(t *testing.T) {
 	}
 }
 
+func TestHugetlbSetRsvd(t *testing.T) {
+	helper := NewCgroupTestUtil("hugetlb", t)
+	defer helper.cleanup()
+
+	for _, pageSize := range HugePageSizes {
+		rsvd := uint64(1024)
+		r := &configs.Resources{
+			HugetlbLimit: []*configs.HugepageLimit{
+				{
+					Pagesize:  pageSize,
+					Limit:     2048,
+					RsvdLimit: &rsvd,
+				},
+			},
+		}
+		hugetlb := &HugetlbGroup{}
+		// No reservation accounting support.
+		err := hugetlb.Set(helper.CgroupPath, r)
+		if !errors.Is(err, cgroups.ErrHugetlbRsvdNotSupported) {
+			t.Fatalf("expected ErrHugetlbRsvdNotSupported, got %v", err)
+		}
+
+		helper.writeFileContents(map[string]string{
+			fmt.Sprintf(rsvdLimit, pageSize): "0",
+		})
+		if err := hugetlb.Set(helper.CgroupPath, r); err != nil {
+			t.Fatal(err)
+		}
+		value, err := fscommon.GetCgroupParamUint(helper.CgroupPath, fmt.Sprintf(rsvdLimit, pageSize))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != rsvd {
+			t.Fatalf("Set hugetlb.rsvd.limit_in_bytes failed. Expected: %v, Got: %v", rsvd, value)
+		}
+	}
+}
+
+func TestHugetlbStatsRsvd(t *testing.T) {
+	helper := NewCgroupTestUtil("hugetlb", t)
+	defer helper.cleanup()
+	for _, pageSize := range HugePageSizes {
+		helper.writeFileContents(map[string]string{
+			fmt.Sprintf(usage, pageSize):        hugetlbUsageContents,
+			fmt.Sprintf(maxUsage, pageSize):     hugetlbMaxUsageContents,
+			fmt.Sprintf(failcnt, pageSize):      hugetlbFailcnt,
+			fmt.Sprintf(rsvdUsage, pageSize):    "64\n",
+			fmt.Sprintf(rsvdMaxUsage, pageSize): "512\n",
+			fmt.Sprintf(rsvdFailcnt, pageSize):  "3\n",
+		})
+	}
+
+	hugetlb := &HugetlbGroup{}
+	actualStats := *cgroups.NewStats()
+	err := hugetlb.GetStats(helper.CgroupPath, &actualStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStats := cgroups.HugetlbStats{
+		Usage: 128, MaxUsage: 256, Failcnt: 100,
+		RsvdUsage: 64, RsvdMaxUsage: 512, RsvdFailcnt: 3,
+	}
+	for _, pageSize := range HugePageSizes {
+		expectHugetlbStatEquals(t, expectedStats, actualStats.HugetlbStats[pageSize])
+	}
+}
+
 func TestHugetlbStatsNoUsageFile(t *testing.T) {
 	helper := NewCgroupTestUtil("hugetlb", t)
 	defer helper.cleanup()
@@ -111,13 +111,28 @@ func setMemoryAndSwap(path string, r *configs.Resources) error {
 }
 
 func (s *MemoryGroup) Set(path string, r *configs.Resources) error {
-	if err := setMemoryAndSwap(path, r); err != nil {
+	if r.MemorySwapMax != nil {
+		// Emulate the cgroup v2 swap limit using memory+swap one.
+		memorySwap, err := cgroups.ConvertMemorySwapMaxToCgroupV1Value(*r.MemorySwapMax, r.Memory)
+		if err != nil {
+			return err
+		}
+		res := *r
+		res.MemorySwap = memorySwap
+		if err := setMemoryAndSwap(path, &res); err != nil {
+			return err
+		}
+	} else if err := setMemoryAndSwap(path, r); err != nil {
 		return err
 	}
 
 	// ignore KernelMemory and KernelMemoryTCP
 
-	if r.MemoryReservation != 0 {
+	if r.MemoryLow != nil {
+		if err := cgroups.WriteFile(path, "memory.soft_limit_in_bytes", strconv.FormatInt(*r.MemoryLow, 10)); err != nil {
+			return err
+		}
+	} else if r.MemoryReservation != 0 {
 		if err := cgroups.WriteFile(path, "memory.soft_limit_in_bytes", strconv.FormatInt(r.MemoryReservation, 10)); err != nil {
 			return err
 		}
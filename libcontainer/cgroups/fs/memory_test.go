@@ -107,6 +107,49 @@ func TestMemorySetMemoryswap(t *testing.T) {
 	}
 }
 
+func TestMemorySetSwapMax(t *testing.T) {
+	helper := NewCgroupTestUtil("memory", t)
+	defer helper.cleanup()
+
+	const (
+		memoryLimit = 314572800 // 300M
+		swapMax     = 209715200 // 200M
+		lowest      = 104857600 // 100M
+	)
+
+	helper.writeFileContents(map[string]string{
+		"memory.limit_in_bytes":       "0",
+		"memory.memsw.limit_in_bytes": "0",
+		"memory.soft_limit_in_bytes":  "0",
+	})
+
+	swap, low := int64(swapMax), int64(lowest)
+	helper.CgroupData.config.Resources.Memory = memoryLimit
+	helper.CgroupData.config.Resources.MemorySwapMax = &swap
+	helper.CgroupData.config.Resources.MemoryLow = &low
+	memory := &MemoryGroup{}
+	if err := memory.Set(helper.CgroupPath, helper.CgroupData.config.Resources); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]uint64{
+		"memory.limit_in_bytes":       memoryLimit,
+		"memory.memsw.limit_in_bytes": memoryLimit + swapMax,
+		"memory.soft_limit_in_bytes":  lowest,
+	} {
+		value, err := fscommon.GetCgroupParamUint(helper.CgroupPath, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Errorf("expected %s to be %d, got %d", file, expected, value)
+		}
+	}
+	if helper.CgroupData.config.Resources.MemorySwap != 0 {
+		t.Error("expected the resources not to be modified")
+	}
+}
+
 func TestMemorySetMemoryLargerThanSwap(t *testing.T) {
 	helper := NewCgroupTestUtil("memory", t)
 	defer helper.cleanup()
@@ -13,7 +13,8 @@ import (
 )
 
 func isCpuSet(r *configs.Resources) bool {
-	return r.CpuWeight != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0
+	return r.CpuWeight != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0 ||
+		r.CpuBurst != nil || r.CpuIdle != nil || r.CpuUclampMin != "" || r.CpuUclampMax != ""
 }
 
 func setCpu(dirPath string, r *configs.Resources) error {
@@ -44,6 +45,26 @@ func setCpu(dirPath string, r *configs.Resources) error {
 			return err
 		}
 	}
+	if r.CpuBurst != nil {
+		if err := cgroups.WriteFile(dirPath, "cpu.max.burst", strconv.FormatUint(*r.CpuBurst, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CpuIdle != nil {
+		if err := cgroups.WriteFile(dirPath, "cpu.idle", strconv.FormatInt(*r.CpuIdle, 10)); err != nil {
+			return err
+		}
+	}
+	if r.CpuUclampMin != "" {
+		if err := cgroups.WriteFile(dirPath, "cpu.uclamp.min", r.CpuUclampMin); err != nil {
+			return err
+		}
+	}
+	if r.CpuUclampMax != "" {
+		if err := cgroups.WriteFile(dirPath, "cpu.uclamp.max", r.CpuUclampMax); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -80,6 +101,12 @@ func statCpu(dirPath string, stats *cgroups.Stats) error {
 
 		case "throttled_usec":
 			stats.CpuStats.ThrottlingData.ThrottledTime = v * 1000
+
+		case "nr_bursts":
+			stats.CpuStats.ThrottlingData.Bursts = v
+
+		case "burst_usec":
+			stats.CpuStats.ThrottlingData.BurstTime = v * 1000
 		}
 	}
 	if err := sc.Err(); err != nil {
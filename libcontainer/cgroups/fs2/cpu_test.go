@@ -0,0 +1,51 @@
+package fs2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+const exampleCpuStatData = `usage_usec 1000000
+user_usec 600000
+system_usec 400000
+nr_periods 2000
+nr_throttled 200
+throttled_usec 30000
+nr_bursts 20
+burst_usec 4000`
+
+func TestStatCpu(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	dir, err := ioutil.TempDir("", "runc-stat-cpu-test.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "cpu.stat"), []byte(exampleCpuStatData), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cgroups.NewStats()
+	if err := statCpu(dir, stats); err != nil {
+		t.Fatal(err)
+	}
+	expected := cgroups.ThrottlingData{
+		Periods:          2000,
+		ThrottledPeriods: 200,
+		ThrottledTime:    30000000,
+		Bursts:           20,
+		BurstTime:        4000000,
+	}
+	if stats.CpuStats.ThrottlingData != expected {
+		t.Errorf("expected throttling data %+v, got %+v", expected, stats.CpuStats.ThrottlingData)
+	}
+	if stats.CpuStats.CpuUsage.TotalUsage != 1000000000 {
+		t.Errorf("expected total usage 1000000000, got %d", stats.CpuStats.CpuUsage.TotalUsage)
+	}
+}
@@ -0,0 +1,70 @@
+package fs2
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// delegateListFile contains the names of cgroup files to be owned by the
+// delegatee (in addition to the cgroup directory itself).
+// See "Delegation Containment" in Documentation/admin-guide/cgroup-v2.rst.
+var delegateListFile = "/sys/kernel/cgroup/delegate"
+
+// delegateFiles returns the list of cgroup files to chown on delegation.
+func delegateFiles() []string {
+	data, err := ioutil.ReadFile(delegateListFile)
+	if err != nil {
+		// The file is only available since Linux 4.15.
+		return []string{"cgroup.procs", "cgroup.threads", "cgroup.subtree_control"}
+	}
+	return strings.Fields(string(data))
+}
+
+// Delegate prepares the cgroup at dirPath for delegation, as described by d.
+// It moves the process pid (which must be the only process in the cgroup)
+// to a leaf sub-cgroup, enables the requested controllers for the cgroup
+// children, and makes both cgroups owned by the delegatee.
+func Delegate(dirPath string, pid int, d *configs.CgroupDelegate) error {
+	leaf := filepath.Join(dirPath, d.LeafName())
+	if err := os.Mkdir(leaf, 0o755); err != nil && !os.IsExist(err) {
+		return err
+	}
+	// Due to the "no internal processes" rule, the process must be moved
+	// out before any domain controllers can be enabled.
+	if err := cgroups.WriteCgroupProc(leaf, pid); err != nil {
+		return err
+	}
+	ctrs := d.Controllers
+	if len(ctrs) == 0 {
+		content, err := cgroups.ReadFile(dirPath, "cgroup.controllers")
+		if err != nil {
+			return err
+		}
+		ctrs = strings.Fields(content)
+	}
+	for _, ctr := range ctrs {
+		if err := cgroups.WriteFile(dirPath, "cgroup.subtree_control", "+"+ctr); err != nil {
+			return fmt.Errorf("unable to enable %s controller for delegation: %w", ctr, err)
+		}
+	}
+	files := delegateFiles()
+	for _, dir := range []string{dirPath, leaf} {
+		if err := os.Chown(dir, d.UID, d.GID); err != nil {
+			return err
+		}
+		for _, file := range files {
+			err := os.Chown(filepath.Join(dir, file), d.UID, d.GID)
+			// Files of controllers which are not enabled do not exist.
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
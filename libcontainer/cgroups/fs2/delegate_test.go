@@ -0,0 +1,78 @@
+package fs2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestDelegate(t *testing.T) {
+	cgroups.TestMode = true
+	dir, err := ioutil.TempDir("", "fs2-delegate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	listFile := filepath.Join(dir, "delegate")
+	if err := ioutil.WriteFile(listFile, []byte("cgroup.procs\ncgroup.subtree_control\nmemory.reclaim\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(f string) { delegateListFile = f }(delegateListFile)
+	delegateListFile = listFile
+
+	cg := filepath.Join(dir, "container")
+	leaf := filepath.Join(cg, "payload")
+	if err := os.MkdirAll(leaf, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, data := range map[string]string{
+		filepath.Join(cg, "cgroup.controllers"):     "cpu memory pids",
+		filepath.Join(cg, "cgroup.subtree_control"): "",
+		filepath.Join(cg, "cgroup.procs"):           "",
+		filepath.Join(leaf, "cgroup.procs"):         "",
+	} {
+		if err := ioutil.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uid, gid := os.Getuid(), os.Getgid()
+	d := &configs.CgroupDelegate{
+		Controllers: []string{"memory"},
+		Leaf:        "payload",
+		UID:         uid,
+		GID:         gid,
+	}
+	if err := Delegate(cg, 1234, d); err != nil {
+		t.Fatal(err)
+	}
+
+	if procs, err := cgroups.ReadFile(leaf, "cgroup.procs"); err != nil {
+		t.Fatal(err)
+	} else if procs != "1234" {
+		t.Errorf("expected pid to be moved to the leaf, got %q", procs)
+	}
+	if ctrs, err := cgroups.ReadFile(cg, "cgroup.subtree_control"); err != nil {
+		t.Fatal(err)
+	} else if ctrs != "+memory" {
+		t.Errorf("expected memory controller to be enabled, got %q", ctrs)
+	}
+
+	// Without controllers, all the available ones are enabled (our fake
+	// cgroup.subtree_control only keeps the last write).
+	d.Controllers = nil
+	if err := Delegate(cg, 1234, d); err != nil {
+		t.Fatal(err)
+	}
+	if ctrs, err := cgroups.ReadFile(cg, "cgroup.subtree_control"); err != nil {
+		t.Fatal(err)
+	} else if !strings.HasSuffix(ctrs, "+pids") {
+		t.Errorf("expected pids controller to be enabled, got %q", ctrs)
+	}
+}
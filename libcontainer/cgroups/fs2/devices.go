@@ -59,7 +59,11 @@ func setDevices(dirPath string, r *configs.Resources) error {
 	if r.SkipDevices {
 		return nil
 	}
-	insts, license, err := devicefilter.DeviceFilter(r.Devices)
+	filter := devicefilter.DeviceFilter
+	if r.DevicesAudit {
+		filter = devicefilter.DeviceFilterAudit
+	}
+	insts, license, err := filter(r.Devices)
 	if err != nil {
 		return err
 	}
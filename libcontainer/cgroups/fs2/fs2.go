@@ -85,6 +85,9 @@ func (m *manager) Apply(pid int) error {
 		}
 		return err
 	}
+	if m.config.Delegate != nil {
+		return Delegate(m.dirPath, pid, m.config.Delegate)
+	}
 	if err := cgroups.WriteCgroupProc(m.dirPath, pid); err != nil {
 		return err
 	}
@@ -139,6 +142,24 @@ func (m *manager) Freeze(state configs.FreezerState) error {
 	return nil
 }
 
+func (m *manager) Kill() error {
+	return Kill(m.dirPath)
+}
+
+// Kill kills all the processes in the cgroup at dirPath (including the ones
+// in its sub-cgroups) by writing to cgroup.kill. A cgroup that does not
+// exist has no processes to kill.
+func Kill(dirPath string) error {
+	err := cgroups.WriteFile(dirPath, "cgroup.kill", "1")
+	if errors.Is(err, os.ErrNotExist) {
+		if cgroups.PathExists(dirPath) {
+			return cgroups.ErrKillNotSupported
+		}
+		return nil
+	}
+	return err
+}
+
 func (m *manager) Destroy() error {
 	return cgroups.RemovePath(m.dirPath)
 }
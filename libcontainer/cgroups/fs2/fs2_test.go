@@ -0,0 +1,33 @@
+package fs2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+func TestKill(t *testing.T) {
+	cgroups.TestMode = true
+	dir, err := ioutil.TempDir("", "fs2-kill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Kill(dir); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := cgroups.ReadFile(dir, "cgroup.kill"); err != nil {
+		t.Fatal(err)
+	} else if value != "1" {
+		t.Fatalf("expected cgroup.kill to be written 1, got %q", value)
+	}
+
+	// A removed cgroup has no processes to kill.
+	if err := Kill(filepath.Join(dir, "removed")); err != nil {
+		t.Fatalf("expected no error for non-existent cgroup, got %v", err)
+	}
+}
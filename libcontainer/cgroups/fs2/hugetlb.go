@@ -3,7 +3,10 @@
 package fs2
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -20,7 +23,17 @@ func setHugeTlb(dirPath string, r *configs.Resources) error {
 		return nil
 	}
 	for _, hugetlb := range r.HugetlbLimit {
-		if err := cgroups.WriteFile(dirPath, "hugetlb."+hugetlb.Pagesize+".max", strconv.FormatUint(hugetlb.Limit, 10)); err != nil {
+		prefix := "hugetlb." + hugetlb.Pagesize
+		if err := cgroups.WriteFile(dirPath, prefix+".max", strconv.FormatUint(hugetlb.Limit, 10)); err != nil {
+			return err
+		}
+		if hugetlb.RsvdLimit == nil {
+			continue
+		}
+		if !hugetlbRsvdSupported(dirPath, hugetlb.Pagesize) {
+			return fmt.Errorf("unable to set %s hugetlb reservation limit: %w", hugetlb.Pagesize, cgroups.ErrHugetlbRsvdNotSupported)
+		}
+		if err := cgroups.WriteFile(dirPath, prefix+".rsvd.max", strconv.FormatUint(*hugetlb.RsvdLimit, 10)); err != nil {
 			return err
 		}
 	}
@@ -28,14 +41,20 @@ func setHugeTlb(dirPath string, r *configs.Resources) error {
 	return nil
 }
 
+// hugetlbRsvdSupported checks whether the cgroup at dirPath supports hugetlb
+// reservation accounting (Linux 5.7+).
+func hugetlbRsvdSupported(dirPath, pagesize string) bool {
+	_, err := os.Stat(filepath.Join(dirPath, "hugetlb."+pagesize+".rsvd.max"))
+	return err == nil
+}
+
 func statHugeTlb(dirPath string, stats *cgroups.Stats) error {
 	hugePageSizes, err := cgroups.GetHugePageSize()
 	if err != nil {
 		return fmt.Errorf("failed to fetch hugetlb info: %w", err)
 	}
-	hugetlbStats := cgroups.HugetlbStats{}
-
 	for _, pagesize := range hugePageSizes {
+		hugetlbStats := cgroups.HugetlbStats{}
 		value, err := fscommon.GetCgroupParamUint(dirPath, "hugetlb."+pagesize+".current")
 		if err != nil {
 			return err
@@ -49,6 +68,14 @@ func statHugeTlb(dirPath string, stats *cgroups.Stats) error {
 		}
 		hugetlbStats.Failcnt = value
 
+		// Reservation accounting is only available since Linux 5.7.
+		value, err = fscommon.GetCgroupParamUint(dirPath, "hugetlb."+pagesize+".rsvd.current")
+		if err == nil {
+			hugetlbStats.RsvdUsage = value
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
 		stats.HugetlbStats[pagesize] = hugetlbStats
 	}
 
@@ -0,0 +1,54 @@
+package fs2
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestSetHugeTlbRsvd(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	dir, err := ioutil.TempDir("", "runc-set-hugetlb-test.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rsvd := uint64(1 << 20)
+	r := &configs.Resources{
+		HugetlbLimit: []*configs.HugepageLimit{
+			{Pagesize: "2MB", Limit: 2 << 20, RsvdLimit: &rsvd},
+		},
+	}
+	// No reservation accounting support.
+	if err := setHugeTlb(dir, r); !errors.Is(err, cgroups.ErrHugetlbRsvdNotSupported) {
+		t.Fatalf("expected ErrHugetlbRsvdNotSupported, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "hugetlb.2MB.rsvd.max"), []byte("max\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setHugeTlb(dir, r); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"hugetlb.2MB.max":      "2097152",
+		"hugetlb.2MB.rsvd.max": "1048576",
+	} {
+		value, err := cgroups.ReadFile(dir, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(value) != expected {
+			t.Errorf("expected %s to be %s, got %s", file, expected, value)
+		}
+	}
+}
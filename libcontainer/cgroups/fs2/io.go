@@ -7,10 +7,11 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
@@ -22,7 +23,29 @@ func isIoSet(r *configs.Resources) bool {
 		len(r.BlkioThrottleReadBpsDevice) > 0 ||
 		len(r.BlkioThrottleWriteBpsDevice) > 0 ||
 		len(r.BlkioThrottleReadIOPSDevice) > 0 ||
-		len(r.BlkioThrottleWriteIOPSDevice) > 0
+		len(r.BlkioThrottleWriteIOPSDevice) > 0 ||
+		r.IoWeight != 0 ||
+		len(r.IoWeightDevice) > 0 ||
+		len(r.IoMax) > 0 ||
+		len(r.IoLatency) > 0 ||
+		len(r.IoCostQos) > 0
+}
+
+// ioDeviceString returns the major:minor numbers of the device, resolving its
+// path if set.
+func ioDeviceString(d *configs.IoDevice) (string, error) {
+	if d.Path == "" {
+		return fmt.Sprintf("%d:%d", d.Major, d.Minor), nil
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(d.Path, &st); err != nil {
+		return "", &os.PathError{Op: "stat", Path: d.Path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return "", fmt.Errorf("%s is not a block device", d.Path)
+	}
+	rdev := uint64(st.Rdev) //nolint:unconvert // Rdev is uint32 on e.g. MIPS.
+	return fmt.Sprintf("%d:%d", unix.Major(rdev), unix.Minor(rdev)), nil
 }
 
 // bfqDeviceWeightSupported checks for per-device BFQ weight support (added
@@ -61,7 +84,7 @@ func setIo(dirPath string, r *configs.Resources) error {
 			if _, err := bfq.WriteString(strconv.FormatUint(uint64(r.BlkioWeight), 10)); err != nil {
 				return err
 			}
-		} else {
+		} else if r.IoWeight == 0 {
 			// Fallback to io.weight with a conversion scheme.
 			v := cgroups.ConvertBlkIOToIOWeightValue(r.BlkioWeight)
 			if err := cgroups.WriteFile(dirPath, "io.weight", strconv.FormatUint(v, 10)); err != nil {
@@ -97,6 +120,49 @@ func setIo(dirPath string, r *configs.Resources) error {
 		}
 	}
 
+	if r.IoWeight != 0 {
+		if err := cgroups.WriteFile(dirPath, "io.weight", "default "+strconv.FormatUint(r.IoWeight, 10)); err != nil {
+			return err
+		}
+	}
+	for _, wd := range r.IoWeightDevice {
+		dev, err := ioDeviceString(&wd.IoDevice)
+		if err != nil {
+			return err
+		}
+		if err := cgroups.WriteFile(dirPath, "io.weight", wd.String(dev)); err != nil {
+			return err
+		}
+	}
+	for _, m := range r.IoMax {
+		dev, err := ioDeviceString(&m.IoDevice)
+		if err != nil {
+			return err
+		}
+		if err := cgroups.WriteFile(dirPath, "io.max", m.String(dev)); err != nil {
+			return err
+		}
+	}
+	for _, l := range r.IoLatency {
+		dev, err := ioDeviceString(&l.IoDevice)
+		if err != nil {
+			return err
+		}
+		if err := cgroups.WriteFile(dirPath, "io.latency", l.String(dev)); err != nil {
+			return err
+		}
+	}
+	// io.cost.qos only exists in the root cgroup.
+	for _, q := range r.IoCostQos {
+		dev, err := ioDeviceString(&q.IoDevice)
+		if err != nil {
+			return err
+		}
+		if err := cgroups.WriteFile(UnifiedMountpoint, "io.cost.qos", q.String(dev)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -144,6 +210,7 @@ func statIo(dirPath string, stats *cgroups.Stats) error {
 			return &parseError{Path: dirPath, File: file, Err: err}
 		}
 
+		ioStat := cgroups.IoStatEntry{Major: major, Minor: minor}
 		for _, item := range v {
 			d := strings.Split(item, "=")
 			if len(d) != 2 {
@@ -151,6 +218,29 @@ func statIo(dirPath string, stats *cgroups.Stats) error {
 			}
 			op := d[0]
 
+			// Record the raw values.
+			var rawValue *uint64
+			switch op {
+			case "rbytes":
+				rawValue = &ioStat.Rbytes
+			case "wbytes":
+				rawValue = &ioStat.Wbytes
+			case "rios":
+				rawValue = &ioStat.Rios
+			case "wios":
+				rawValue = &ioStat.Wios
+			case "dbytes":
+				rawValue = &ioStat.Dbytes
+			case "dios":
+				rawValue = &ioStat.Dios
+			}
+			if rawValue != nil {
+				*rawValue, err = strconv.ParseUint(d[1], 10, 64)
+				if err != nil {
+					return &parseError{Path: dirPath, File: file, Err: err}
+				}
+			}
+
 			// Map to the cgroupv1 naming and layout (in separate tables).
 			var targetTable *[]cgroups.BlkioStatEntry
 			switch op {
@@ -169,10 +259,8 @@ func statIo(dirPath string, stats *cgroups.Stats) error {
 				op = "Write"
 				targetTable = &parsedStats.IoServicedRecursive
 			default:
-				// Skip over entries we cannot map to cgroupv1 stats for now.
-				// In the future we should expand the stats struct to include
-				// them.
-				logrus.Debugf("cgroupv2 io stats: skipping over unmappable %s entry", item)
+				// Entries which cannot be mapped to cgroupv1 stats are
+				// only reported in IoStat.
 				continue
 			}
 
@@ -189,7 +277,15 @@ func statIo(dirPath string, stats *cgroups.Stats) error {
 			}
 			*targetTable = append(*targetTable, entry)
 		}
+		parsedStats.IoStat = append(parsedStats.IoStat, ioStat)
 	}
+	sort.Slice(parsedStats.IoStat, func(i, j int) bool {
+		a, b := parsedStats.IoStat[i], parsedStats.IoStat[j]
+		if a.Major != b.Major {
+			return a.Major < b.Major
+		}
+		return a.Minor < b.Minor
+	})
 	stats.BlkioStats = parsedStats
 	return nil
 }
@@ -6,9 +6,12 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
 )
 
 const exampleIoStatData = `254:1 rbytes=6901432320 wbytes=14245535744 rios=263278 wios=248603 dbytes=0 dios=0
@@ -32,6 +35,11 @@ var exampleIoStatsParsed = cgroups.BlkioStats{
 		{Major: 259, Minor: 0, Value: 264538, Op: "Read"},
 		{Major: 259, Minor: 0, Value: 244914, Op: "Write"},
 	},
+	IoStat: []cgroups.IoStatEntry{
+		{Major: 254, Minor: 0, Rbytes: 2702336, Rios: 97},
+		{Major: 254, Minor: 1, Rbytes: 6901432320, Wbytes: 14245535744, Rios: 263278, Wios: 248603},
+		{Major: 259, Minor: 0, Rbytes: 6911345664, Wbytes: 14245536256, Rios: 264538, Wios: 244914, Dbytes: 530485248, Dios: 2},
+	},
 }
 
 func lessBlkioStatEntry(a, b cgroups.BlkioStatEntry) bool {
@@ -84,3 +92,55 @@ func TestStatIo(t *testing.T) {
 		t.Errorf("parsed cgroupv2 io.stat doesn't match expected result: \ngot %#v\nexpected %#v\n", gotStats.BlkioStats, exampleIoStatsParsed)
 	}
 }
+
+func TestSetIo(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	fakeCgroupDir, err := ioutil.TempDir("", "runc-set-io-test.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fakeCgroupDir)
+
+	// A block device node, to be resolved to its major:minor numbers.
+	devPath := filepath.Join(fakeCgroupDir, "blkdev")
+	if err := unix.Mknod(devPath, unix.S_IFBLK|0o600, int(unix.Mkdev(7, 3))); err != nil {
+		t.Skipf("Test requires privileges to create a block device node: %v", err)
+	}
+
+	r := &configs.Resources{
+		IoWeight: 50,
+		IoMax: []*configs.IoMaxDevice{
+			{IoDevice: configs.IoDevice{Path: devPath}, Rbps: 1048576, Wiops: -1},
+		},
+		IoLatency: []*configs.IoLatencyDevice{
+			{IoDevice: configs.IoDevice{Major: 8, Minor: 0}, Target: 2000},
+		},
+	}
+	if err := setIo(fakeCgroupDir, r); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"io.weight":  "default 50",
+		"io.max":     "7:3 rbps=1048576 wiops=max",
+		"io.latency": "8:0 target=2000",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(fakeCgroupDir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := strings.TrimSpace(string(data)); value != expected {
+			t.Errorf("expected %s to be %q, got %q", file, expected, value)
+		}
+	}
+
+	r = &configs.Resources{
+		IoMax: []*configs.IoMaxDevice{
+			{IoDevice: configs.IoDevice{Path: "/dev/null"}, Rbps: 1048576},
+		},
+	}
+	if err := setIo(fakeCgroupDir, r); err == nil {
+		t.Error("expected an error for a character device")
+	}
+}
@@ -35,7 +35,9 @@ func numToStr(value int64) (ret string) {
 }
 
 func isMemorySet(r *configs.Resources) bool {
-	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0
+	return r.MemoryReservation != 0 || r.Memory != 0 || r.MemorySwap != 0 ||
+		r.MemoryHigh != nil || r.MemoryLow != nil || r.MemoryMin != nil ||
+		r.MemorySwapMax != nil || r.MemoryOomGroup != nil
 }
 
 func setMemory(dirPath string, r *configs.Resources) error {
@@ -51,6 +53,12 @@ func setMemory(dirPath string, r *configs.Resources) error {
 		// memory and memorySwap set to the same value -- disable swap
 		swapStr = "0"
 	}
+	if r.MemorySwapMax != nil {
+		swapStr = strconv.FormatInt(*r.MemorySwapMax, 10)
+		if *r.MemorySwapMax == -1 {
+			swapStr = "max"
+		}
+	}
 	// never write empty string to `memory.swap.max`, it means set to 0.
 	if swapStr != "" {
 		if err := cgroups.WriteFile(dirPath, "memory.swap.max", swapStr); err != nil {
@@ -66,8 +74,38 @@ func setMemory(dirPath string, r *configs.Resources) error {
 
 	// cgroup.Resources.KernelMemory is ignored
 
-	if val := numToStr(r.MemoryReservation); val != "" {
-		if err := cgroups.WriteFile(dirPath, "memory.low", val); err != nil {
+	low := r.MemoryLow
+	if low == nil && r.MemoryReservation != 0 {
+		low = &r.MemoryReservation
+	}
+	for _, knob := range []struct {
+		file  string
+		value *int64
+	}{
+		{"memory.low", low},
+		{"memory.min", r.MemoryMin},
+		{"memory.high", r.MemoryHigh},
+	} {
+		if knob.value == nil {
+			continue
+		}
+		// Unlike with numToStr, 0 is written, to remove the protection
+		// (memory.high is never 0, as the validator rejects it).
+		val := strconv.FormatInt(*knob.value, 10)
+		if *knob.value == -1 {
+			val = "max"
+		}
+		if err := cgroups.WriteFile(dirPath, knob.file, val); err != nil {
+			return err
+		}
+	}
+
+	if r.MemoryOomGroup != nil {
+		val := "0"
+		if *r.MemoryOomGroup {
+			val = "1"
+		}
+		if err := cgroups.WriteFile(dirPath, "memory.oom.group", val); err != nil {
 			return err
 		}
 	}
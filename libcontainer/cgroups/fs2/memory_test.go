@@ -0,0 +1,70 @@
+package fs2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestSetMemory(t *testing.T) {
+	// We're using a fake cgroupfs.
+	cgroups.TestMode = true
+
+	dir, err := ioutil.TempDir("", "runc-set-memory-test.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	swapMax, low, min, high := int64(0), int64(16<<20), int64(8<<20), int64(-1)
+	oomGroup := true
+	r := &configs.Resources{
+		Memory:            1 << 30,
+		MemoryReservation: 1 << 20,
+		MemoryLow:         &low,
+		MemoryMin:         &min,
+		MemoryHigh:        &high,
+		MemorySwapMax:     &swapMax,
+		MemoryOomGroup:    &oomGroup,
+	}
+	if err := setMemory(dir, r); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"memory.max":       "1073741824",
+		"memory.swap.max":  "0",
+		"memory.low":       "16777216",
+		"memory.min":       "8388608",
+		"memory.high":      "max",
+		"memory.oom.group": "1",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := strings.TrimSpace(string(data)); value != expected {
+			t.Errorf("expected %s to be %q, got %q", file, expected, value)
+		}
+	}
+
+	// Explicit zeros remove the protections.
+	zero := int64(0)
+	if err := setMemory(dir, &configs.Resources{MemoryLow: &zero, MemoryMin: &zero}); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"memory.low", "memory.min"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := strings.TrimSpace(string(data)); value != "0" {
+			t.Errorf("expected %s to be reset to 0, got %q", file, value)
+		}
+	}
+}
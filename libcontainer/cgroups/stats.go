@@ -9,6 +9,11 @@ type ThrottlingData struct {
 	ThrottledPeriods uint64 `json:"throttled_periods,omitempty"`
 	// Aggregate time the container was throttled for in nanoseconds.
 	ThrottledTime uint64 `json:"throttled_time,omitempty"`
+	// Number of periods when the container used more than its quota, using
+	// its burst.
+	Bursts uint64 `json:"bursts,omitempty"`
+	// Aggregate time the container used in excess of its quota in nanoseconds.
+	BurstTime uint64 `json:"burst_time,omitempty"`
 }
 
 // CpuUsage denotes the usage of a CPU.
@@ -135,6 +140,21 @@ type BlkioStats struct {
 	IoMergedRecursive       []BlkioStatEntry `json:"io_merged_recursive,omitempty"`
 	IoTimeRecursive         []BlkioStatEntry `json:"io_time_recursive,omitempty"`
 	SectorsRecursive        []BlkioStatEntry `json:"sectors_recursive,omitempty"`
+
+	// cgroup v2 only, io.stat values per device
+	IoStat []IoStatEntry `json:"io_stat,omitempty"`
+}
+
+// IoStatEntry holds the io.stat values of a device.
+type IoStatEntry struct {
+	Major  uint64 `json:"major,omitempty"`
+	Minor  uint64 `json:"minor,omitempty"`
+	Rbytes uint64 `json:"rbytes"`
+	Wbytes uint64 `json:"wbytes"`
+	Rios   uint64 `json:"rios"`
+	Wios   uint64 `json:"wios"`
+	Dbytes uint64 `json:"dbytes"`
+	Dios   uint64 `json:"dios"`
 }
 
 type HugetlbStats struct {
@@ -144,6 +164,12 @@ type HugetlbStats struct {
 	MaxUsage uint64 `json:"max_usage,omitempty"`
 	// number of times hugetlb usage allocation failure.
 	Failcnt uint64 `json:"failcnt"`
+	// current hugetlb reservation usage (Linux 5.7+).
+	RsvdUsage uint64 `json:"rsvd_usage,omitempty"`
+	// maximum reservation usage ever recorded (cgroup v1 only).
+	RsvdMaxUsage uint64 `json:"rsvd_max_usage,omitempty"`
+	// number of times hugetlb reservation failed (cgroup v1 only).
+	RsvdFailcnt uint64 `json:"rsvd_failcnt,omitempty"`
 }
 
 type Stats struct {
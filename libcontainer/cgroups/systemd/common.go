@@ -17,6 +17,7 @@ import (
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/sirupsen/logrus"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	cgroupdevices "github.com/opencontainers/runc/libcontainer/cgroups/devices"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/devices"
@@ -311,6 +312,41 @@ func getUnitName(c *configs.Cgroup) string {
 	return c.Name
 }
 
+// unitProperties returns the description and dependency properties of
+// the unit, as set by c.Unit.
+func unitProperties(c *configs.Cgroup) []systemdDbus.Property {
+	u := c.Unit
+	if u == nil {
+		u = &configs.SystemdUnit{}
+	}
+	desc := u.Description
+	if desc == "" {
+		desc = "libcontainer container " + c.Name
+	}
+	properties := []systemdDbus.Property{systemdDbus.PropDescription(desc)}
+
+	deps := []struct {
+		prop  func(...string) systemdDbus.Property
+		units []string
+	}{
+		{systemdDbus.PropAfter, u.After},
+		{systemdDbus.PropBefore, u.Before},
+		{systemdDbus.PropWants, u.Wants},
+		{systemdDbus.PropRequires, u.Requires},
+		{systemdDbus.PropBindsTo, u.BindsTo},
+		{func(units ...string) systemdDbus.Property { return newProp("PartOf", units) }, u.PartOf},
+	}
+	for _, dep := range deps {
+		if len(dep.units) > 0 {
+			properties = append(properties, dep.prop(dep.units...))
+		}
+	}
+	if u.CollectMode != "" {
+		properties = append(properties, newProp("CollectMode", u.CollectMode))
+	}
+	return properties
+}
+
 // isDbusError returns true if the error is a specific dbus error.
 func isDbusError(err error, name string) bool {
 	if err != nil {
@@ -343,6 +379,9 @@ func startUnit(cm *dbusConnManager, unitName string, properties []systemdDbus.Pr
 			// Please refer to https://pkg.go.dev/github.com/coreos/go-systemd/v22/dbus#Conn.StartUnit
 			if s != "done" {
 				resetFailedUnit(cm, unitName)
+				if s == "dependency" {
+					return fmt.Errorf("error creating systemd unit `%s`: a dependency of the unit failed", unitName)
+				}
 				return fmt.Errorf("error creating systemd unit `%s`: got `%s`", unitName, s)
 			}
 		case <-timeout.C:
@@ -408,6 +447,30 @@ func getManagerProperty(cm *dbusConnManager, name string) (string, error) {
 	return strconv.Unquote(str)
 }
 
+// UnitProperties returns all the properties of the systemd unit of the
+// cgroup c, as reported by systemd, connecting to it using mode (ConnAuto by
+// default). If rootless is true, the user instance of systemd is queried.
+func UnitProperties(c *configs.Cgroup, rootless bool, mode ...ConnMode) (map[string]interface{}, error) {
+	var (
+		cm       = newDbusConnManager(rootless, mode...)
+		unitName = getUnitName(c)
+		props    map[string]interface{}
+	)
+	err := cm.retryOnDisconnect(func(c *systemdDbus.Conn) error {
+		var err error
+		props, err = c.GetAllPropertiesContext(context.TODO(), unitName)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get properties of unit %q: %w", unitName, err)
+	}
+	// systemd reports properties of units it can't find, too.
+	if props["LoadState"] == "not-found" {
+		return nil, fmt.Errorf("unit %q not found", unitName)
+	}
+	return props, nil
+}
+
 func systemdVersion(cm *dbusConnManager) int {
 	versionOnce.Do(func() {
 		version = -1
@@ -490,7 +553,7 @@ func addCpuset(cm *dbusConnManager, props *[]systemdDbus.Property, cpus, mems st
 	}
 
 	if cpus != "" {
-		bits, err := RangeToBits(cpus)
+		bits, err := cgroups.RangeToBits(cpus)
 		if err != nil {
 			return fmt.Errorf("resources.CPU.Cpus=%q conversion error: %w",
 				cpus, err)
@@ -499,7 +562,7 @@ func addCpuset(cm *dbusConnManager, props *[]systemdDbus.Property, cpus, mems st
 			newProp("AllowedCPUs", bits))
 	}
 	if mems != "" {
-		bits, err := RangeToBits(mems)
+		bits, err := cgroups.RangeToBits(mems)
 		if err != nil {
 			return fmt.Errorf("resources.CPU.Mems=%q conversion error: %w",
 				mems, err)
@@ -4,10 +4,12 @@ package systemd
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
 	dbus "github.com/godbus/dbus/v5"
+	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -17,10 +19,64 @@ var (
 	dbusRootless bool
 )
 
-type dbusConnManager struct{}
+// ConnMode is the way systemd cgroup managers connect to systemd.
+type ConnMode int
 
-// newDbusConnManager initializes systemd dbus connection manager.
-func newDbusConnManager(rootless bool) *dbusConnManager {
+const (
+	// ConnAuto connects via D-Bus if possible, and directly to the
+	// systemd private socket otherwise.
+	ConnAuto ConnMode = iota
+	// ConnDbus connects via the system bus, or the session bus of the
+	// user for the systemd user instance (rootless).
+	ConnDbus
+	// ConnPrivate connects directly to the systemd private socket,
+	// /run/systemd/private, or $XDG_RUNTIME_DIR/systemd/private for the
+	// systemd user instance (rootless), so no D-Bus daemon is needed.
+	// Only the user running systemd (root for the system instance) can
+	// connect to it.
+	ConnPrivate
+)
+
+func (m ConnMode) String() string {
+	switch m {
+	case ConnAuto:
+		return "auto"
+	case ConnDbus:
+		return "dbus"
+	case ConnPrivate:
+		return "private"
+	}
+	return fmt.Sprintf("ConnMode(%d)", int(m))
+}
+
+// ParseConnMode parses the string representation of a ConnMode
+// ("auto", "dbus" or "private").
+func ParseConnMode(s string) (ConnMode, error) {
+	for _, m := range []ConnMode{ConnAuto, ConnDbus, ConnPrivate} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return ConnAuto, fmt.Errorf("invalid systemd connection mode %q (must be auto, dbus or private)", s)
+}
+
+// CheckConnection checks whether a connection to systemd (or, if rootless is
+// true, to the systemd user instance) can be established using mode, which
+// defaults to ConnAuto.
+func CheckConnection(rootless bool, mode ...ConnMode) error {
+	_, err := newDbusConnManager(rootless, mode...).getConnection()
+	return err
+}
+
+type dbusConnManager struct {
+	// mode is the way to connect to systemd. The connection is shared by
+	// all managers, so it is the mode of the one which establishes it.
+	mode ConnMode
+}
+
+// newDbusConnManager initializes systemd dbus connection manager. Only the
+// first mode is used, if any; it defaults to ConnAuto.
+func newDbusConnManager(rootless bool, mode ...ConnMode) *dbusConnManager {
 	dbusMu.Lock()
 	defer dbusMu.Unlock()
 	if dbusInited && rootless != dbusRootless {
@@ -28,7 +84,11 @@ func newDbusConnManager(rootless bool) *dbusConnManager {
 	}
 	dbusInited = true
 	dbusRootless = rootless
-	return &dbusConnManager{}
+	d := &dbusConnManager{}
+	if len(mode) > 0 {
+		d.mode = mode[0]
+	}
+	return d
 }
 
 // getConnection lazily initializes and returns systemd dbus connection.
@@ -61,10 +121,36 @@ func (d *dbusConnManager) getConnection() (*systemdDbus.Conn, error) {
 }
 
 func (d *dbusConnManager) newConnection() (*systemdDbus.Conn, error) {
+	switch d.mode {
+	case ConnDbus:
+		return newBusConnection()
+	case ConnPrivate:
+		return newPrivateConnection()
+	}
+	conn, err := newBusConnection()
+	if err == nil {
+		return conn, nil
+	}
+	conn, privErr := newPrivateConnection()
+	if privErr != nil {
+		return nil, fmt.Errorf("unable to connect to systemd via D-Bus (%v), nor via its private socket: %w", err, privErr)
+	}
+	logrus.Debugf("unable to connect to systemd via D-Bus (%v), using its private socket", err)
+	return conn, nil
+}
+
+func newBusConnection() (*systemdDbus.Conn, error) {
 	if dbusRootless {
 		return newUserSystemdDbus()
 	}
-	return systemdDbus.NewWithContext(context.TODO())
+	return systemdDbus.NewSystemConnectionContext(context.TODO())
+}
+
+func newPrivateConnection() (*systemdDbus.Conn, error) {
+	if dbusRootless {
+		return newUserSystemdPrivate()
+	}
+	return systemdDbus.NewSystemdConnectionContext(context.TODO())
 }
 
 // resetConnection resets the connection to its initial state
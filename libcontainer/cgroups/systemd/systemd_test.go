@@ -40,6 +40,56 @@ func TestSystemdVersion(t *testing.T) {
 	}
 }
 
+func TestParseConnMode(t *testing.T) {
+	for _, mode := range []ConnMode{ConnAuto, ConnDbus, ConnPrivate} {
+		m, err := ParseConnMode(mode.String())
+		if err != nil {
+			t.Errorf("ParseConnMode(%s); want nil; got %v", mode, err)
+		}
+		if m != mode {
+			t.Errorf("ParseConnMode(%s); want %d; got %d", mode, mode, m)
+		}
+	}
+	if _, err := ParseConnMode("socket"); err == nil {
+		t.Error("ParseConnMode(socket); wanted failure; got nil")
+	}
+}
+
+func TestUnitProperties(t *testing.T) {
+	c := &configs.Cgroup{Name: "test"}
+	props := unitProperties(c)
+	if len(props) != 1 || props[0].Name != "Description" || props[0].Value.Value() != "libcontainer container test" {
+		t.Fatalf("unexpected default unit properties: %+v", props)
+	}
+
+	c.Unit = &configs.SystemdUnit{
+		Description: "my container",
+		After:       []string{"network-online.target"},
+		BindsTo:     []string{"foo.service", "bar.service"},
+		PartOf:      []string{"pod.target"},
+		CollectMode: "inactive-or-failed",
+	}
+	got := make(map[string]string)
+	for _, p := range unitProperties(c) {
+		got[p.Name] = p.Value.String()
+	}
+	expected := map[string]string{
+		"Description": `"my container"`,
+		"After":       `["network-online.target"]`,
+		"BindsTo":     `["foo.service", "bar.service"]`,
+		"PartOf":      `["pod.target"]`,
+		"CollectMode": `"inactive-or-failed"`,
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected properties %v, got %v", expected, got)
+	}
+	for name, value := range expected {
+		if got[name] != value {
+			t.Errorf("expected %s=%s, got %q", name, value, got[name])
+		}
+	}
+}
+
 func newManager(config *configs.Cgroup) cgroups.Manager {
 	if cgroups.IsCgroup2UnifiedMode() {
 		return NewUnifiedManager(config, "", false)
@@ -58,6 +58,10 @@ func (m *Manager) Freeze(state configs.FreezerState) error {
 	return errors.New("Systemd not supported")
 }
 
+func (m *Manager) Kill() error {
+	return errors.New("Systemd not supported")
+}
+
 func Freeze(c *configs.Cgroup, state configs.FreezerState) error {
 	return errors.New("Systemd not supported")
 }
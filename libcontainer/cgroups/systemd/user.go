@@ -16,6 +16,7 @@ import (
 	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
 	dbus "github.com/godbus/dbus/v5"
 
+	"github.com/opencontainers/runc/libcontainer/user"
 	"github.com/opencontainers/runc/libcontainer/userns"
 )
 
@@ -49,6 +50,54 @@ func newUserSystemdDbus() (*systemdDbus.Conn, error) {
 	})
 }
 
+// newUserSystemdPrivate creates a direct connection to the private socket of
+// systemd user-instance, which does not need the session bus.
+func newUserSystemdPrivate() (*systemdDbus.Conn, error) {
+	uid, err := hostUID()
+	if err != nil {
+		return nil, err
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = "/run/user/" + strconv.Itoa(uid)
+	}
+	addr := "unix:path=" + filepath.Join(runtimeDir, "systemd/private")
+
+	return systemdDbus.NewConnection(func() (*dbus.Conn, error) {
+		conn, err := dbus.Dial(addr)
+		if err != nil {
+			return nil, fmt.Errorf("error while dialing %q: %w", addr, err)
+		}
+		methods := []dbus.Auth{dbus.AuthExternal(strconv.Itoa(uid))}
+		err = conn.Auth(methods)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error while authenticating connection (address=%q, UID=%d): %w", addr, uid, err)
+		}
+		// Unlike a bus, systemd does not expect a Hello message.
+		return conn, nil
+	})
+}
+
+// hostUID returns the UID of the current user outside of the user namespace
+// runc is running in (if any), according to /proc/self/uid_map.
+func hostUID() (int, error) {
+	uid := os.Getuid()
+	if !userns.RunningInUserNS() {
+		return uid, nil
+	}
+	uidMap, err := user.CurrentProcessUIDMap()
+	if err != nil {
+		return -1, err
+	}
+	for _, m := range uidMap {
+		if int64(uid) >= m.ID && int64(uid) < m.ID+m.Count {
+			return int(m.ParentID + int64(uid) - m.ID), nil
+		}
+	}
+	return -1, fmt.Errorf("UID %d is not mapped", uid)
+}
+
 // DetectUID detects UID from the OwnerUID field of `busctl --user status`
 // if running in userNS. The value corresponds to sd_bus_creds_get_owner_uid(3) .
 //
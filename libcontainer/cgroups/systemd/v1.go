@@ -24,11 +24,13 @@ type legacyManager struct {
 	dbus    *dbusConnManager
 }
 
-func NewLegacyManager(cg *configs.Cgroup, paths map[string]string) cgroups.Manager {
+// NewLegacyManager returns a systemd cgroup manager for cgroup v1, which
+// connects to systemd using mode (ConnAuto by default).
+func NewLegacyManager(cg *configs.Cgroup, paths map[string]string, mode ...ConnMode) cgroups.Manager {
 	return &legacyManager{
 		cgroups: cg,
 		paths:   paths,
-		dbus:    newDbusConnManager(false),
+		dbus:    newDbusConnManager(false, mode...),
 	}
 }
 
@@ -132,7 +134,7 @@ func (m *legacyManager) Apply(pid int) error {
 		slice = c.Parent
 	}
 
-	properties = append(properties, systemdDbus.PropDescription("libcontainer container "+c.Name))
+	properties = append(properties, unitProperties(c)...)
 
 	// if we create a slice, the parent is defined via a Wants=
 	if strings.HasSuffix(unitName, ".slice") {
@@ -402,6 +404,10 @@ func (m *legacyManager) GetFreezerState() (configs.FreezerState, error) {
 	return freezer.GetState(path)
 }
 
+func (m *legacyManager) Kill() error {
+	return cgroups.ErrKillNotSupported
+}
+
 func (m *legacyManager) Exists() bool {
 	return cgroups.PathExists(m.Path("devices"))
 }
@@ -29,12 +29,14 @@ type unifiedManager struct {
 	dbus     *dbusConnManager
 }
 
-func NewUnifiedManager(config *configs.Cgroup, path string, rootless bool) cgroups.Manager {
+// NewUnifiedManager returns a systemd cgroup manager for cgroup v2, which
+// connects to systemd using mode (ConnAuto by default).
+func NewUnifiedManager(config *configs.Cgroup, path string, rootless bool, mode ...ConnMode) cgroups.Manager {
 	return &unifiedManager{
 		cgroups:  config,
 		path:     path,
 		rootless: rootless,
-		dbus:     newDbusConnManager(rootless),
+		dbus:     newDbusConnManager(rootless, mode...),
 	}
 }
 
@@ -96,7 +98,7 @@ func unifiedResToSystemdProps(cm *dbusConnManager, res map[string]string) (props
 				newProp("CPUWeight", num))
 
 		case "cpuset.cpus", "cpuset.mems":
-			bits, err := RangeToBits(v)
+			bits, err := cgroups.RangeToBits(v)
 			if err != nil {
 				return nil, fmt.Errorf("unified resource %q=%q conversion error: %w", k, v, err)
 			}
@@ -182,26 +184,48 @@ func genV2ResourcesProperties(r *configs.Resources, cm *dbusConnManager) ([]syst
 		properties = append(properties,
 			newProp("MemoryMax", uint64(r.Memory)))
 	}
-	if r.MemoryReservation != 0 {
+	if r.MemoryLow != nil {
+		properties = append(properties,
+			newProp("MemoryLow", uint64(*r.MemoryLow)))
+	} else if r.MemoryReservation != 0 {
 		properties = append(properties,
 			newProp("MemoryLow", uint64(r.MemoryReservation)))
 	}
-
-	swap, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
-	if err != nil {
-		return nil, err
+	if r.MemoryMin != nil {
+		properties = append(properties,
+			newProp("MemoryMin", uint64(*r.MemoryMin)))
 	}
-	if swap != 0 {
+	if r.MemoryHigh != nil {
+		// -1 is converted to math.MaxUint64, meaning "infinity"; 0 is
+		// rejected by the validator, as it would throttle everything.
 		properties = append(properties,
-			newProp("MemorySwapMax", uint64(swap)))
+			newProp("MemoryHigh", uint64(*r.MemoryHigh)))
 	}
 
+	if r.MemorySwapMax != nil {
+		properties = append(properties,
+			newProp("MemorySwapMax", uint64(*r.MemorySwapMax)))
+	} else {
+		swap, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
+		if err != nil {
+			return nil, err
+		}
+		if swap != 0 {
+			properties = append(properties,
+				newProp("MemorySwapMax", uint64(swap)))
+		}
+	}
+
+	// r.MemoryOomGroup has no systemd equivalent, and is set by fs2.
+
 	if r.CpuWeight != 0 {
 		properties = append(properties,
 			newProp("CPUWeight", r.CpuWeight))
 	}
 
 	addCpuQuota(cm, &properties, r.CpuQuota, r.CpuPeriod)
+	// r.CpuBurst, r.CpuIdle, r.CpuUclampMin and r.CpuUclampMax have no
+	// systemd equivalent, and are set by fs2.
 
 	if r.PidsLimit > 0 || r.PidsLimit == -1 {
 		properties = append(properties,
@@ -246,7 +270,7 @@ func (m *unifiedManager) Apply(pid int) error {
 		slice = c.Parent
 	}
 
-	properties = append(properties, systemdDbus.PropDescription("libcontainer container "+c.Name))
+	properties = append(properties, unitProperties(c)...)
 
 	// if we create a slice, the parent is defined via a Wants=
 	if strings.HasSuffix(unitName, ".slice") {
@@ -265,6 +289,8 @@ func (m *unifiedManager) Apply(pid int) error {
 	if !strings.HasSuffix(unitName, ".slice") {
 		// Assume scopes always support delegation.
 		properties = append(properties, newProp("Delegate", true))
+	} else if c.Delegate != nil {
+		return fmt.Errorf("unable to delegate %s: slices can not be delegated", unitName)
 	}
 
 	// Always enable accounting, this gets us the same behaviour as the fs implementation,
@@ -292,6 +318,9 @@ func (m *unifiedManager) Apply(pid int) error {
 	if err := fs2.CreateCgroupPath(m.path, m.cgroups); err != nil {
 		return err
 	}
+	if c.Delegate != nil {
+		return fs2.Delegate(m.path, pid, c.Delegate)
+	}
 	return nil
 }
 
@@ -447,6 +476,10 @@ func (m *unifiedManager) GetFreezerState() (configs.FreezerState, error) {
 	return fsMgr.GetFreezerState()
 }
 
+func (m *unifiedManager) Kill() error {
+	return fs2.Kill(m.path)
+}
+
 func (m *unifiedManager) Exists() bool {
 	return cgroups.PathExists(m.path)
 }
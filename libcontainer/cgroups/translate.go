@@ -0,0 +1,443 @@
+// +build linux
+
+package cgroups
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// ResourceTranslation describes how a configs.Resources field is applied by
+// the cgroup managers for a given cgroup version.
+type ResourceTranslation struct {
+	// Field is the name of the configs.Resources field.
+	Field string `json:"field"`
+	// Value is the value of the field.
+	Value string `json:"value"`
+	// Result lists the cgroup file settings the field results in, such as
+	// "cpu.weight=79". It is empty if the field is ignored.
+	Result string `json:"result,omitempty"`
+	// Note explains the conversion, or why the field is ignored.
+	Note string `json:"note,omitempty"`
+	// Lossy is set if the conversion loses precision, or changes semantics.
+	Lossy bool `json:"lossy,omitempty"`
+}
+
+// Ignored returns true if the field has no effect.
+func (t ResourceTranslation) Ignored() bool {
+	return t.Result == ""
+}
+
+func (t ResourceTranslation) String() string {
+	s := t.Field + "=" + t.Value + ": "
+	if t.Ignored() {
+		s += "ignored"
+	} else {
+		s += t.Result
+	}
+	if t.Note != "" {
+		s += " (" + t.Note + ")"
+	}
+	if t.Lossy {
+		s += " [lossy]"
+	}
+	return s
+}
+
+// limitString returns the cgroup v2 representation of a limit.
+func limitString(v int64) string {
+	if v == -1 {
+		return "max"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func ioDeviceName(d *configs.IoDevice) string {
+	if d.Path != "" {
+		return d.Path
+	}
+	return fmt.Sprintf("%d:%d", d.Major, d.Minor)
+}
+
+// TranslateResources describes how each of the set fields of r is applied
+// on cgroup v2 (if unified is true) or cgroup v1, including the conversions
+// of cgroup v1 settings done for cgroup v2 (and vice versa), and the fields
+// which are ignored. See IsCgroup2UnifiedMode for the host cgroup version.
+func TranslateResources(r *configs.Resources, unified bool) []ResourceTranslation {
+	if r == nil {
+		return nil
+	}
+	var ts []ResourceTranslation
+	add := func(field string, value interface{}, result, note string, lossy bool) {
+		ts = append(ts, ResourceTranslation{
+			Field:  field,
+			Value:  fmt.Sprint(value),
+			Result: result,
+			Note:   note,
+			Lossy:  lossy,
+		})
+	}
+	if unified {
+		translateV1ToV2(r, add)
+	} else {
+		translateV2ToV1(r, add)
+	}
+	if len(r.Devices) > 0 {
+		prog := "devices.allow, devices.deny"
+		if unified {
+			prog = "eBPF device filter"
+		}
+		add("Devices", fmt.Sprintf("%d rules", len(r.Devices)), prog, "", false)
+	}
+	return ts
+}
+
+type addFunc func(field string, value interface{}, result, note string, lossy bool)
+
+func translateV1ToV2(r *configs.Resources, add addFunc) {
+	const noV2 = "no cgroup v2 equivalent"
+
+	if r.Memory != 0 {
+		add("Memory", r.Memory, "memory.max="+limitString(r.Memory), "", false)
+	}
+	if r.MemoryReservation != 0 {
+		if r.MemoryLow != nil {
+			add("MemoryReservation", r.MemoryReservation, "", "overridden by MemoryLow", false)
+		} else {
+			add("MemoryReservation", r.MemoryReservation, "memory.low="+limitString(r.MemoryReservation),
+				"soft limit converted to best-effort memory protection", true)
+		}
+	}
+	if r.MemorySwap != 0 {
+		if r.MemorySwapMax != nil {
+			add("MemorySwap", r.MemorySwap, "", "overridden by MemorySwapMax", false)
+		} else if swap, err := ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory); err != nil {
+			add("MemorySwap", r.MemorySwap, "", "invalid: "+err.Error(), false)
+		} else {
+			add("MemorySwap", r.MemorySwap, "memory.swap.max="+limitString(swap),
+				"memory+swap limit converted to swap-only limit", false)
+		}
+	}
+	if r.MemorySwappiness != nil {
+		add("MemorySwappiness", *r.MemorySwappiness, "", noV2, false)
+	}
+	if r.OomKillDisable {
+		add("OomKillDisable", r.OomKillDisable, "", noV2, false)
+	}
+
+	if r.CpuShares != 0 {
+		weight := ConvertCPUSharesToCgroupV2Value(r.CpuShares)
+		switch r.CpuWeight {
+		case weight:
+			// Shares map to weights many-to-one.
+			lossy := 2+(weight-1)*262142/9999 != r.CpuShares
+			add("CpuShares", r.CpuShares, "cpu.weight="+strconv.FormatUint(weight, 10),
+				"scaled from [2, 262144] to [1, 10000]", lossy)
+		case 0:
+			add("CpuShares", r.CpuShares, "", "not converted, CpuWeight must be set", false)
+		default:
+			add("CpuShares", r.CpuShares, "", "overridden by CpuWeight", false)
+		}
+	}
+	if r.CpuWeight != 0 && r.CpuWeight != ConvertCPUSharesToCgroupV2Value(r.CpuShares) {
+		add("CpuWeight", r.CpuWeight, "cpu.weight="+strconv.FormatUint(r.CpuWeight, 10), "", false)
+	}
+	if r.CpuQuota != 0 || r.CpuPeriod != 0 {
+		quota := "max"
+		if r.CpuQuota > 0 {
+			quota = strconv.FormatInt(r.CpuQuota, 10)
+		}
+		period := r.CpuPeriod
+		if period == 0 {
+			period = 100000
+		}
+		result := "cpu.max=" + quota + " " + strconv.FormatUint(period, 10)
+		if r.CpuQuota != 0 {
+			add("CpuQuota", r.CpuQuota, result, "", false)
+		}
+		if r.CpuPeriod != 0 {
+			add("CpuPeriod", r.CpuPeriod, result, "", false)
+		}
+	}
+	if r.CpuRtRuntime != 0 {
+		add("CpuRtRuntime", r.CpuRtRuntime, "", noV2, false)
+	}
+	if r.CpuRtPeriod != 0 {
+		add("CpuRtPeriod", r.CpuRtPeriod, "", noV2, false)
+	}
+	if r.CpuBurst != nil {
+		add("CpuBurst", *r.CpuBurst, "cpu.max.burst="+strconv.FormatUint(*r.CpuBurst, 10), "", false)
+	}
+	if r.CpuIdle != nil {
+		add("CpuIdle", *r.CpuIdle, "cpu.idle="+strconv.FormatInt(*r.CpuIdle, 10), "", false)
+	}
+	if r.CpuUclampMin != "" {
+		add("CpuUclampMin", r.CpuUclampMin, "cpu.uclamp.min="+r.CpuUclampMin, "", false)
+	}
+	if r.CpuUclampMax != "" {
+		add("CpuUclampMax", r.CpuUclampMax, "cpu.uclamp.max="+r.CpuUclampMax, "", false)
+	}
+	if r.CpusetCpus != "" {
+		add("CpusetCpus", r.CpusetCpus, "cpuset.cpus="+r.CpusetCpus, "", false)
+	}
+	if r.CpusetMems != "" {
+		add("CpusetMems", r.CpusetMems, "cpuset.mems="+r.CpusetMems, "", false)
+	}
+	if r.PidsLimit != 0 {
+		add("PidsLimit", r.PidsLimit, "pids.max="+limitString(r.PidsLimit), "", false)
+	}
+
+	if r.BlkioWeight != 0 {
+		weight := ConvertBlkIOToIOWeightValue(r.BlkioWeight)
+		note := "with the BFQ I/O scheduler"
+		lossy := false
+		if r.IoWeight != 0 {
+			note += ", otherwise overridden by IoWeight"
+		} else {
+			note += fmt.Sprintf(", otherwise io.weight=%d, scaled from [10, 1000] to [1, 10000]", weight)
+			lossy = 10+(weight-1)*990/9999 != uint64(r.BlkioWeight)
+		}
+		add("BlkioWeight", r.BlkioWeight, "io.bfq.weight="+strconv.FormatUint(uint64(r.BlkioWeight), 10), note, lossy)
+	}
+	if r.BlkioLeafWeight != 0 {
+		add("BlkioLeafWeight", r.BlkioLeafWeight, "", noV2, false)
+	}
+	if len(r.BlkioWeightDevice) > 0 {
+		var res []string
+		for _, wd := range r.BlkioWeightDevice {
+			res = append(res, "io.bfq.weight="+wd.WeightString())
+		}
+		add("BlkioWeightDevice", len(r.BlkioWeightDevice), strings.Join(res, "; "),
+			"with the BFQ I/O scheduler (supporting per-device weights), otherwise ignored", true)
+	}
+	for _, th := range []struct {
+		field string
+		name  string
+		devs  []*configs.ThrottleDevice
+	}{
+		{"BlkioThrottleReadBpsDevice", "rbps", r.BlkioThrottleReadBpsDevice},
+		{"BlkioThrottleWriteBpsDevice", "wbps", r.BlkioThrottleWriteBpsDevice},
+		{"BlkioThrottleReadIOPSDevice", "riops", r.BlkioThrottleReadIOPSDevice},
+		{"BlkioThrottleWriteIOPSDevice", "wiops", r.BlkioThrottleWriteIOPSDevice},
+	} {
+		if len(th.devs) == 0 {
+			continue
+		}
+		var res []string
+		for _, td := range th.devs {
+			res = append(res, "io.max="+td.StringName(th.name))
+		}
+		add(th.field, len(th.devs), strings.Join(res, "; "), "", false)
+	}
+
+	if len(r.HugetlbLimit) > 0 {
+		var res []string
+		for _, h := range r.HugetlbLimit {
+			res = append(res, "hugetlb."+h.Pagesize+".max="+strconv.FormatUint(h.Limit, 10))
+			if h.RsvdLimit != nil {
+				res = append(res, "hugetlb."+h.Pagesize+".rsvd.max="+strconv.FormatUint(*h.RsvdLimit, 10))
+			}
+		}
+		add("HugetlbLimit", len(r.HugetlbLimit), strings.Join(res, "; "), "", false)
+	}
+	if len(r.NetPrioIfpriomap) > 0 {
+		add("NetPrioIfpriomap", len(r.NetPrioIfpriomap), "", noV2, false)
+	}
+	if r.NetClsClassid != 0 {
+		add("NetClsClassid", r.NetClsClassid, "", noV2, false)
+	}
+	switch r.Freezer {
+	case configs.Frozen:
+		add("Freezer", r.Freezer, "cgroup.freeze=1", "", false)
+	case configs.Thawed:
+		add("Freezer", r.Freezer, "cgroup.freeze=0", "", false)
+	}
+
+	// Settings specific to cgroup v2.
+	if r.MemoryLow != nil {
+		add("MemoryLow", *r.MemoryLow, "memory.low="+limitString(*r.MemoryLow), "", false)
+	}
+	if r.MemoryMin != nil {
+		add("MemoryMin", *r.MemoryMin, "memory.min="+limitString(*r.MemoryMin), "", false)
+	}
+	if r.MemoryHigh != nil {
+		add("MemoryHigh", *r.MemoryHigh, "memory.high="+limitString(*r.MemoryHigh), "", false)
+	}
+	if r.MemorySwapMax != nil {
+		add("MemorySwapMax", *r.MemorySwapMax, "memory.swap.max="+limitString(*r.MemorySwapMax), "", false)
+	}
+	if r.MemoryOomGroup != nil {
+		add("MemoryOomGroup", *r.MemoryOomGroup, "memory.oom.group="+strconv.FormatBool(*r.MemoryOomGroup), "", false)
+	}
+	if r.IoWeight != 0 {
+		add("IoWeight", r.IoWeight, "io.weight=default "+strconv.FormatUint(r.IoWeight, 10), "", false)
+	}
+	for _, wd := range r.IoWeightDevice {
+		add("IoWeightDevice", ioDeviceName(&wd.IoDevice), "io.weight="+wd.String(ioDeviceName(&wd.IoDevice)), "", false)
+	}
+	for _, m := range r.IoMax {
+		add("IoMax", ioDeviceName(&m.IoDevice), "io.max="+m.String(ioDeviceName(&m.IoDevice)), "", false)
+	}
+	for _, l := range r.IoLatency {
+		add("IoLatency", ioDeviceName(&l.IoDevice), "io.latency="+l.String(ioDeviceName(&l.IoDevice)), "", false)
+	}
+	for _, q := range r.IoCostQos {
+		add("IoCostQos", ioDeviceName(&q.IoDevice), "io.cost.qos="+q.String(ioDeviceName(&q.IoDevice)),
+			"set in the root cgroup", false)
+	}
+	keys := make([]string, 0, len(r.Unified))
+	for k := range r.Unified {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add("Unified", k, k+"="+r.Unified[k], "", false)
+	}
+}
+
+func translateV2ToV1(r *configs.Resources, add addFunc) {
+	const v2Only = "requires cgroup v2"
+
+	// Settings common to cgroup v1 and v2 are applied as is.
+	for _, s := range []struct {
+		field string
+		set   bool
+		value interface{}
+		file  string
+	}{
+		{"Memory", r.Memory != 0, r.Memory, "memory.limit_in_bytes"},
+		{"MemorySwap", r.MemorySwap != 0 && r.MemorySwapMax == nil, r.MemorySwap, "memory.memsw.limit_in_bytes"},
+		{"CpuShares", r.CpuShares != 0, r.CpuShares, "cpu.shares"},
+		{"CpuQuota", r.CpuQuota != 0, r.CpuQuota, "cpu.cfs_quota_us"},
+		{"CpuPeriod", r.CpuPeriod != 0, r.CpuPeriod, "cpu.cfs_period_us"},
+		{"CpuRtRuntime", r.CpuRtRuntime != 0, r.CpuRtRuntime, "cpu.rt_runtime_us"},
+		{"CpuRtPeriod", r.CpuRtPeriod != 0, r.CpuRtPeriod, "cpu.rt_period_us"},
+		{"CpusetCpus", r.CpusetCpus != "", r.CpusetCpus, "cpuset.cpus"},
+		{"CpusetMems", r.CpusetMems != "", r.CpusetMems, "cpuset.mems"},
+		{"BlkioWeight", r.BlkioWeight != 0, r.BlkioWeight, "blkio.weight"},
+		{"BlkioLeafWeight", r.BlkioLeafWeight != 0, r.BlkioLeafWeight, "blkio.leaf_weight"},
+		{"NetClsClassid", r.NetClsClassid != 0, r.NetClsClassid, "net_cls.classid"},
+		{"Freezer", r.Freezer != configs.Undefined, r.Freezer, "freezer.state"},
+	} {
+		if s.set {
+			add(s.field, s.value, s.file+"="+fmt.Sprint(s.value), "", false)
+		}
+	}
+	if r.OomKillDisable {
+		add("OomKillDisable", r.OomKillDisable, "memory.oom_control=1", "", false)
+	}
+	if r.PidsLimit != 0 {
+		limit := "max"
+		if r.PidsLimit > 0 {
+			limit = strconv.FormatInt(r.PidsLimit, 10)
+		}
+		add("PidsLimit", r.PidsLimit, "pids.max="+limit, "", false)
+	}
+	if r.MemoryReservation != 0 {
+		if r.MemoryLow != nil {
+			add("MemoryReservation", r.MemoryReservation, "", "overridden by MemoryLow", false)
+		} else {
+			add("MemoryReservation", r.MemoryReservation, "memory.soft_limit_in_bytes="+strconv.FormatInt(r.MemoryReservation, 10), "", false)
+		}
+	}
+	if r.MemorySwappiness != nil {
+		add("MemorySwappiness", *r.MemorySwappiness, "memory.swappiness="+strconv.FormatUint(*r.MemorySwappiness, 10), "", false)
+	}
+	if r.CpuBurst != nil {
+		add("CpuBurst", *r.CpuBurst, "cpu.cfs_burst_us="+strconv.FormatUint(*r.CpuBurst, 10), "", false)
+	}
+	if r.CpuIdle != nil {
+		add("CpuIdle", *r.CpuIdle, "cpu.idle="+strconv.FormatInt(*r.CpuIdle, 10), "", false)
+	}
+	if r.CpuUclampMin != "" {
+		add("CpuUclampMin", r.CpuUclampMin, "cpu.uclamp.min="+r.CpuUclampMin, "", false)
+	}
+	if r.CpuUclampMax != "" {
+		add("CpuUclampMax", r.CpuUclampMax, "cpu.uclamp.max="+r.CpuUclampMax, "", false)
+	}
+	if len(r.BlkioWeightDevice) > 0 {
+		var res []string
+		for _, wd := range r.BlkioWeightDevice {
+			res = append(res, "blkio.weight_device="+wd.WeightString())
+		}
+		add("BlkioWeightDevice", len(r.BlkioWeightDevice), strings.Join(res, "; "), "", false)
+	}
+	for _, th := range []struct {
+		field string
+		file  string
+		devs  []*configs.ThrottleDevice
+	}{
+		{"BlkioThrottleReadBpsDevice", "blkio.throttle.read_bps_device", r.BlkioThrottleReadBpsDevice},
+		{"BlkioThrottleWriteBpsDevice", "blkio.throttle.write_bps_device", r.BlkioThrottleWriteBpsDevice},
+		{"BlkioThrottleReadIOPSDevice", "blkio.throttle.read_iops_device", r.BlkioThrottleReadIOPSDevice},
+		{"BlkioThrottleWriteIOPSDevice", "blkio.throttle.write_iops_device", r.BlkioThrottleWriteIOPSDevice},
+	} {
+		if len(th.devs) == 0 {
+			continue
+		}
+		var res []string
+		for _, td := range th.devs {
+			res = append(res, th.file+"="+td.String())
+		}
+		add(th.field, len(th.devs), strings.Join(res, "; "), "", false)
+	}
+	if len(r.HugetlbLimit) > 0 {
+		var res []string
+		for _, h := range r.HugetlbLimit {
+			res = append(res, "hugetlb."+h.Pagesize+".limit_in_bytes="+strconv.FormatUint(h.Limit, 10))
+			if h.RsvdLimit != nil {
+				res = append(res, "hugetlb."+h.Pagesize+".rsvd.limit_in_bytes="+strconv.FormatUint(*h.RsvdLimit, 10))
+			}
+		}
+		add("HugetlbLimit", len(r.HugetlbLimit), strings.Join(res, "; "), "", false)
+	}
+	if len(r.NetPrioIfpriomap) > 0 {
+		var res []string
+		for _, p := range r.NetPrioIfpriomap {
+			res = append(res, "net_prio.ifpriomap="+p.CgroupString())
+		}
+		add("NetPrioIfpriomap", len(r.NetPrioIfpriomap), strings.Join(res, "; "), "", false)
+	}
+
+	// Settings specific to cgroup v2.
+	if r.MemoryLow != nil {
+		add("MemoryLow", *r.MemoryLow, "memory.soft_limit_in_bytes="+strconv.FormatInt(*r.MemoryLow, 10),
+			"best-effort memory protection converted to soft limit", true)
+	}
+	if r.MemorySwapMax != nil {
+		if swap, err := ConvertMemorySwapMaxToCgroupV1Value(*r.MemorySwapMax, r.Memory); err != nil {
+			add("MemorySwapMax", *r.MemorySwapMax, "", "invalid: "+err.Error(), false)
+		} else {
+			add("MemorySwapMax", *r.MemorySwapMax, "memory.memsw.limit_in_bytes="+strconv.FormatInt(swap, 10),
+				"swap-only limit converted to memory+swap limit", false)
+		}
+		if r.MemorySwap != 0 {
+			add("MemorySwap", r.MemorySwap, "", "overridden by MemorySwapMax", false)
+		}
+	}
+	if r.CpuWeight != 0 && r.CpuWeight != ConvertCPUSharesToCgroupV2Value(r.CpuShares) {
+		add("CpuWeight", r.CpuWeight, "", v2Only, false)
+	}
+	if r.MemoryMin != nil && *r.MemoryMin != 0 {
+		add("MemoryMin", *r.MemoryMin, "", v2Only, false)
+	}
+	if r.MemoryHigh != nil && *r.MemoryHigh != 0 {
+		add("MemoryHigh", *r.MemoryHigh, "", v2Only, false)
+	}
+	if r.MemoryOomGroup != nil {
+		add("MemoryOomGroup", *r.MemoryOomGroup, "", v2Only, false)
+	}
+	if r.IoWeight != 0 {
+		add("IoWeight", r.IoWeight, "", v2Only, false)
+	}
+	if n := len(r.IoWeightDevice) + len(r.IoMax) + len(r.IoLatency) + len(r.IoCostQos); n > 0 {
+		add("IoWeightDevice, IoMax, IoLatency, IoCostQos", fmt.Sprintf("%d devices", n), "", v2Only, false)
+	}
+	if len(r.Unified) > 0 {
+		add("Unified", len(r.Unified), "", v2Only, false)
+	}
+}
@@ -0,0 +1,111 @@
+// +build linux
+
+package cgroups
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestTranslateResources(t *testing.T) {
+	swappiness := uint64(60)
+	r := &configs.Resources{
+		Memory:           1 << 30,
+		MemorySwap:       2 << 30,
+		MemorySwappiness: &swappiness,
+		CpuShares:        1024,
+		CpuWeight:        ConvertCPUSharesToCgroupV2Value(1024),
+		CpuQuota:         50000,
+		CpuRtRuntime:     1000,
+		PidsLimit:        -1,
+		BlkioWeight:      500,
+	}
+
+	for _, tc := range []struct {
+		unified  bool
+		expected map[string]string
+	}{
+		{
+			unified: true,
+			expected: map[string]string{
+				"Memory":           "Memory=1073741824: memory.max=1073741824",
+				"MemorySwap":       "MemorySwap=2147483648: memory.swap.max=1073741824 (memory+swap limit converted to swap-only limit)",
+				"MemorySwappiness": "MemorySwappiness=60: ignored (no cgroup v2 equivalent)",
+				"CpuShares":        "CpuShares=1024: cpu.weight=39 (scaled from [2, 262144] to [1, 10000]) [lossy]",
+				"CpuQuota":         "CpuQuota=50000: cpu.max=50000 100000",
+				"CpuRtRuntime":     "CpuRtRuntime=1000: ignored (no cgroup v2 equivalent)",
+				"PidsLimit":        "PidsLimit=-1: pids.max=max",
+				"BlkioWeight":      "BlkioWeight=500: io.bfq.weight=500 (with the BFQ I/O scheduler, otherwise io.weight=4950, scaled from [10, 1000] to [1, 10000])",
+			},
+		},
+		{
+			unified: false,
+			expected: map[string]string{
+				"Memory":           "Memory=1073741824: memory.limit_in_bytes=1073741824",
+				"MemorySwap":       "MemorySwap=2147483648: memory.memsw.limit_in_bytes=2147483648",
+				"MemorySwappiness": "MemorySwappiness=60: memory.swappiness=60",
+				"CpuShares":        "CpuShares=1024: cpu.shares=1024",
+				"CpuQuota":         "CpuQuota=50000: cpu.cfs_quota_us=50000",
+				"CpuRtRuntime":     "CpuRtRuntime=1000: cpu.rt_runtime_us=1000",
+				"PidsLimit":        "PidsLimit=-1: pids.max=max",
+				"BlkioWeight":      "BlkioWeight=500: blkio.weight=500",
+			},
+		},
+	} {
+		got := make(map[string]string)
+		for _, tr := range TranslateResources(r, tc.unified) {
+			got[tr.Field] = tr.String()
+		}
+		if len(got) != len(tc.expected) {
+			t.Errorf("unified=%v: expected %d fields, got %v", tc.unified, len(tc.expected), got)
+		}
+		for field, exp := range tc.expected {
+			if got[field] != exp {
+				t.Errorf("unified=%v: expected %q, got %q", tc.unified, exp, got[field])
+			}
+		}
+	}
+}
+
+func TestTranslateResourcesOverride(t *testing.T) {
+	swapMax := int64(0)
+	low := int64(1 << 28)
+	r := &configs.Resources{
+		Memory:            1 << 30,
+		MemoryReservation: 1 << 29,
+		MemoryLow:         &low,
+		MemorySwap:        2 << 30,
+		MemorySwapMax:     &swapMax,
+		CpuShares:         1024,
+		CpuWeight:         100,
+	}
+	for _, tc := range []struct {
+		unified bool
+		field   string
+		ignored bool
+	}{
+		{true, "MemoryReservation", true},
+		{true, "MemorySwap", true},
+		{true, "CpuShares", true},
+		{true, "CpuWeight", false},
+		{false, "MemoryReservation", true},
+		{false, "MemorySwap", true},
+		{false, "MemorySwapMax", false},
+		{false, "CpuWeight", true},
+	} {
+		found := false
+		for _, tr := range TranslateResources(r, tc.unified) {
+			if tr.Field != tc.field {
+				continue
+			}
+			found = true
+			if tr.Ignored() != tc.ignored {
+				t.Errorf("unified=%v: expected %s ignored=%v, got %s", tc.unified, tc.field, tc.ignored, tr)
+			}
+		}
+		if !found {
+			t.Errorf("unified=%v: %s not found", tc.unified, tc.field)
+		}
+	}
+}
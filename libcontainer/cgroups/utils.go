@@ -438,6 +438,26 @@ func ConvertMemorySwapToCgroupV2Value(memorySwap, memory int64) (int64, error) {
 	return memorySwap - memory, nil
 }
 
+// ConvertMemorySwapMaxToCgroupV1Value converts a cgroup v2 style swap limit
+// (Resources.MemorySwapMax) to a memory+swap limit, for use by cgroup v1
+// drivers.
+func ConvertMemorySwapMaxToCgroupV1Value(swapMax, memory int64) (int64, error) {
+	if swapMax == -1 {
+		return -1, nil
+	}
+	if swapMax < 0 {
+		return 0, fmt.Errorf("invalid swap value: %d", swapMax)
+	}
+	if memory == 0 || memory == -1 {
+		return 0, errors.New("unable to set swap limit without memory limit")
+	}
+	if memory < 0 {
+		return 0, fmt.Errorf("invalid memory value: %d", memory)
+	}
+
+	return memory + swapMax, nil
+}
+
 // Since the OCI spec is designed for cgroup v1, in some cases
 // there is need to convert from the cgroup v1 configuration to cgroup v2
 // the formula for BlkIOWeight to IOWeight is y = (1 + (x - 10) * 9999 / 990)
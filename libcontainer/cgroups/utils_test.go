@@ -642,6 +642,39 @@ func TestConvertMemorySwapToCgroupV2Value(t *testing.T) {
 	}
 }
 
+func TestConvertMemorySwapMaxToCgroupV1Value(t *testing.T) {
+	cases := []struct {
+		swapMax, memory int64
+		expected        int64
+		expErr          bool
+	}{
+		{swapMax: -1, memory: 0, expected: -1},
+		{swapMax: -1, memory: 1000, expected: -1},
+		{swapMax: 0, memory: 1000, expected: 1000},
+		{swapMax: 500, memory: 200, expected: 700},
+		{swapMax: -2, memory: 1000, expErr: true},
+		{swapMax: 300, memory: 0, expErr: true},
+		{swapMax: 300, memory: -1, expErr: true},
+		{swapMax: 300, memory: -300, expErr: true},
+	}
+
+	for _, c := range cases {
+		memswap, err := ConvertMemorySwapMaxToCgroupV1Value(c.swapMax, c.memory)
+		if c.expErr {
+			if err == nil {
+				t.Errorf("swap: %d, memory %d, expected error, got %d, nil", c.swapMax, c.memory, memswap)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("swap: %d, memory %d, expected success, got error %s", c.swapMax, c.memory, err)
+		}
+		if memswap != c.expected {
+			t.Errorf("swap: %d, memory %d, expected %d, got %d", c.swapMax, c.memory, c.expected, memswap)
+		}
+	}
+}
+
 func TestConvertBlkIOToIOWeightValue(t *testing.T) {
 	cases := map[uint16]uint64{
 		0:    0,
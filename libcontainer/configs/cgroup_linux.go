@@ -39,6 +39,69 @@ type Cgroup struct {
 	// derived from org.systemd.property.xxx annotations.
 	// Ignored unless systemd is used for managing cgroups.
 	SystemdProps []systemdDbus.Property `json:"-"`
+
+	// Delegate, if set, delegates the cgroup subtree to the container,
+	// so that a cgroup manager running inside it (e.g. systemd) can create
+	// and manage its own cgroups. Only supported on cgroup v2.
+	Delegate *CgroupDelegate `json:"delegate,omitempty"`
+
+	// Unit holds the settings of the systemd unit created for the container.
+	// Ignored unless systemd is used for managing cgroups.
+	Unit *SystemdUnit `json:"unit,omitempty"`
+}
+
+// SystemdUnit describes the transient systemd unit (a scope, or a slice)
+// created for a container by the systemd cgroup managers. See systemd.unit(5)
+// for details on each setting.
+type SystemdUnit struct {
+	// Description is the unit description, as shown by systemctl status.
+	// Defaults to "libcontainer container <name>".
+	Description string `json:"description,omitempty"`
+
+	// After and Before set the ordering dependencies of the unit.
+	After  []string `json:"after,omitempty"`
+	Before []string `json:"before,omitempty"`
+
+	// Wants, Requires, BindsTo and PartOf set the requirement dependencies
+	// of the unit.
+	Wants    []string `json:"wants,omitempty"`
+	Requires []string `json:"requires,omitempty"`
+	BindsTo  []string `json:"binds_to,omitempty"`
+	PartOf   []string `json:"part_of,omitempty"`
+
+	// CollectMode tunes the garbage collection of the unit; either
+	// "inactive" (systemd default) or "inactive-or-failed".
+	CollectMode string `json:"collect_mode,omitempty"`
+}
+
+// CgroupDelegate describes how a cgroup v2 subtree is delegated to a container.
+type CgroupDelegate struct {
+	// Controllers are the controllers to enable in the container cgroup's
+	// cgroup.subtree_control, making them available to the container's own
+	// sub-cgroups. If empty, all controllers available are enabled.
+	Controllers []string `json:"controllers,omitempty"`
+
+	// Leaf is the name of the sub-cgroup the container init is placed in,
+	// as a cgroup with controllers enabled in its cgroup.subtree_control can
+	// not have processes of its own. Defaults to DefaultDelegateLeaf.
+	Leaf string `json:"leaf,omitempty"`
+
+	// UID and GID are the owner of the delegated cgroup files, normally
+	// the host IDs of the container root user.
+	UID int `json:"uid"`
+	GID int `json:"gid"`
+}
+
+// DefaultDelegateLeaf is the default name of the cgroup the container init
+// is placed in when the cgroup is delegated.
+const DefaultDelegateLeaf = "init"
+
+// LeafName returns the name of the sub-cgroup for the container init.
+func (d *CgroupDelegate) LeafName() string {
+	if d.Leaf == "" {
+		return DefaultDelegateLeaf
+	}
+	return d.Leaf
 }
 
 type Resources struct {
@@ -69,6 +132,19 @@ type Resources struct {
 	// CPU period to be used for realtime scheduling (in usecs).
 	CpuRtPeriod uint64 `json:"cpu_rt_period"`
 
+	// CPU time (in usecs) unused in previous periods which can be used in
+	// addition to CpuQuota; the burst is limited to CpuQuota.
+	CpuBurst *uint64 `json:"cpu_burst,omitempty"`
+
+	// CpuIdle, if set to 1, makes the cgroup's processes be scheduled with
+	// the lowest priority, like SCHED_IDLE tasks, relative to other cgroups.
+	CpuIdle *int64 `json:"cpu_idle,omitempty"`
+
+	// Minimum and maximum CPU utilization clamps, as percentages with up to
+	// two decimal places (such as "12.34"), or "max".
+	CpuUclampMin string `json:"cpu_uclamp_min,omitempty"`
+	CpuUclampMax string `json:"cpu_uclamp_max,omitempty"`
+
 	// CPU to use
 	CpusetCpus string `json:"cpuset_cpus"`
 
@@ -122,9 +198,54 @@ type Resources struct {
 	// CpuWeight sets a proportional bandwidth limit.
 	CpuWeight uint64 `json:"cpu_weight"`
 
+	// MemoryHigh is the memory usage throttle limit (in bytes); set `-1` to
+	// remove the limit. Unlike with MemoryLow and MemoryMin, `0` is invalid,
+	// as it would throttle all the memory usage. Requires cgroup v2.
+	MemoryHigh *int64 `json:"memory_high,omitempty"`
+
+	// MemoryLow is the best-effort memory protection (in bytes); set `0` to
+	// remove the protection. If set, it takes precedence over
+	// MemoryReservation. On cgroup v1, it is set as the soft limit.
+	MemoryLow *int64 `json:"memory_low,omitempty"`
+
+	// MemoryMin is the hard memory protection (in bytes); set `0` to remove
+	// the protection. Requires cgroup v2.
+	MemoryMin *int64 `json:"memory_min,omitempty"`
+
+	// MemorySwapMax is the swap usage limit (in bytes), not including
+	// memory, unlike MemorySwap; set `-1` for unlimited swap. If set, it
+	// takes precedence over MemorySwap. On cgroup v1, it is converted to a
+	// memory+swap limit, which requires Memory to be set.
+	MemorySwapMax *int64 `json:"memory_swap_max,omitempty"`
+
+	// MemoryOomGroup makes the OOM killer kill all the processes of the
+	// cgroup together. Requires cgroup v2.
+	MemoryOomGroup *bool `json:"memory_oom_group,omitempty"`
+
+	// IoWeight is the default io.weight, range is from 1 to 10000. If set,
+	// it takes precedence over BlkioWeight.
+	IoWeight uint64 `json:"io_weight,omitempty"`
+
+	// IoWeightDevice sets per-device io.weight values.
+	IoWeightDevice []*IoWeightDevice `json:"io_weight_device,omitempty"`
+
+	// IoMax sets per-device io.max limits.
+	IoMax []*IoMaxDevice `json:"io_max,omitempty"`
+
+	// IoLatency sets per-device io.latency targets.
+	IoLatency []*IoLatencyDevice `json:"io_latency,omitempty"`
+
+	// IoCostQos sets the io.cost.qos parameters of devices. These are set in
+	// the root cgroup, and thus affect the whole host.
+	IoCostQos []*IoCostQos `json:"io_cost_qos,omitempty"`
+
 	// Unified is cgroupv2-only key-value map.
 	Unified map[string]string `json:"unified"`
 
+	// DevicesAudit makes the cgroupv2 device filter record denied device
+	// accesses, which can be read back using ebpf.ReadDeviceAudit.
+	DevicesAudit bool `json:"devices_audit,omitempty"`
+
 	// SkipDevices allows to skip configuring device permissions.
 	// Used by e.g. kubelet while creating a parent cgroup (kubepods)
 	// common for many containers, and by runc update.
@@ -94,6 +94,10 @@ type Config struct {
 	// Path to a directory containing the container's root filesystem.
 	Rootfs string `json:"rootfs"`
 
+	// RootfsLayers, if set, assembles an overlay filesystem from the given
+	// layers at Rootfs, which then only has to be an empty directory.
+	RootfsLayers *RootfsLayers `json:"rootfs_layers,omitempty"`
+
 	// Umask is the umask to use inside of the container.
 	Umask *uint32 `json:"umask"`
 
@@ -101,6 +105,10 @@ type Config struct {
 	// bind mounts are writtable.
 	Readonlyfs bool `json:"readonlyfs"`
 
+	// WritablePaths lists the directories of a readonly rootfs which are
+	// made writable, while keeping the contents they have in the rootfs.
+	WritablePaths []WritablePath `json:"writable_paths,omitempty"`
+
 	// Specifies the mount propagation flags to be applied to /.
 	RootPropagation int `json:"rootPropagation"`
 
@@ -6,4 +6,10 @@ type HugepageLimit struct {
 
 	// usage limit for hugepage.
 	Limit uint64 `json:"limit"`
+
+	// RsvdLimit, if set, limits the hugepage reservations (made when
+	// hugepages are mmapped, rather than faulted in), in bytes. Unlike Limit,
+	// it makes applications fail at mmap time instead of getting SIGBUS on
+	// page fault. Requires Linux 5.7 or later.
+	RsvdLimit *uint64 `json:"rsvd_limit,omitempty"`
 }
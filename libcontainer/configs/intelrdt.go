@@ -1,6 +1,14 @@
 package configs
 
 type IntelRdt struct {
+	// ClosID is the name of the resctrl group (Class of Service) to place
+	// the container into. It defaults to the container id. If it is set,
+	// the group may be shared with other containers; if it was created by
+	// runc (as recorded in the container's state), it is removed along with
+	// the container only if no tasks are left in it. If no schema is
+	// specified, the group must exist.
+	ClosID string `json:"closID,omitempty"`
+
 	// The schema for L3 cache id and capacity bitmask (CBM)
 	// Format: "L3:<cache_id0>=<cbm0>;<cache_id1>=<cbm1>;..."
 	L3CacheSchema string `json:"l3_cache_schema,omitempty"`
@@ -10,4 +18,16 @@ type IntelRdt struct {
 	// The unit of memory bandwidth is specified in "percentages" by
 	// default, and in "MBps" if MBA Software Controller is enabled.
 	MemBwSchema string `json:"memBwSchema,omitempty"`
+
+	// Schemata lists additional schema lines, for the resources other than
+	// L3 cache and memory bandwidth, in the same format.
+	// Format: "<resource>:<domain_id0>=<value0>;<domain_id1>=<value1>;..."
+	// e.g. "L2:0=ff;1=f0" or "L3CODE:0=fff;1=fff"
+	Schemata []string `json:"schemata,omitempty"`
+
+	// EnableMonitoring creates a monitoring group for the container (named
+	// after the container id) in the mon_groups directory of its resctrl
+	// group, so that CMT and MBM statistics are collected for the container
+	// only, even if its resctrl group is shared.
+	EnableMonitoring bool `json:"enableMonitoring,omitempty"`
 }
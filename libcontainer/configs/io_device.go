@@ -0,0 +1,143 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IoDevice identifies a block device for the cgroup v2 io controller, either
+// by its major:minor numbers, or by the path of its device node, which is
+// resolved when the cgroup configuration is applied.
+type IoDevice struct {
+	Major int64  `json:"major,omitempty"`
+	Minor int64  `json:"minor,omitempty"`
+	Path  string `json:"path,omitempty"`
+}
+
+// IoWeightDevice is a per-device io.weight entry.
+type IoWeightDevice struct {
+	IoDevice
+	// Weight is the device specific weight, range is from 1 to 10000.
+	Weight uint64 `json:"weight"`
+}
+
+// String formats the entry to be written to io.weight, for the device
+// identified by dev (in major:minor format).
+func (w *IoWeightDevice) String(dev string) string {
+	return fmt.Sprintf("%s %d", dev, w.Weight)
+}
+
+// IoMaxDevice is an io.max entry. Limits set to 0 are left unchanged, and
+// limits set to -1 are removed.
+type IoMaxDevice struct {
+	IoDevice
+	// Rbps and Wbps are the read and write limits, in bytes per second.
+	Rbps int64 `json:"rbps,omitempty"`
+	Wbps int64 `json:"wbps,omitempty"`
+	// Riops and Wiops are the read and write limits, in IO per second.
+	Riops int64 `json:"riops,omitempty"`
+	Wiops int64 `json:"wiops,omitempty"`
+}
+
+// String formats the entry to be written to io.max, for the device
+// identified by dev (in major:minor format).
+func (m *IoMaxDevice) String(dev string) string {
+	var b strings.Builder
+	b.WriteString(dev)
+	for _, limit := range []struct {
+		key   string
+		value int64
+	}{
+		{"rbps", m.Rbps},
+		{"wbps", m.Wbps},
+		{"riops", m.Riops},
+		{"wiops", m.Wiops},
+	} {
+		switch limit.value {
+		case 0:
+		case -1:
+			b.WriteString(" " + limit.key + "=max")
+		default:
+			b.WriteString(" " + limit.key + "=" + strconv.FormatInt(limit.value, 10))
+		}
+	}
+	return b.String()
+}
+
+// IoLatencyDevice is an io.latency entry.
+type IoLatencyDevice struct {
+	IoDevice
+	// Target is the latency target (in usecs); 0 removes the target.
+	Target uint64 `json:"target"`
+}
+
+// String formats the entry to be written to io.latency, for the device
+// identified by dev (in major:minor format).
+func (l *IoLatencyDevice) String(dev string) string {
+	if l.Target == 0 {
+		return dev + " target=max"
+	}
+	return fmt.Sprintf("%s target=%d", dev, l.Target)
+}
+
+// IoCostQos is an io.cost.qos entry, which configures the io.cost model
+// based controller for the device. Unlike other io settings, it only exists
+// in the root cgroup, so it affects the whole host. Parameters set to 0 (or
+// empty) are left unchanged.
+type IoCostQos struct {
+	IoDevice
+	// Enable enables or disables the controller for the device.
+	Enable bool `json:"enable"`
+	// Ctrl is either "auto" or "user".
+	Ctrl string `json:"ctrl,omitempty"`
+	// Rpct and Wpct are the read and write latency percentiles, and Rlat
+	// and Wlat the matching latency targets (in usecs).
+	Rpct float64 `json:"rpct,omitempty"`
+	Rlat uint64  `json:"rlat,omitempty"`
+	Wpct float64 `json:"wpct,omitempty"`
+	Wlat uint64  `json:"wlat,omitempty"`
+	// Min and Max are the bounds of the vrate scaling, as percentages.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+}
+
+// String formats the entry to be written to io.cost.qos, for the device
+// identified by dev (in major:minor format).
+func (q *IoCostQos) String(dev string) string {
+	var b strings.Builder
+	b.WriteString(dev)
+	if q.Enable {
+		b.WriteString(" enable=1")
+	} else {
+		b.WriteString(" enable=0")
+	}
+	if q.Ctrl != "" {
+		b.WriteString(" ctrl=" + q.Ctrl)
+	}
+	for _, p := range []struct {
+		key   string
+		value float64
+	}{
+		{"rpct", q.Rpct},
+		{"wpct", q.Wpct},
+		{"min", q.Min},
+		{"max", q.Max},
+	} {
+		if p.value != 0 {
+			b.WriteString(" " + p.key + "=" + strconv.FormatFloat(p.value, 'f', 2, 64))
+		}
+	}
+	for _, p := range []struct {
+		key   string
+		value uint64
+	}{
+		{"rlat", q.Rlat},
+		{"wlat", q.Wlat},
+	} {
+		if p.value != 0 {
+			b.WriteString(" " + p.key + "=" + strconv.FormatUint(p.value, 10))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package configs_test
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestIoDeviceStrings(t *testing.T) {
+	for _, tc := range []struct {
+		got, expected string
+	}{
+		{(&configs.IoWeightDevice{Weight: 200}).String("8:0"), "8:0 200"},
+		{(&configs.IoMaxDevice{Wbps: 1024, Riops: -1}).String("8:0"), "8:0 wbps=1024 riops=max"},
+		{(&configs.IoLatencyDevice{Target: 500}).String("8:0"), "8:0 target=500"},
+		{(&configs.IoLatencyDevice{}).String("8:0"), "8:0 target=max"},
+		{(&configs.IoCostQos{Enable: true, Ctrl: "user", Rpct: 95, Rlat: 5000, Max: 150}).String("8:0"), "8:0 enable=1 ctrl=user rpct=95.00 max=150.00 rlat=5000"},
+		{(&configs.IoCostQos{}).String("8:0"), "8:0 enable=0"},
+	} {
+		if tc.got != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, tc.got)
+		}
+	}
+}
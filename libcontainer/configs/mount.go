@@ -31,9 +31,20 @@ type Mount struct {
 	// Extensions are additional flags that are specific to runc.
 	Extensions int `json:"extensions"`
 
+	// RecAttr represents mount properties to be applied recursively
+	// (AT_RECURSIVE) to the mount and all its submounts, see mount_setattr(2).
+	RecAttr *MountAttr `json:"rec_attr,omitempty"`
+
 	// Optional Command to be run before Source is mounted.
 	PremountCmds []Command `json:"premount_cmds"`
 
 	// Optional Command to be run after Source is mounted.
 	PostmountCmds []Command `json:"postmount_cmds"`
 }
+
+// MountAttr holds the MOUNT_ATTR_* flags to set and clear on a mount, see
+// mount_setattr(2).
+type MountAttr struct {
+	AttrSet uint64 `json:"attr_set"`
+	AttrClr uint64 `json:"attr_clr"`
+}
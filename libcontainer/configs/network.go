@@ -5,10 +5,11 @@ package configs
 // The network configuration can be omitted from a container causing the
 // container to be setup with the host's networking stack
 type Network struct {
-	// Type sets the networks type, commonly veth and loopback
+	// Type sets the networks type, one of loopback, veth, macvlan, ipvlan and
+	// host-device
 	Type string `json:"type"`
 
-	// Name of the network interface
+	// Name of the network interface (inside the container)
 	Name string `json:"name"`
 
 	// The bridge to use.
@@ -40,7 +41,8 @@ type Network struct {
 	TxQueueLen int `json:"txqueuelen"`
 
 	// HostInterfaceName is a unique name of a veth pair that resides on in the host interface of the
-	// container.
+	// container. In the case of type host-device, it is the name of the host interface which is
+	// moved into the container's network namespace (and renamed to Name, if set).
 	HostInterfaceName string `json:"host_interface_name"`
 
 	// HairpinMode specifies if hairpin NAT should be enabled on the virtual interface
@@ -48,6 +50,23 @@ type Network struct {
 	// Note: This is unsupported on some systems.
 	// Note: This does not apply to loopback interfaces.
 	HairpinMode bool `json:"hairpin_mode"`
+
+	// Parent is the name of the host interface on top of which the
+	// container's interface is created, in the case of types macvlan and ipvlan.
+	Parent string `json:"parent,omitempty"`
+
+	// Mode is the macvlan (private, vepa, bridge or passthru) or ipvlan (l2,
+	// l3 or l3s) mode of the container's interface. Defaults to bridge and
+	// l2, respectively.
+	Mode string `json:"mode,omitempty"`
+
+	// HostInterfaceIndex and HostInterfaceAltName are set by runc when a
+	// host-device interface is moved into the container, to its index on
+	// the host and to an alternative name given to it (if supported by the
+	// kernel). They are used to find the interface once it is back to the
+	// host, whatever its name.
+	HostInterfaceIndex   int    `json:"host_interface_index,omitempty"`
+	HostInterfaceAltName string `json:"host_interface_alt_name,omitempty"`
 }
 
 // Route defines a routing table entry.
@@ -0,0 +1,35 @@
+package configs
+
+// RootfsLayers describes an overlay filesystem which is assembled at the
+// container's rootfs before the container is started.
+type RootfsLayers struct {
+	// Lower is the list of read-only layer directories, with the uppermost
+	// layer first.
+	Lower []string `json:"lower"`
+
+	// Upper is the writable layer directory, which receives all the changes
+	// made to the rootfs. It must be on the same filesystem as Work.
+	Upper string `json:"upper"`
+
+	// Work is the overlay filesystem's work directory.
+	Work string `json:"work"`
+
+	// UserXattr mounts the overlay filesystem with the userxattr option,
+	// from inside of the container's user namespace. This allows rootless
+	// containers to use it, and requires Linux 5.11 or later. Otherwise,
+	// the overlay filesystem is mounted on the host and unmounted when the
+	// container is destroyed.
+	UserXattr bool `json:"userxattr,omitempty"`
+}
+
+// WritablePath is a directory of a readonly rootfs which is made writable.
+// The changes made to it are discarded when the container is destroyed.
+type WritablePath struct {
+	// Path is the absolute path of the directory inside the container.
+	Path string `json:"path"`
+
+	// Overlay makes the directory writable by mounting an overlay filesystem
+	// on it, whose upper layer is on a tmpfs. Otherwise, a tmpfs is mounted
+	// on it, and the directory's contents are copied to the tmpfs.
+	Overlay bool `json:"overlay,omitempty"`
+}
@@ -0,0 +1,166 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/intelrdt"
+)
+
+// schemaChecker validates schema lines against the resources exposed by the
+// "resource control" filesystem.
+type schemaChecker struct {
+	isEnabled    func(resource string) bool
+	rootSchemata map[string]map[string]string
+	cacheInfo    func(resource string) (*intelrdt.CacheInfo, error)
+	sparseMasks  func(resource string) bool
+	memBwInfo    func() (*intelrdt.MemBwInfo, error)
+	mbaSc        bool
+}
+
+func newSchemaChecker() (*schemaChecker, error) {
+	rootSchemata, err := intelrdt.GetRootSchemata()
+	if err != nil {
+		return nil, err
+	}
+	return &schemaChecker{
+		isEnabled:    intelrdt.IsResourceEnabled,
+		rootSchemata: rootSchemata,
+		cacheInfo:    intelrdt.GetCacheInfo,
+		sparseMasks:  intelrdt.HasSparseMasks,
+		memBwInfo:    intelrdt.GetMemBwInfo,
+		mbaSc:        intelrdt.IsMBAScEnabled(),
+	}, nil
+}
+
+// intelRdtSchemata validates all the schema lines of the Intel RDT config.
+func intelRdtSchemata(c *configs.IntelRdt) error {
+	type schema struct {
+		field string
+		line  string
+		match func(resource string) bool
+	}
+	var schemata []schema
+	for _, line := range strings.Split(c.L3CacheSchema, "\n") {
+		schemata = append(schemata, schema{"l3CacheSchema", line, func(r string) bool {
+			return r == "L3" || r == "L3CODE" || r == "L3DATA"
+		}})
+	}
+	for _, line := range strings.Split(c.MemBwSchema, "\n") {
+		schemata = append(schemata, schema{"memBwSchema", line, func(r string) bool {
+			return r == "MB"
+		}})
+	}
+	for _, line := range c.Schemata {
+		schemata = append(schemata, schema{"schemata", line, nil})
+	}
+
+	var checker *schemaChecker
+	seen := make(map[string]bool)
+	for _, s := range schemata {
+		if strings.TrimSpace(s.line) == "" {
+			continue
+		}
+		if checker == nil {
+			var err error
+			if checker, err = newSchemaChecker(); err != nil {
+				return err
+			}
+		}
+		resource, err := checker.check(s.line)
+		if err != nil {
+			return fmt.Errorf("invalid intelRdt.%s: %w", s.field, err)
+		}
+		if s.match != nil && !s.match(resource) {
+			return fmt.Errorf("invalid intelRdt.%s: unexpected resource %s", s.field, resource)
+		}
+		if seen[resource] {
+			return fmt.Errorf("invalid intelRdt.%s: resource %s is specified more than once", s.field, resource)
+		}
+		seen[resource] = true
+	}
+	return nil
+}
+
+// check validates a schema line, and returns the name of its resource.
+func (c *schemaChecker) check(line string) (string, error) {
+	resource, domains, err := intelrdt.ParseSchema(line)
+	if err != nil {
+		return "", err
+	}
+	if !c.isEnabled(resource) {
+		return "", fmt.Errorf("resource %s is not enabled", resource)
+	}
+	for id, value := range domains {
+		if _, ok := c.rootSchemata[resource][id]; !ok {
+			return "", fmt.Errorf("%s: domain %s does not exist", resource, id)
+		}
+		switch {
+		case intelrdt.IsCacheResource(resource):
+			err = c.checkCbm(resource, value)
+		case resource == "MB":
+			err = c.checkBandwidth(value)
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: domain %s: %w", resource, id, err)
+		}
+	}
+	return resource, nil
+}
+
+// checkCbm validates the capacity bitmask of a cache resource.
+func (c *schemaChecker) checkCbm(resource, value string) error {
+	cbm, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid capacity bitmask %q", value)
+	}
+	info, err := c.cacheInfo(resource)
+	if err != nil {
+		return err
+	}
+	mask, err := strconv.ParseUint(info.CbmMask, 16, 64)
+	if err != nil {
+		return fmt.Errorf("invalid cbm_mask %q", info.CbmMask)
+	}
+	if cbm&^mask != 0 {
+		return fmt.Errorf("capacity bitmask %s exceeds %s", value, info.CbmMask)
+	}
+	if uint64(bits.OnesCount64(cbm)) < info.MinCbmBits {
+		return fmt.Errorf("capacity bitmask %s has less than %d bits set", value, info.MinCbmBits)
+	}
+	if !c.sparseMasks(resource) {
+		if v := cbm >> bits.TrailingZeros64(cbm); v&(v+1) != 0 {
+			return fmt.Errorf("capacity bitmask %s is not contiguous", value)
+		}
+	}
+	return nil
+}
+
+// checkBandwidth validates a memory bandwidth value.
+func (c *schemaChecker) checkBandwidth(value string) error {
+	bw, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid memory bandwidth %q", value)
+	}
+	// With MBA Software Controller, the bandwidth is in MBps.
+	if c.mbaSc {
+		return nil
+	}
+	info, err := c.memBwInfo()
+	if err != nil {
+		return err
+	}
+	if bw < info.MinBandwidth {
+		return fmt.Errorf("memory bandwidth %d is less than %d", bw, info.MinBandwidth)
+	}
+	// AMD takes the bandwidth in units of 1/8 GBps rather than as a
+	// percentage, and reports neither a minimum nor a granularity.
+	if (info.MinBandwidth > 0 || info.BandwidthGran > 1) && bw > 100 {
+		return errors.New("memory bandwidth is a percentage, and can not exceed 100")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/intelrdt"
+)
+
+func newTestSchemaChecker(mbaSc bool, memBwInfo intelrdt.MemBwInfo) *schemaChecker {
+	resources := map[string]bool{"L3CODE": true, "L3DATA": true, "L2": true, "MB": true}
+	return &schemaChecker{
+		isEnabled: func(resource string) bool { return resources[resource] },
+		rootSchemata: map[string]map[string]string{
+			"L3CODE": {"0": "7ff", "1": "7ff"},
+			"L3DATA": {"0": "7ff", "1": "7ff"},
+			"L2":     {"0": "ff"},
+			"MB":     {"0": "100", "1": "100"},
+		},
+		cacheInfo: func(resource string) (*intelrdt.CacheInfo, error) {
+			if resource == "L2" {
+				return &intelrdt.CacheInfo{CbmMask: "ff", MinCbmBits: 1}, nil
+			}
+			return &intelrdt.CacheInfo{CbmMask: "7ff", MinCbmBits: 2}, nil
+		},
+		sparseMasks: func(resource string) bool { return resource == "L2" },
+		memBwInfo: func() (*intelrdt.MemBwInfo, error) {
+			return &memBwInfo, nil
+		},
+		mbaSc: mbaSc,
+	}
+}
+
+func TestSchemaChecker(t *testing.T) {
+	intel := intelrdt.MemBwInfo{BandwidthGran: 10, MinBandwidth: 10, DelayLinear: 1}
+	amd := intelrdt.MemBwInfo{BandwidthGran: 1}
+	testCases := []struct {
+		line      string
+		mbaSc     bool
+		memBwInfo intelrdt.MemBwInfo
+		isError   bool
+	}{
+		{line: "L3CODE:0=7f0;1=1f", memBwInfo: intel},
+		{line: "L3DATA:0=3", memBwInfo: intel},
+		{line: "L2:0=a5", memBwInfo: intel},
+		{line: "MB:0=20;1=70", memBwInfo: intel},
+		{line: "MB:0=5000", mbaSc: true, memBwInfo: intel},
+		{line: "MB:0=2048", memBwInfo: amd},
+		{line: "L3:0=7f0", memBwInfo: intel, isError: true},
+		{line: "L3CODE:2=7f0", memBwInfo: intel, isError: true},
+		{line: "L3CODE:0=xyz", memBwInfo: intel, isError: true},
+		{line: "L3CODE:0=ff0", memBwInfo: intel, isError: true},
+		{line: "L3CODE:0=1", memBwInfo: intel, isError: true},
+		{line: "L3CODE:0=505", memBwInfo: intel, isError: true},
+		{line: "MB:0=5", memBwInfo: intel, isError: true},
+		{line: "MB:0=200", memBwInfo: intel, isError: true},
+		{line: "MB:0=fast", memBwInfo: amd, isError: true},
+	}
+	for _, tc := range testCases {
+		_, err := newTestSchemaChecker(tc.mbaSc, tc.memBwInfo).check(tc.line)
+		if tc.isError && err == nil {
+			t.Errorf("%q: expected error, got nil", tc.line)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.line, err)
+		}
+	}
+}
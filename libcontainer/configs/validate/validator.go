@@ -3,14 +3,17 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/system"
 	selinux "github.com/opencontainers/selinux/go-selinux"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
@@ -32,6 +35,8 @@ func (v *ConfigValidator) Validate(config *configs.Config) error {
 	checks := []check{
 		v.cgroups,
 		v.rootfs,
+		v.rootfsLayers,
+		v.writablePaths,
 		v.network,
 		v.hostname,
 		v.security,
@@ -80,12 +85,200 @@ func (v *ConfigValidator) rootfs(config *configs.Config) error {
 	return nil
 }
 
+// rootfsLayers validates the layers of an overlay rootfs, if any.
+func (v *ConfigValidator) rootfsLayers(config *configs.Config) error {
+	l := config.RootfsLayers
+	if l == nil {
+		return nil
+	}
+	if len(l.Lower) == 0 {
+		return errors.New("rootfs layers: at least one lower layer is required")
+	}
+	if l.Upper == "" || l.Work == "" {
+		return errors.New("rootfs layers: upper and work directories are required")
+	}
+	if filepath.Clean(l.Upper) == filepath.Clean(l.Work) {
+		return errors.New("rootfs layers: upper and work directories must be different")
+	}
+	for _, dir := range append([]string{l.Upper, l.Work}, l.Lower...) {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("rootfs layers: %s is not an absolute path", dir)
+		}
+		// Commas and colons separate overlay mount options and lower
+		// layers, respectively.
+		if strings.ContainsAny(dir, ",:") {
+			return fmt.Errorf("rootfs layers: %s contains an invalid character", dir)
+		}
+	}
+	if l.UserXattr {
+		if !config.Namespaces.Contains(configs.NEWUSER) || !config.Namespaces.Contains(configs.NEWNS) {
+			return errors.New("rootfs layers: userxattr requires private user and mount namespaces")
+		}
+	} else if config.RootlessEUID {
+		return errors.New("rootfs layers: rootless containers require userxattr")
+	}
+	return nil
+}
+
+// writablePaths validates the writable paths of a readonly rootfs.
+func (v *ConfigValidator) writablePaths(config *configs.Config) error {
+	if len(config.WritablePaths) == 0 {
+		return nil
+	}
+	if !config.Readonlyfs {
+		return errors.New("writable paths require a readonly rootfs")
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("unable to make paths writable without a private MNT namespace")
+	}
+	seen := make(map[string]bool)
+	for _, p := range config.WritablePaths {
+		if !filepath.IsAbs(p.Path) {
+			return fmt.Errorf("writable path %s is not an absolute path", p.Path)
+		}
+		path := filepath.Clean(p.Path)
+		if path == "/" {
+			return errors.New("writable path / conflicts with the readonly rootfs")
+		}
+		if seen[path] {
+			return fmt.Errorf("writable path %s is duplicated", p.Path)
+		}
+		seen[path] = true
+		// Writable paths are mounted before the mounts from the config,
+		// so one beneath a mount destination would be hidden by it.
+		for _, m := range config.Mounts {
+			dest := filepath.Clean(m.Destination)
+			if dest != "/" && strings.HasPrefix(path, dest+"/") {
+				return fmt.Errorf("writable path %s would be hidden by the mount on %s", p.Path, m.Destination)
+			}
+		}
+	}
+	return nil
+}
+
 func (v *ConfigValidator) network(config *configs.Config) error {
 	if !config.Namespaces.Contains(configs.NEWNET) {
 		if len(config.Networks) > 0 || len(config.Routes) > 0 {
 			return errors.New("unable to apply network settings without a private NET namespace")
 		}
 	}
+	for _, n := range config.Networks {
+		switch n.Type {
+		case "loopback":
+		case "veth":
+			if n.Bridge == "" {
+				return fmt.Errorf("network %s: bridge is not specified", n.Name)
+			}
+			if err := validateInterfaceName(n.HostInterfaceName); err != nil {
+				return fmt.Errorf("network %s: host interface name: %w", n.Name, err)
+			}
+			if err := validateInterface(n); err != nil {
+				return err
+			}
+		case "host-device":
+			if err := validateInterfaceName(n.HostInterfaceName); err != nil {
+				return fmt.Errorf("network %s: host interface name: %w", n.Name, err)
+			}
+			if n.Name == "" {
+				// The interface keeps its name inside the container.
+				m := *n
+				m.Name = n.HostInterfaceName
+				n = &m
+			}
+			if err := validateInterface(n); err != nil {
+				return err
+			}
+		case "macvlan", "ipvlan":
+			if err := validateInterfaceName(n.Parent); err != nil {
+				return fmt.Errorf("network %s: parent interface name: %w", n.Name, err)
+			}
+			modes := macvlanModes
+			if n.Type == "ipvlan" {
+				modes = ipvlanModes
+			}
+			if n.Mode != "" && !modes[n.Mode] {
+				return fmt.Errorf("network %s: invalid %s mode %q", n.Name, n.Type, n.Mode)
+			}
+			if err := validateInterface(n); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown network type %q", n.Type)
+		}
+	}
+	for _, r := range config.Routes {
+		if err := validateRoute(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	macvlanModes = map[string]bool{"private": true, "vepa": true, "bridge": true, "passthru": true}
+	ipvlanModes  = map[string]bool{"l2": true, "l3": true, "l3s": true}
+)
+
+func validateRoute(r *configs.Route) error {
+	if r.Destination == "" && r.Source == "" && r.Gateway == "" {
+		return errors.New("route: one of destination, source and gateway must be specified")
+	}
+	if r.Destination != "" {
+		if _, _, err := net.ParseCIDR(r.Destination); err != nil {
+			return fmt.Errorf("route: %w", err)
+		}
+	}
+	if r.Source != "" && net.ParseIP(r.Source) == nil {
+		return fmt.Errorf("route: invalid source %q", r.Source)
+	}
+	if r.Gateway != "" && net.ParseIP(r.Gateway) == nil {
+		return fmt.Errorf("route: invalid gateway %q", r.Gateway)
+	}
+	if r.InterfaceName != "" {
+		if err := validateInterfaceName(r.InterfaceName); err != nil {
+			return fmt.Errorf("route: interface name: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateInterfaceName(name string) error {
+	if name == "" {
+		return errors.New("not specified")
+	}
+	if len(name) >= unix.IFNAMSIZ || strings.ContainsAny(name, "/: \t\n") || name == "." || name == ".." {
+		return fmt.Errorf("invalid interface name %q", name)
+	}
+	return nil
+}
+
+// validateInterface checks the settings of a network interface which is
+// configured inside the container.
+func validateInterface(n *configs.Network) error {
+	if err := validateInterfaceName(n.Name); err != nil {
+		return fmt.Errorf("network interface name: %w", err)
+	}
+	if n.MacAddress != "" {
+		if _, err := net.ParseMAC(n.MacAddress); err != nil {
+			return fmt.Errorf("network %s: %w", n.Name, err)
+		}
+	}
+	for _, address := range []string{n.Address, n.IPv6Address} {
+		if address == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			return fmt.Errorf("network %s: %w", n.Name, err)
+		}
+	}
+	for _, gateway := range []string{n.Gateway, n.IPv6Gateway} {
+		if gateway != "" && net.ParseIP(gateway) == nil {
+			return fmt.Errorf("network %s: invalid gateway %q", n.Name, gateway)
+		}
+	}
+	if n.Mtu < 0 {
+		return fmt.Errorf("network %s: invalid mtu %d", n.Name, n.Mtu)
+	}
 	return nil
 }
 
@@ -201,7 +394,7 @@ func (v *ConfigValidator) sysctl(config *configs.Config) error {
 
 func (v *ConfigValidator) intelrdt(config *configs.Config) error {
 	if config.IntelRdt != nil {
-		if !intelrdt.IsCATEnabled() && !intelrdt.IsMBAEnabled() {
+		if !intelrdt.IsCATEnabled() && !intelrdt.IsL2CATEnabled() && !intelrdt.IsMBAEnabled() {
 			return errors.New("intelRdt is specified in config, but Intel RDT is not supported or enabled")
 		}
 
@@ -212,6 +405,21 @@ func (v *ConfigValidator) intelrdt(config *configs.Config) error {
 			return errors.New("intelRdt.memBwSchema is specified in config, but Intel RDT/MBA is not enabled")
 		}
 
+		if config.IntelRdt.EnableMonitoring && !intelrdt.IsCMTEnabled() && !intelrdt.IsMBMEnabled() {
+			return errors.New("intelRdt.enableMonitoring is specified in config, but Intel RDT/CMT and MBM are not enabled")
+		}
+		if err := intelRdtSchemata(config.IntelRdt); err != nil {
+			return err
+		}
+
+		if closID := config.IntelRdt.ClosID; closID != "" {
+			// A shared group may be pre-configured, or configured
+			// by another container, so the schemata are optional.
+			if err := validateClosID(closID); err != nil {
+				return err
+			}
+			return nil
+		}
 		if intelrdt.IsCATEnabled() && config.IntelRdt.L3CacheSchema == "" {
 			return errors.New("Intel RDT/CAT is enabled and intelRdt is specified in config, but intelRdt.l3CacheSchema is empty")
 		}
@@ -223,6 +431,19 @@ func (v *ConfigValidator) intelrdt(config *configs.Config) error {
 	return nil
 }
 
+// validateClosID checks that closID can be used as the name of a resctrl
+// group, which must not clash with the files of the resctrl root.
+func validateClosID(closID string) error {
+	switch closID {
+	case ".", "..", "info", "mon_groups", "mon_data":
+		return fmt.Errorf("invalid intelRdt.closID %q", closID)
+	}
+	if strings.Contains(closID, "/") {
+		return fmt.Errorf("invalid intelRdt.closID %q", closID)
+	}
+	return nil
+}
+
 func (v *ConfigValidator) cgroups(config *configs.Config) error {
 	c := config.Cgroups
 	if c == nil {
@@ -233,6 +454,13 @@ func (v *ConfigValidator) cgroups(config *configs.Config) error {
 		return fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c)
 	}
 
+	if err := delegate(c); err != nil {
+		return err
+	}
+	if err := systemdUnit(c); err != nil {
+		return err
+	}
+
 	r := c.Resources
 	if r == nil {
 		return nil
@@ -242,6 +470,10 @@ func (v *ConfigValidator) cgroups(config *configs.Config) error {
 		return cgroups.ErrV1NoUnified
 	}
 
+	if !cgroups.IsCgroup2UnifiedMode() && r.DevicesAudit {
+		return errors.New("devices audit requires cgroup v2")
+	}
+
 	if cgroups.IsCgroup2UnifiedMode() {
 		_, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
 		if err != nil {
@@ -249,6 +481,231 @@ func (v *ConfigValidator) cgroups(config *configs.Config) error {
 		}
 	}
 
+	if err := cpu(r); err != nil {
+		return err
+	}
+	if err := ioPolicy(config); err != nil {
+		return err
+	}
+	return memory(r)
+}
+
+// delegate checks the cgroup delegation settings.
+func delegate(c *configs.Cgroup) error {
+	d := c.Delegate
+	if d == nil {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("cgroup delegation requires cgroup v2")
+	}
+	if c.Paths != nil {
+		return errors.New("cgroup delegation can not be used when joining existing cgroups")
+	}
+	if d.Leaf == "." || d.Leaf == ".." || strings.Contains(d.Leaf, "/") {
+		return fmt.Errorf("invalid cgroup delegation leaf %q", d.Leaf)
+	}
+	for _, ctr := range d.Controllers {
+		// Names must not have a "+" or "-" prefix, which is added when
+		// writing to cgroup.subtree_control.
+		if ctr == "" || strings.ContainsAny(ctr, " \t\n") || strings.TrimLeft(ctr, "+-") != ctr {
+			return fmt.Errorf("invalid cgroup delegation controller %q", ctr)
+		}
+	}
+	if d.UID < 0 || d.GID < 0 {
+		return fmt.Errorf("invalid cgroup delegation owner %d:%d", d.UID, d.GID)
+	}
+	return nil
+}
+
+// systemdUnit checks the systemd unit settings.
+func systemdUnit(c *configs.Cgroup) error {
+	u := c.Unit
+	if u == nil {
+		return nil
+	}
+	switch u.CollectMode {
+	case "", "inactive", "inactive-or-failed":
+	default:
+		return fmt.Errorf("invalid systemd unit collect mode %q", u.CollectMode)
+	}
+	for _, units := range [][]string{u.After, u.Before, u.Wants, u.Requires, u.BindsTo, u.PartOf} {
+		for _, unit := range units {
+			if unit == "" || strings.ContainsAny(unit, " \t\n/") {
+				return fmt.Errorf("invalid systemd unit dependency %q", unit)
+			}
+		}
+	}
+	return nil
+}
+
+// ioPolicy checks the cgroup v2 io controller settings.
+func ioPolicy(config *configs.Config) error {
+	r := config.Cgroups.Resources
+	if r.IoWeight == 0 && len(r.IoWeightDevice) == 0 && len(r.IoMax) == 0 &&
+		len(r.IoLatency) == 0 && len(r.IoCostQos) == 0 {
+		return nil
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("io weight, max, latency and cost qos settings require cgroup v2")
+	}
+	checkDevice := func(d *configs.IoDevice) error {
+		if d.Path == "" {
+			return nil
+		}
+		if !filepath.IsAbs(d.Path) {
+			return fmt.Errorf("io device path %q is not absolute", d.Path)
+		}
+		if d.Major != 0 || d.Minor != 0 {
+			return fmt.Errorf("io device %s: either path or major:minor should be used", d.Path)
+		}
+		return nil
+	}
+	if r.IoWeight > 10000 {
+		return fmt.Errorf("invalid io weight %d (valid range is 1-10000)", r.IoWeight)
+	}
+	for _, wd := range r.IoWeightDevice {
+		if err := checkDevice(&wd.IoDevice); err != nil {
+			return err
+		}
+		if wd.Weight < 1 || wd.Weight > 10000 {
+			return fmt.Errorf("invalid io device weight %d (valid range is 1-10000)", wd.Weight)
+		}
+	}
+	for _, m := range r.IoMax {
+		if err := checkDevice(&m.IoDevice); err != nil {
+			return err
+		}
+		if m.Rbps < -1 || m.Wbps < -1 || m.Riops < -1 || m.Wiops < -1 {
+			return fmt.Errorf("invalid io max limits %+v", *m)
+		}
+	}
+	for _, l := range r.IoLatency {
+		if err := checkDevice(&l.IoDevice); err != nil {
+			return err
+		}
+	}
+	if len(r.IoCostQos) > 0 && config.RootlessCgroups {
+		return errors.New("io cost qos can not be set for rootless containers, as it affects the whole host")
+	}
+	for _, q := range r.IoCostQos {
+		if err := checkDevice(&q.IoDevice); err != nil {
+			return err
+		}
+		if q.Ctrl != "" && q.Ctrl != "auto" && q.Ctrl != "user" {
+			return fmt.Errorf("invalid io cost qos ctrl %q (must be auto or user)", q.Ctrl)
+		}
+		if q.Rpct < 0 || q.Rpct > 100 || q.Wpct < 0 || q.Wpct > 100 {
+			return errors.New("io cost qos percentiles must be between 0 and 100")
+		}
+		if q.Min < 0 || q.Max < 0 || (q.Max != 0 && q.Min > q.Max) {
+			return errors.New("invalid io cost qos min and max")
+		}
+	}
+	return nil
+}
+
+// cpu checks the CPU burst, idle and utilization clamp knobs.
+func cpu(r *configs.Resources) error {
+	if r.CpuBurst != nil && r.CpuQuota > 0 && *r.CpuBurst > uint64(r.CpuQuota) {
+		return errors.New("cpu burst must not be greater than cpu quota")
+	}
+	if r.CpuIdle != nil {
+		if *r.CpuIdle != 0 && *r.CpuIdle != 1 {
+			return fmt.Errorf("invalid cpu idle value: %d (must be 0 or 1)", *r.CpuIdle)
+		}
+		if *r.CpuIdle == 1 && (r.CpuShares != 0 || r.CpuWeight != 0) {
+			return errors.New("cpu idle can not be set along with cpu shares or weight")
+		}
+	}
+	uclampMin, err := parseUclamp(r.CpuUclampMin)
+	if err != nil {
+		return fmt.Errorf("invalid cpu uclamp min: %w", err)
+	}
+	uclampMax, err := parseUclamp(r.CpuUclampMax)
+	if err != nil {
+		return fmt.Errorf("invalid cpu uclamp max: %w", err)
+	}
+	if r.CpuUclampMin != "" && r.CpuUclampMax != "" && uclampMin > uclampMax {
+		return errors.New("cpu uclamp min must not be greater than cpu uclamp max")
+	}
+	return nil
+}
+
+// parseUclamp parses a utilization clamp value, in hundredths of percent.
+func parseUclamp(value string) (int, error) {
+	if value == "" || value == "max" {
+		return 10000, nil
+	}
+	parts := strings.SplitN(value, ".", 2)
+	if len(parts) == 2 && (len(parts[1]) == 0 || len(parts[1]) > 2) {
+		return 0, fmt.Errorf("%q has more than two decimal places", value)
+	}
+	percent, err := strconv.ParseUint(parts[0], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a percentage", value)
+	}
+	hundredths := int(percent) * 100
+	if len(parts) == 2 {
+		frac, err := strconv.ParseUint(parts[1], 10, 8)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a percentage", value)
+		}
+		if len(parts[1]) == 1 {
+			frac *= 10
+		}
+		hundredths += int(frac)
+	}
+	if hundredths > 10000 {
+		return 0, fmt.Errorf("%q is greater than 100", value)
+	}
+	return hundredths, nil
+}
+
+// memory checks the memory knobs which are specific to cgroup v2.
+func memory(r *configs.Resources) error {
+	for _, knob := range []struct {
+		name  string
+		value *int64
+	}{
+		{"memory high", r.MemoryHigh},
+		{"memory low", r.MemoryLow},
+		{"memory min", r.MemoryMin},
+	} {
+		if knob.value != nil && *knob.value < -1 {
+			return fmt.Errorf("invalid %s value: %d", knob.name, *knob.value)
+		}
+	}
+	// Unlike the protections, memory high can not be removed by setting it
+	// to 0, which throttles all the memory usage; -1 is used for that.
+	if r.MemoryHigh != nil && *r.MemoryHigh == 0 {
+		return errors.New("invalid memory high value: 0 (use -1 to remove the limit)")
+	}
+	if r.MemoryMin != nil && r.MemoryLow != nil &&
+		*r.MemoryMin > 0 && *r.MemoryLow > 0 && *r.MemoryMin > *r.MemoryLow {
+		return errors.New("memory min must not be greater than memory low")
+	}
+	if r.MemorySwapMax != nil && *r.MemorySwapMax < -1 {
+		return fmt.Errorf("invalid memory swap max value: %d", *r.MemorySwapMax)
+	}
+
+	if cgroups.IsCgroup2UnifiedMode() {
+		return nil
+	}
+	if r.MemoryHigh != nil {
+		return errors.New("memory high requires cgroup v2")
+	}
+	if r.MemoryMin != nil && *r.MemoryMin != 0 {
+		return errors.New("memory min requires cgroup v2")
+	}
+	if r.MemoryOomGroup != nil && *r.MemoryOomGroup {
+		return errors.New("memory oom group requires cgroup v2")
+	}
+	if r.MemorySwapMax != nil {
+		if _, err := cgroups.ConvertMemorySwapMaxToCgroupV1Value(*r.MemorySwapMax, r.Memory); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -257,6 +714,9 @@ func (v *ConfigValidator) mounts(config *configs.Config) error {
 		if !filepath.IsAbs(m.Destination) {
 			return fmt.Errorf("invalid mount %+v: mount destination not absolute", m)
 		}
+		if m.RecAttr != nil && !system.HaveMountSetattr() {
+			return fmt.Errorf("invalid mount %+v: recursive mount attributes require mount_setattr(2), available since Linux 5.12", m)
+		}
 	}
 
 	return nil
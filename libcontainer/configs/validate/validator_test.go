@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"golang.org/x/sys/unix"
@@ -41,6 +42,102 @@ func TestValidateWithInvalidRootfs(t *testing.T) {
 	}
 }
 
+func TestValidateRootfsLayers(t *testing.T) {
+	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
+		t.Skip("Test requires userns.")
+	}
+	valid := configs.RootfsLayers{
+		Lower: []string{"/layers/2", "/layers/1"},
+		Upper: "/layers/upper",
+		Work:  "/layers/work",
+	}
+	userns := configs.Namespaces([]configs.Namespace{{Type: configs.NEWUSER}, {Type: configs.NEWNS}})
+	tests := []struct {
+		name     string
+		modify   func(*configs.RootfsLayers)
+		ns       configs.Namespaces
+		rootless bool
+		isError  bool
+	}{
+		{name: "valid"},
+		{name: "userxattr", modify: func(l *configs.RootfsLayers) { l.UserXattr = true }, ns: userns},
+		{name: "rootless userxattr", modify: func(l *configs.RootfsLayers) { l.UserXattr = true }, ns: userns, rootless: true},
+		{name: "no lower", modify: func(l *configs.RootfsLayers) { l.Lower = nil }, isError: true},
+		{name: "no upper", modify: func(l *configs.RootfsLayers) { l.Upper = "" }, isError: true},
+		{name: "same upper and work", modify: func(l *configs.RootfsLayers) { l.Work = "/layers/upper/" }, isError: true},
+		{name: "relative lower", modify: func(l *configs.RootfsLayers) { l.Lower = []string{"layers/1"} }, isError: true},
+		{name: "colon", modify: func(l *configs.RootfsLayers) { l.Upper = "/layers/a:b" }, isError: true},
+		{name: "comma", modify: func(l *configs.RootfsLayers) { l.Work = "/layers/a,b" }, isError: true},
+		{name: "userxattr without userns", modify: func(l *configs.RootfsLayers) { l.UserXattr = true }, isError: true},
+		{name: "rootless without userxattr", ns: userns, rootless: true, isError: true},
+	}
+	for _, tc := range tests {
+		layers := valid
+		layers.Lower = append([]string(nil), valid.Lower...)
+		if tc.modify != nil {
+			tc.modify(&layers)
+		}
+		config := &configs.Config{
+			Rootfs:       "/var",
+			RootfsLayers: &layers,
+			Namespaces:   tc.ns,
+			RootlessEUID: tc.rootless,
+		}
+		if tc.rootless {
+			config.UidMappings = []configs.IDMap{{HostID: os.Geteuid(), Size: 1}}
+			config.GidMappings = []configs.IDMap{{HostID: os.Getegid(), Size: 1}}
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateWritablePaths(t *testing.T) {
+	mntns := configs.Namespaces([]configs.Namespace{{Type: configs.NEWNS}})
+	tests := []struct {
+		name       string
+		paths      []configs.WritablePath
+		readonlyfs bool
+		ns         configs.Namespaces
+		mounts     []*configs.Mount
+		isError    bool
+	}{
+		{name: "valid", paths: []configs.WritablePath{{Path: "/tmp"}, {Path: "/var/lib", Overlay: true}}, readonlyfs: true, ns: mntns},
+		{name: "mount destination", paths: []configs.WritablePath{{Path: "/var/lib"}}, readonlyfs: true, ns: mntns, mounts: []*configs.Mount{{Destination: "/var/lib"}}},
+		{name: "mount inside", paths: []configs.WritablePath{{Path: "/etc"}}, readonlyfs: true, ns: mntns, mounts: []*configs.Mount{{Destination: "/etc/resolv.conf"}}},
+		{name: "mount above", paths: []configs.WritablePath{{Path: "/var/lib/data"}}, readonlyfs: true, ns: mntns, mounts: []*configs.Mount{{Destination: "/var/lib/"}}, isError: true},
+		{name: "mount sibling", paths: []configs.WritablePath{{Path: "/var/library"}}, readonlyfs: true, ns: mntns, mounts: []*configs.Mount{{Destination: "/var/lib"}}},
+		{name: "read-write rootfs", paths: []configs.WritablePath{{Path: "/tmp"}}, ns: mntns, isError: true},
+		{name: "no mntns", paths: []configs.WritablePath{{Path: "/tmp"}}, readonlyfs: true, isError: true},
+		{name: "relative", paths: []configs.WritablePath{{Path: "tmp"}}, readonlyfs: true, ns: mntns, isError: true},
+		{name: "root", paths: []configs.WritablePath{{Path: "/"}}, readonlyfs: true, ns: mntns, isError: true},
+		{name: "duplicated", paths: []configs.WritablePath{{Path: "/tmp"}, {Path: "/tmp/", Overlay: true}}, readonlyfs: true, ns: mntns, isError: true},
+	}
+	for _, tc := range tests {
+		config := &configs.Config{
+			Rootfs:        "/var",
+			Readonlyfs:    tc.readonlyfs,
+			WritablePaths: tc.paths,
+			Namespaces:    tc.ns,
+			Mounts:        tc.mounts,
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
 func TestValidateNetworkWithoutNETNamespace(t *testing.T) {
 	network := &configs.Network{Type: "loopback"}
 	config := &configs.Config{
@@ -56,6 +153,116 @@ func TestValidateNetworkWithoutNETNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateNetworkVeth(t *testing.T) {
+	valid := configs.Network{
+		Type:              "veth",
+		Name:              "eth0",
+		Bridge:            "br0",
+		HostInterfaceName: "veth0123",
+		Address:           "10.0.0.2/24",
+		Gateway:           "10.0.0.1",
+		IPv6Address:       "fd00::2/64",
+		MacAddress:        "02:42:ac:11:00:02",
+		Mtu:               1500,
+	}
+	tests := []struct {
+		modify  func(*configs.Network)
+		isError bool
+	}{
+		{modify: func(n *configs.Network) {}},
+		{modify: func(n *configs.Network) { n.Bridge = "" }, isError: true},
+		{modify: func(n *configs.Network) { n.HostInterfaceName = "" }, isError: true},
+		{modify: func(n *configs.Network) { n.HostInterfaceName = "a-much-too-long-name" }, isError: true},
+		{modify: func(n *configs.Network) { n.Name = "eth/0" }, isError: true},
+		{modify: func(n *configs.Network) { n.Address = "10.0.0.2" }, isError: true},
+		{modify: func(n *configs.Network) { n.Gateway = "gateway" }, isError: true},
+		{modify: func(n *configs.Network) { n.MacAddress = "02:42" }, isError: true},
+		{modify: func(n *configs.Network) { n.Type = "bogus" }, isError: true},
+	}
+	for i, tc := range tests {
+		network := valid
+		tc.modify(&network)
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: configs.Namespaces([]configs.Namespace{{Type: configs.NEWNET}}),
+			Networks:   []*configs.Network{&network},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%d: expected error, got nil (%+v)", i, network)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestValidateNetworkSubInterface(t *testing.T) {
+	tests := []struct {
+		network configs.Network
+		isError bool
+	}{
+		{network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eth1"}},
+		{network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eth1", Mode: "vepa", Address: "192.0.2.2/24"}},
+		{network: configs.Network{Type: "ipvlan", Name: "eth0", Parent: "eth1", Mode: "l3s"}},
+		{network: configs.Network{Type: "macvlan", Name: "eth0"}, isError: true},
+		{network: configs.Network{Type: "macvlan", Name: "eth0", Parent: "eth1", Mode: "l2"}, isError: true},
+		{network: configs.Network{Type: "ipvlan", Name: "eth0", Parent: "eth1", Mode: "bridge"}, isError: true},
+		{network: configs.Network{Type: "ipvlan", Parent: "eth1"}, isError: true},
+		{network: configs.Network{Type: "host-device", HostInterfaceName: "enp1s0f0v1"}},
+		{network: configs.Network{Type: "host-device", Name: "eth0", HostInterfaceName: "enp1s0f0v1"}},
+		{network: configs.Network{Type: "host-device", Name: "eth0"}, isError: true},
+	}
+	for _, tc := range tests {
+		network := tc.network
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: configs.Namespaces([]configs.Namespace{{Type: configs.NEWNET}}),
+			Networks:   []*configs.Network{&network},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("expected error, got nil (%+v)", network)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%+v: unexpected error: %v", network, err)
+		}
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	tests := []struct {
+		route   configs.Route
+		isError bool
+	}{
+		{route: configs.Route{Gateway: "192.0.2.1"}},
+		{route: configs.Route{Destination: "198.51.100.0/24", InterfaceName: "eth0"}},
+		{route: configs.Route{Destination: "2001:db8::/32", Source: "2001:db8::2", Gateway: "fe80::1"}},
+		{route: configs.Route{InterfaceName: "eth0"}, isError: true},
+		{route: configs.Route{Destination: "198.51.100.0"}, isError: true},
+		{route: configs.Route{Gateway: "gateway"}, isError: true},
+		{route: configs.Route{Gateway: "192.0.2.1", Source: "source"}, isError: true},
+	}
+	for _, tc := range tests {
+		route := tc.route
+		config := &configs.Config{
+			Rootfs:     "/var",
+			Namespaces: configs.Namespaces([]configs.Namespace{{Type: configs.NEWNET}}),
+			Routes:     []*configs.Route{&route},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("expected error, got nil (%+v)", route)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%+v: unexpected error: %v", route, err)
+		}
+	}
+}
+
 func TestValidateNetworkRoutesWithoutNETNamespace(t *testing.T) {
 	route := &configs.Route{Gateway: "255.255.255.0"}
 	config := &configs.Config{
@@ -352,3 +559,200 @@ func TestValidateMounts(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateMemory(t *testing.T) {
+	i64 := func(i int64) *int64 { return &i }
+	v2 := cgroups.IsCgroup2UnifiedMode()
+	tests := []struct {
+		name      string
+		resources configs.Resources
+		isError   bool
+	}{
+		{name: "low", resources: configs.Resources{MemoryLow: i64(1 << 20)}},
+		{name: "low and reservation", resources: configs.Resources{MemoryLow: i64(1 << 20), MemoryReservation: 2 << 20}},
+		{name: "swap max", resources: configs.Resources{Memory: 1 << 30, MemorySwapMax: i64(0)}},
+		{name: "invalid swap max", resources: configs.Resources{Memory: 1 << 30, MemorySwapMax: i64(-2)}, isError: true},
+		{name: "invalid high", resources: configs.Resources{MemoryHigh: i64(-2)}, isError: true},
+		{name: "min greater than low", resources: configs.Resources{MemoryMin: i64(2 << 20), MemoryLow: i64(1 << 20)}, isError: true},
+		{name: "high", resources: configs.Resources{MemoryHigh: i64(1 << 30)}, isError: !v2},
+		{name: "min", resources: configs.Resources{MemoryMin: i64(1 << 20)}, isError: !v2},
+		{name: "zero min", resources: configs.Resources{MemoryMin: i64(0)}},
+		{name: "zero high", resources: configs.Resources{MemoryHigh: i64(0)}, isError: true},
+		{name: "swap max without memory", resources: configs.Resources{MemorySwapMax: i64(1 << 30)}, isError: !v2},
+	}
+	for _, tc := range tests {
+		resources := tc.resources
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Resources: &resources,
+			},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateCpu(t *testing.T) {
+	u64 := func(i uint64) *uint64 { return &i }
+	i64 := func(i int64) *int64 { return &i }
+	tests := []struct {
+		name      string
+		resources configs.Resources
+		isError   bool
+	}{
+		{name: "burst", resources: configs.Resources{CpuQuota: 50000, CpuBurst: u64(20000)}},
+		{name: "burst without quota", resources: configs.Resources{CpuBurst: u64(20000)}},
+		{name: "burst greater than quota", resources: configs.Resources{CpuQuota: 50000, CpuBurst: u64(60000)}, isError: true},
+		{name: "idle", resources: configs.Resources{CpuIdle: i64(1)}},
+		{name: "not idle with weight", resources: configs.Resources{CpuIdle: i64(0), CpuWeight: 100}},
+		{name: "invalid idle", resources: configs.Resources{CpuIdle: i64(2)}, isError: true},
+		{name: "idle with shares", resources: configs.Resources{CpuIdle: i64(1), CpuShares: 1024}, isError: true},
+		{name: "uclamp", resources: configs.Resources{CpuUclampMin: "10.5", CpuUclampMax: "max"}},
+		{name: "uclamp integers", resources: configs.Resources{CpuUclampMin: "0", CpuUclampMax: "100"}},
+		{name: "uclamp min greater than max", resources: configs.Resources{CpuUclampMin: "50", CpuUclampMax: "49.99"}, isError: true},
+		{name: "uclamp too precise", resources: configs.Resources{CpuUclampMin: "10.125"}, isError: true},
+		{name: "uclamp too large", resources: configs.Resources{CpuUclampMax: "100.01"}, isError: true},
+		{name: "uclamp negative", resources: configs.Resources{CpuUclampMin: "-1"}, isError: true},
+		{name: "uclamp not a number", resources: configs.Resources{CpuUclampMin: "min"}, isError: true},
+	}
+	for _, tc := range tests {
+		resources := tc.resources
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Resources: &resources,
+			},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateIoPolicy(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("Test requires cgroup v2.")
+	}
+	dev := configs.IoDevice{Major: 8, Minor: 0}
+	tests := []struct {
+		name      string
+		resources configs.Resources
+		rootless  bool
+		isError   bool
+	}{
+		{name: "weight", resources: configs.Resources{IoWeight: 100, IoWeightDevice: []*configs.IoWeightDevice{{IoDevice: dev, Weight: 200}}}},
+		{name: "invalid weight", resources: configs.Resources{IoWeight: 10001}, isError: true},
+		{name: "invalid device weight", resources: configs.Resources{IoWeightDevice: []*configs.IoWeightDevice{{IoDevice: dev}}}, isError: true},
+		{name: "max by path", resources: configs.Resources{IoMax: []*configs.IoMaxDevice{{IoDevice: configs.IoDevice{Path: "/dev/sda"}, Rbps: 1 << 20, Wiops: -1}}}},
+		{name: "relative path", resources: configs.Resources{IoMax: []*configs.IoMaxDevice{{IoDevice: configs.IoDevice{Path: "sda"}, Rbps: 1 << 20}}}, isError: true},
+		{name: "path and numbers", resources: configs.Resources{IoLatency: []*configs.IoLatencyDevice{{IoDevice: configs.IoDevice{Major: 8, Path: "/dev/sda"}, Target: 1000}}}, isError: true},
+		{name: "invalid max", resources: configs.Resources{IoMax: []*configs.IoMaxDevice{{IoDevice: dev, Rbps: -2}}}, isError: true},
+		{name: "cost qos", resources: configs.Resources{IoCostQos: []*configs.IoCostQos{{IoDevice: dev, Enable: true, Ctrl: "user", Rpct: 95, Rlat: 5000, Min: 50, Max: 150}}}},
+		{name: "cost qos rootless", resources: configs.Resources{IoCostQos: []*configs.IoCostQos{{IoDevice: dev, Enable: true}}}, rootless: true, isError: true},
+		{name: "cost qos invalid ctrl", resources: configs.Resources{IoCostQos: []*configs.IoCostQos{{IoDevice: dev, Ctrl: "manual"}}}, isError: true},
+		{name: "cost qos invalid percentile", resources: configs.Resources{IoCostQos: []*configs.IoCostQos{{IoDevice: dev, Wpct: 101}}}, isError: true},
+	}
+	for _, tc := range tests {
+		resources := tc.resources
+		config := &configs.Config{
+			Rootfs:          "/var",
+			RootlessCgroups: tc.rootless,
+			Cgroups: &configs.Cgroup{
+				Resources: &resources,
+			},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateCgroupDelegate(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("Test requires cgroup v2.")
+	}
+	tests := []struct {
+		name     string
+		delegate configs.CgroupDelegate
+		paths    map[string]string
+		isError  bool
+	}{
+		{name: "all controllers", delegate: configs.CgroupDelegate{UID: 1000, GID: 1000}},
+		{name: "controllers and leaf", delegate: configs.CgroupDelegate{Controllers: []string{"cpu", "memory", "pids"}, Leaf: "payload"}},
+		{name: "nested leaf", delegate: configs.CgroupDelegate{Leaf: "a/b"}, isError: true},
+		{name: "parent leaf", delegate: configs.CgroupDelegate{Leaf: ".."}, isError: true},
+		{name: "prefixed controller", delegate: configs.CgroupDelegate{Controllers: []string{"+cpu"}}, isError: true},
+		{name: "multiple controllers", delegate: configs.CgroupDelegate{Controllers: []string{"cpu memory"}}, isError: true},
+		{name: "empty controller", delegate: configs.CgroupDelegate{Controllers: []string{""}}, isError: true},
+		{name: "invalid owner", delegate: configs.CgroupDelegate{UID: -1}, isError: true},
+		{name: "existing cgroup", delegate: configs.CgroupDelegate{}, paths: map[string]string{"": "/sys/fs/cgroup/foo"}, isError: true},
+	}
+	for _, tc := range tests {
+		delegate := tc.delegate
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Paths:     tc.paths,
+				Delegate:  &delegate,
+				Resources: &configs.Resources{},
+			},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateCgroupSystemdUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		unit    configs.SystemdUnit
+		isError bool
+	}{
+		{name: "dependencies", unit: configs.SystemdUnit{After: []string{"network-online.target"}, BindsTo: []string{"foo.service"}}},
+		{name: "collect mode", unit: configs.SystemdUnit{CollectMode: "inactive-or-failed"}},
+		{name: "invalid collect mode", unit: configs.SystemdUnit{CollectMode: "always"}, isError: true},
+		{name: "empty dependency", unit: configs.SystemdUnit{Requires: []string{""}}, isError: true},
+		{name: "multiple dependencies", unit: configs.SystemdUnit{Wants: []string{"a.service b.service"}}, isError: true},
+	}
+	for _, tc := range tests {
+		unit := tc.unit
+		config := &configs.Config{
+			Rootfs: "/var",
+			Cgroups: &configs.Cgroup{
+				Unit:      &unit,
+				Resources: &configs.Resources{},
+			},
+		}
+
+		validator := validate.New()
+		err := validator.Validate(config)
+		if tc.isError && err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		} else if !tc.isError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
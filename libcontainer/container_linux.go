@@ -30,6 +30,7 @@ import (
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -42,7 +43,9 @@ type linuxContainer struct {
 	root                 string
 	config               *configs.Config
 	cgroupManager        cgroups.Manager
+	cgroupManagerName    string
 	intelRdtManager      intelrdt.Manager
+	hotplugDevices       []string
 	initPath             string
 	initArgs             []string
 	initProcess          parentProcess
@@ -75,6 +78,10 @@ type State struct {
 	// For cgroup v2 unified hierarchy, a key is "", and the value is the unified path.
 	CgroupPaths map[string]string `json:"cgroup_paths"`
 
+	// CgroupManager is the kind of cgroup manager ("cgroupfs" or "systemd")
+	// used for the container, if set up by the factory options.
+	CgroupManager string `json:"cgroup_manager,omitempty"`
+
 	// NamespacePaths are filepaths to the container's namespaces. Key is the namespace type
 	// with the value as the path.
 	NamespacePaths map[configs.NamespaceType]string `json:"namespace_paths"`
@@ -84,6 +91,18 @@ type State struct {
 
 	// Intel RDT "resource control" filesystem path
 	IntelRdtPath string `json:"intel_rdt_path"`
+
+	// IntelRdtGroupCreated is set if the Intel RDT group named by ClosID
+	// was created by runc, so that it is removed along with the container.
+	IntelRdtGroupCreated bool `json:"intel_rdt_group_created,omitempty"`
+
+	// HotplugDevices are the paths of the device nodes added to the running
+	// container by AddDevice, which are the only ones RemoveDevice removes.
+	HotplugDevices []string `json:"hotplug_devices,omitempty"`
+
+	// Upper directory of the container's overlay rootfs, which holds all the
+	// changes made to the rootfs, if it was assembled from layers.
+	RootfsUpperDir string `json:"rootfs_upper_dir,omitempty"`
 }
 
 // Container is a libcontainer container object.
@@ -118,6 +137,16 @@ type Container interface {
 
 	// NotifyMemoryPressure returns a read-only channel signaling when the container reaches a given pressure level
 	NotifyMemoryPressure(level PressureLevel) (<-chan struct{}, error)
+
+	// AddDevice creates a device node in the running container and allows access to it
+	// in the container's device cgroup. The device node is bind-mounted from the same
+	// path on the host when it cannot be created (e.g. in a user namespace).
+	AddDevice(device *devices.Device) error
+
+	// RemoveDevice removes a device node previously added to the running container
+	// by AddDevice, and denies access to it in the container's device cgroup. Other
+	// devices, such as the ones from the container's config, are never removed.
+	RemoveDevice(path string) error
 }
 
 // ID returns the container's unique ID
@@ -172,7 +201,7 @@ func (c *linuxContainer) Stats() (*Stats, error) {
 		stats = &Stats{}
 	)
 	if stats.CgroupStats, err = c.cgroupManager.GetStats(); err != nil {
-		return stats, fmt.Errorf("unable to get container cgroup stats: %w", err)
+		return stats, withClass(ErrCgroup, fmt.Errorf("unable to get container cgroup stats: %w", err))
 	}
 	if c.intelRdtManager != nil {
 		if stats.IntelRdtStats, err = c.intelRdtManager.GetStats(); err != nil {
@@ -187,6 +216,16 @@ func (c *linuxContainer) Stats() (*Stats, error) {
 				return stats, fmt.Errorf("unable to get network stats for interface %q: %w", iface.HostInterfaceName, err)
 			}
 			stats.Interfaces = append(stats.Interfaces, istats)
+		case "host-device":
+			if c.initProcess == nil {
+				continue
+			}
+			name := hostDeviceName(iface)
+			istats, err := getNetNSInterfaceStats(c.initProcess.pid(), name)
+			if err != nil {
+				return stats, fmt.Errorf("unable to get network stats for interface %q: %w", name, err)
+			}
+			stats.Interfaces = append(stats.Interfaces, istats)
 		}
 	}
 	return stats, nil
@@ -207,7 +246,7 @@ func (c *linuxContainer) Set(config configs.Config) error {
 		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
 			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
 		}
-		return err
+		return withClass(ErrCgroup, err)
 	}
 	if c.intelRdtManager != nil {
 		if err := c.intelRdtManager.Set(&config); err != nil {
@@ -336,6 +375,18 @@ type openResult struct {
 }
 
 func (c *linuxContainer) start(process *Process) (retErr error) {
+	if process.Init {
+		if err := setupRootfsLayers(c.config); err != nil {
+			return fmt.Errorf("unable to set up rootfs layers: %w", err)
+		}
+		defer func() {
+			if retErr != nil {
+				if err := teardownRootfsLayers(c.config); err != nil {
+					logrus.Warn(err)
+				}
+			}
+		}()
+	}
 	parent, err := c.newParentProcess(process)
 	if err != nil {
 		return fmt.Errorf("unable to create new parent process: %w", err)
@@ -369,7 +420,7 @@ func (c *linuxContainer) start(process *Process) (retErr error) {
 				if err := ignoreTerminateErrors(parent.terminate()); err != nil {
 					logrus.Warn(fmt.Errorf("error running poststart hook: %w", err))
 				}
-				return err
+				return withClass(ErrHook, err)
 			}
 		}
 	}
@@ -555,11 +606,16 @@ func (c *linuxContainer) newSetnsProcess(p *Process, cmd *exec.Cmd, messageSockP
 	if err != nil {
 		return nil, err
 	}
+	var intelRdtMonPath string
+	if c.config.IntelRdt != nil && c.config.IntelRdt.EnableMonitoring && state.IntelRdtPath != "" {
+		intelRdtMonPath = intelrdt.GetMonGroupPath(state.IntelRdtPath, c.ID())
+	}
 	return &setnsProcess{
 		cmd:             cmd,
 		cgroupPaths:     state.CgroupPaths,
 		rootlessCgroups: c.config.RootlessCgroups,
 		intelRdtPath:    state.IntelRdtPath,
+		intelRdtMonPath: intelRdtMonPath,
 		messageSockPair: messageSockPair,
 		logFilePair:     logFilePair,
 		manager:         c.cgroupManager,
@@ -626,7 +682,7 @@ func (c *linuxContainer) Pause() error {
 	switch status {
 	case Running, Created:
 		if err := c.cgroupManager.Freeze(configs.Frozen); err != nil {
-			return err
+			return withClass(ErrCgroup, err)
 		}
 		return c.state.transition(&pausedState{
 			c: c,
@@ -646,7 +702,7 @@ func (c *linuxContainer) Resume() error {
 		return ErrNotPaused
 	}
 	if err := c.cgroupManager.Freeze(configs.Thawed); err != nil {
-		return err
+		return withClass(ErrCgroup, err)
 	}
 	return c.state.transition(&runningState{
 		c: c,
@@ -736,7 +792,10 @@ func compareCriuVersion(criuVersion int, minVersion int) error {
 }
 
 // checkCriuVersion checks Criu version greater than or equal to minVersion
-func (c *linuxContainer) checkCriuVersion(minVersion int) error {
+func (c *linuxContainer) checkCriuVersion(minVersion int) (retErr error) {
+	defer func() {
+		retErr = withClass(ErrCriu, retErr)
+	}()
 	// If the version of criu has already been determined there is no need
 	// to ask criu for the version again. Use the value from c.criuVersion.
 	if c.criuVersion != 0 {
@@ -1123,6 +1182,19 @@ func (c *linuxContainer) Checkpoint(criuOpts *CriuOpts) error {
 		if err != nil {
 			return err
 		}
+
+		// Record the rootfs layers, so that the rootfs (including the
+		// changes in its upper directory) can be reassembled on restore.
+		if c.config.RootfsLayers != nil {
+			layersJSON, err := json.Marshal(c.config.RootfsLayers)
+			if err != nil {
+				return err
+			}
+			err = ioutil.WriteFile(filepath.Join(criuOpts.ImagesDirectory, rootfsLayersFilename), layersJSON, 0o600)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	err = c.criuSwrk(nil, req, criuOpts, nil)
@@ -1308,7 +1380,25 @@ func (c *linuxContainer) Restore(process *Process, criuOpts *CriuOpts) error {
 	if err != nil {
 		return err
 	}
-	err = mount(c.config.Rootfs, root, "", "", unix.MS_BIND|unix.MS_REC, "")
+	// An overlay rootfs is mounted directly, using the layers recorded by
+	// Checkpoint if the container's config does not specify any.
+	if c.config.RootfsLayers == nil {
+		layersJSON, err := ioutil.ReadFile(filepath.Join(criuOpts.ImagesDirectory, rootfsLayersFilename))
+		if err == nil {
+			var layers configs.RootfsLayers
+			if err := json.Unmarshal(layersJSON, &layers); err != nil {
+				return err
+			}
+			c.config.RootfsLayers = &layers
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if c.config.RootfsLayers != nil {
+		err = mountRootfsLayers(c.config.RootfsLayers, root)
+	} else {
+		err = mount(c.config.Rootfs, root, "", "", unix.MS_BIND|unix.MS_REC, "")
+	}
 	if err != nil {
 		return err
 	}
@@ -1476,7 +1566,10 @@ func (c *linuxContainer) criuApplyCgroups(pid int, req *criurpc.CriuReq) error {
 	return nil
 }
 
-func (c *linuxContainer) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuOpts, extraFiles []*os.File) error {
+func (c *linuxContainer) criuSwrk(process *Process, req *criurpc.CriuReq, opts *CriuOpts, extraFiles []*os.File) (retErr error) {
+	defer func() {
+		retErr = withClass(ErrCriu, retErr)
+	}()
 	fds, err := unix.Socketpair(unix.AF_LOCAL, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
 	if err != nil {
 		return err
@@ -1726,10 +1819,10 @@ func (c *linuxContainer) criuNotifications(resp *criurpc.CriuResp, process *Proc
 			s.Pid = int(notify.GetPid())
 
 			if err := c.config.Hooks[configs.Prestart].RunHooks(s); err != nil {
-				return err
+				return withClass(ErrHook, err)
 			}
 			if err := c.config.Hooks[configs.CreateRuntime].RunHooks(s); err != nil {
-				return err
+				return withClass(ErrHook, err)
 			}
 		}
 	case "post-restore":
@@ -1902,7 +1995,11 @@ func (c *linuxContainer) currentState() (*State, error) {
 		startTime, _ = c.initProcess.startTime()
 		externalDescriptors = c.initProcess.externalDescriptors()
 	}
-	intelRdtPath, err := intelrdt.GetIntelRdtPath(c.ID())
+	intelRdtGroup := c.ID()
+	if c.config.IntelRdt != nil && c.config.IntelRdt.ClosID != "" {
+		intelRdtGroup = c.config.IntelRdt.ClosID
+	}
+	intelRdtPath, err := intelrdt.GetIntelRdtPath(intelRdtGroup)
 	if err != nil {
 		intelRdtPath = ""
 	}
@@ -1916,9 +2013,17 @@ func (c *linuxContainer) currentState() (*State, error) {
 		},
 		Rootless:            c.config.RootlessEUID && c.config.RootlessCgroups,
 		CgroupPaths:         c.cgroupManager.GetPaths(),
+		CgroupManager:       c.cgroupManagerName,
 		IntelRdtPath:        intelRdtPath,
 		NamespacePaths:      make(map[configs.NamespaceType]string),
 		ExternalDescriptors: externalDescriptors,
+		HotplugDevices:      c.hotplugDevices,
+	}
+	if c.intelRdtManager != nil {
+		state.IntelRdtGroupCreated = c.intelRdtManager.GroupCreated()
+	}
+	if c.config.RootfsLayers != nil {
+		state.RootfsUpperDir = c.config.RootfsLayers.Upper
 	}
 	if pid > 0 {
 		for _, ns := range c.config.Namespaces {
@@ -55,6 +55,10 @@ func (m *mockCgroupManager) Exists() bool {
 	return err == nil
 }
 
+func (m *mockCgroupManager) Kill() error {
+	return cgroups.ErrKillNotSupported
+}
+
 func (m *mockCgroupManager) OOMKillCount() (uint64, error) {
 	return 0, nil
 }
@@ -95,6 +99,12 @@ func (m *mockIntelRdtManager) GetPath() string {
 	return m.path
 }
 
+func (m *mockIntelRdtManager) GroupCreated() bool {
+	return false
+}
+
+func (m *mockIntelRdtManager) SetGroupCreated(bool) {}
+
 func (m *mockIntelRdtManager) Set(container *configs.Config) error {
 	return nil
 }
@@ -12,6 +12,75 @@ var (
 	ErrNotPaused  = errors.New("container not paused")
 )
 
+// Classes of errors returned by libcontainer, which can be checked for using
+// errors.Is. The message of a classified error is left unchanged.
+var (
+	ErrCgroup  = errors.New("cgroup error")
+	ErrSeccomp = errors.New("seccomp error")
+	ErrCriu    = errors.New("criu error")
+	ErrHook    = errors.New("hook error")
+)
+
+// errorClasses lists the names of error classes, as passed from the
+// container's init to the parent, along with the classes. An error may belong
+// to several classes, in which case the first one listed is used, so the
+// order is the same as the one used by runc to report the error.
+var errorClasses = []struct {
+	name  string
+	class error
+}{
+	{"hook", ErrHook},
+	{"seccomp", ErrSeccomp},
+	{"criu", ErrCriu},
+	{"cgroup", ErrCgroup},
+}
+
+type classError struct {
+	class error
+	err   error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+func (e *classError) Is(target error) bool {
+	return target == e.class //nolint:errorlint // classes are sentinels
+}
+
+// withClass marks err as belonging to the given class. A nil error is
+// returned as is.
+func withClass(class, err error) error {
+	if err == nil || errors.Is(err, class) {
+		return err
+	}
+	return &classError{class: class, err: err}
+}
+
+// errorClassName returns the name of the class of err, if any.
+func errorClassName(err error) string {
+	for _, c := range errorClasses {
+		if errors.Is(err, c.class) {
+			return c.name
+		}
+	}
+	return ""
+}
+
+// errorClass returns the class with the given name, or nil.
+func errorClass(name string) error {
+	for _, c := range errorClasses {
+		if c.name == name {
+			return c.class
+		}
+	}
+	return nil
+}
+
 type ConfigError struct {
 	details string
 }
@@ -0,0 +1,64 @@
+package libcontainer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+func TestErrorClass(t *testing.T) {
+	err := withClass(ErrCgroup, fmt.Errorf("unable to apply: %w", os.ErrPermission))
+	if err.Error() != "unable to apply: permission denied" {
+		t.Errorf("unexpected message %q", err)
+	}
+	if !errors.Is(err, ErrCgroup) {
+		t.Error("expected a cgroup error")
+	}
+	if errors.Is(err, ErrHook) {
+		t.Error("expected not to be a hook error")
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Error("expected the wrapped error to be preserved")
+	}
+	if withClass(ErrCgroup, nil) != nil {
+		t.Error("expected nil for a nil error")
+	}
+	if withClass(ErrCgroup, err) != err { //nolint:errorlint // checking for identity
+		t.Error("expected an already classified error to be returned as is")
+	}
+}
+
+func TestInitErrorClass(t *testing.T) {
+	var buf bytes.Buffer
+	if err := utils.WriteJSON(&buf, syncT{procError}); err != nil {
+		t.Fatal(err)
+	}
+	err := withClass(ErrSeccomp, errors.New("unable to init seccomp"))
+	if err := utils.WriteJSON(&buf, &initError{Message: err.Error(), Class: errorClassName(err)}); err != nil {
+		t.Fatal(err)
+	}
+	err = parseSync(&buf, func(*syncT) error { return nil })
+	if err == nil || err.Error() != "unable to init seccomp" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !errors.Is(err, ErrSeccomp) {
+		t.Error("expected the class to be passed along with the error")
+	}
+	if errors.Is(err, ErrCriu) {
+		t.Error("expected not to be a criu error")
+	}
+}
+
+func TestErrorClassNameOrder(t *testing.T) {
+	// An error belonging to several classes always gets the first one.
+	err := withClass(ErrCgroup, withClass(ErrHook, errors.New("hook failed")))
+	for i := 0; i < 10; i++ {
+		if name := errorClassName(err); name != "hook" {
+			t.Fatalf("expected class hook, got %q", name)
+		}
+	}
+}
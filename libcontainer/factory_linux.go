@@ -14,6 +14,7 @@ import (
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/moby/sys/mountinfo"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
@@ -74,41 +75,87 @@ func getUnifiedPath(paths map[string]string) string {
 	return path
 }
 
-func systemdCgroupV2(l *LinuxFactory, rootless bool) error {
-	l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
-		return systemd.NewUnifiedManager(config, getUnifiedPath(paths), rootless)
+// Names of the kinds of cgroup managers set up by the factory options, as
+// saved in the state of the containers.
+const (
+	cgroupManagerCgroupfs = "cgroupfs"
+	cgroupManagerSystemd  = "systemd"
+)
+
+// SystemdConnection returns an options func to configure how the systemd
+// cgroup managers of a LinuxFactory connect to systemd. If fallback is true,
+// and systemd can not be used (e.g. it is not running, or can't be connected
+// to) when a container is created, the container uses the cgroupfs manager
+// instead (see SystemdCgroups), which is logged. It must precede the
+// systemd cgroup managers options.
+func SystemdConnection(mode systemd.ConnMode, fallback bool) func(*LinuxFactory) error {
+	return func(l *LinuxFactory) error {
+		l.SystemdConnMode = mode
+		l.SystemdFallback = fallback
+		return nil
 	}
-	return nil
 }
 
-// SystemdCgroups is an options func to configure a LinuxFactory to return
-// containers that use systemd to create and manage cgroups.
-func SystemdCgroups(l *LinuxFactory) error {
+// checkSystemd checks whether systemd can be used to manage cgroups.
+func checkSystemd(rootless bool, mode systemd.ConnMode, connect bool) error {
 	if !systemd.IsRunningSystemd() {
 		return errNoSystemd
 	}
+	if rootless && !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("cgroup v2 not enabled on this host, can't use systemd (rootless) as cgroups manager")
+	}
+	if connect {
+		return systemd.CheckConnection(rootless, mode)
+	}
+	return nil
+}
 
-	if cgroups.IsCgroup2UnifiedMode() {
-		return systemdCgroupV2(l, false)
+func systemdCgroups(l *LinuxFactory, rootless bool) error {
+	mode := l.SystemdConnMode
+	if !l.SystemdFallback {
+		// The connection is only established when needed.
+		if err := checkSystemd(rootless, mode, false); err != nil {
+			return err
+		}
 	}
 
-	l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
-		return systemd.NewLegacyManager(config, paths)
+	// The cgroupfs manager is used for the containers which fell back to it.
+	if err := cgroupfs(l, rootless); err != nil {
+		return err
+	}
+	l.newCgroupfsManager = l.NewCgroupsManager
+	if l.SystemdFallback {
+		// Checked by Create, as only new containers can fall back.
+		l.checkSystemd = func() error {
+			return checkSystemd(rootless, mode, true)
+		}
 	}
 
+	l.cgroupManager = cgroupManagerSystemd
+	if cgroups.IsCgroup2UnifiedMode() {
+		l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
+			return systemd.NewUnifiedManager(config, getUnifiedPath(paths), rootless, mode)
+		}
+		return nil
+	}
+	l.NewCgroupsManager = func(config *configs.Cgroup, paths map[string]string) cgroups.Manager {
+		return systemd.NewLegacyManager(config, paths, mode)
+	}
 	return nil
 }
 
+// SystemdCgroups is an options func to configure a LinuxFactory to return
+// containers that use systemd to create and manage cgroups. If the factory
+// is configured to fall back to cgroupfs (see SystemdConnection), a new
+// container for which systemd can not be used gets a cgroupfs manager, which
+// is also used for it once loaded.
+func SystemdCgroups(l *LinuxFactory) error {
+	return systemdCgroups(l, false)
+}
+
 // RootlessSystemdCgroups is rootless version of SystemdCgroups.
 func RootlessSystemdCgroups(l *LinuxFactory) error {
-	if !systemd.IsRunningSystemd() {
-		return errNoSystemd
-	}
-
-	if !cgroups.IsCgroup2UnifiedMode() {
-		return errors.New("cgroup v2 not enabled on this host, can't use systemd (rootless) as cgroups manager")
-	}
-	return systemdCgroupV2(l, true)
+	return systemdCgroups(l, true)
 }
 
 func cgroupfs2(l *LinuxFactory, rootless bool) error {
@@ -123,6 +170,9 @@ func cgroupfs2(l *LinuxFactory, rootless bool) error {
 }
 
 func cgroupfs(l *LinuxFactory, rootless bool) error {
+	l.cgroupManager = cgroupManagerCgroupfs
+	l.newCgroupfsManager = nil
+	l.checkSystemd = nil
 	if cgroups.IsCgroup2UnifiedMode() {
 		return cgroupfs2(l, rootless)
 	}
@@ -247,6 +297,26 @@ type LinuxFactory struct {
 
 	// NewIntelRdtManager returns an initialized Intel RDT manager for a single container.
 	NewIntelRdtManager func(config *configs.Config, id string, path string) intelrdt.Manager
+
+	// SystemdConnMode is the way the systemd cgroup managers connect to
+	// systemd (see SystemdConnection).
+	SystemdConnMode systemd.ConnMode
+
+	// SystemdFallback makes new containers fall back to the cgroupfs
+	// manager if systemd can not be used (see SystemdConnection).
+	SystemdFallback bool
+
+	// cgroupManager is the kind of manager returned by NewCgroupsManager,
+	// if set up by the cgroup managers options.
+	cgroupManager string
+
+	// newCgroupfsManager returns a cgroupfs manager, if NewCgroupsManager
+	// returns a systemd one.
+	newCgroupfsManager func(config *configs.Cgroup, paths map[string]string) cgroups.Manager
+
+	// checkSystemd is set if new containers fall back to the cgroupfs
+	// manager when it fails.
+	checkSystemd func() error
 }
 
 func (l *LinuxFactory) Create(id string, config *configs.Config) (Container, error) {
@@ -259,6 +329,9 @@ func (l *LinuxFactory) Create(id string, config *configs.Config) (Container, err
 	if err := l.Validator.Validate(config); err != nil {
 		return nil, &ConfigError{err.Error()}
 	}
+	if err := l.validateMappingTools(config); err != nil {
+		return nil, &ConfigError{err.Error()}
+	}
 	containerRoot, err := securejoin.SecureJoin(l.Root, id)
 	if err != nil {
 		return nil, err
@@ -274,16 +347,24 @@ func (l *LinuxFactory) Create(id string, config *configs.Config) (Container, err
 	if err := os.Chown(containerRoot, unix.Geteuid(), unix.Getegid()); err != nil {
 		return nil, err
 	}
+	newCgroupsManager, cgroupManager := l.NewCgroupsManager, l.cgroupManager
+	if l.checkSystemd != nil {
+		if err := l.checkSystemd(); err != nil {
+			logrus.Warnf("unable to use systemd to manage cgroups (%v), falling back to cgroupfs", err)
+			newCgroupsManager, cgroupManager = l.newCgroupfsManager, cgroupManagerCgroupfs
+		}
+	}
 	c := &linuxContainer{
-		id:            id,
-		root:          containerRoot,
-		config:        config,
-		initPath:      l.InitPath,
-		initArgs:      l.InitArgs,
-		criuPath:      l.CriuPath,
-		newuidmapPath: l.NewuidmapPath,
-		newgidmapPath: l.NewgidmapPath,
-		cgroupManager: l.NewCgroupsManager(config.Cgroups, nil),
+		id:                id,
+		root:              containerRoot,
+		config:            config,
+		initPath:          l.InitPath,
+		initArgs:          l.InitArgs,
+		criuPath:          l.CriuPath,
+		newuidmapPath:     l.NewuidmapPath,
+		newgidmapPath:     l.NewgidmapPath,
+		cgroupManager:     newCgroupsManager(config.Cgroups, nil),
+		cgroupManagerName: cgroupManager,
 	}
 	if l.NewIntelRdtManager != nil {
 		c.intelRdtManager = l.NewIntelRdtManager(config, id, "")
@@ -313,6 +394,12 @@ func (l *LinuxFactory) Load(id string) (Container, error) {
 		processStartTime: state.InitProcessStartTime,
 		fds:              state.ExternalDescriptors,
 	}
+	// Use the same kind of cgroup manager as when the container was
+	// created, which may have fallen back to cgroupfs.
+	newCgroupsManager := l.NewCgroupsManager
+	if state.CgroupManager == cgroupManagerCgroupfs && l.newCgroupfsManager != nil {
+		newCgroupsManager = l.newCgroupfsManager
+	}
 	c := &linuxContainer{
 		initProcess:          r,
 		initProcessStartTime: state.InitProcessStartTime,
@@ -323,12 +410,15 @@ func (l *LinuxFactory) Load(id string) (Container, error) {
 		criuPath:             l.CriuPath,
 		newuidmapPath:        l.NewuidmapPath,
 		newgidmapPath:        l.NewgidmapPath,
-		cgroupManager:        l.NewCgroupsManager(state.Config.Cgroups, state.CgroupPaths),
+		cgroupManager:        newCgroupsManager(state.Config.Cgroups, state.CgroupPaths),
+		cgroupManagerName:    state.CgroupManager,
+		hotplugDevices:       state.HotplugDevices,
 		root:                 containerRoot,
 		created:              state.Created,
 	}
 	if l.NewIntelRdtManager != nil {
 		c.intelRdtManager = l.NewIntelRdtManager(&state.Config, id, state.IntelRdtPath)
+		c.intelRdtManager.SetGroupCreated(state.IntelRdtGroupCreated)
 	}
 	c.state = &loadedState{c: c}
 	if err := c.refreshState(); err != nil {
@@ -391,7 +481,7 @@ func (l *LinuxFactory) StartInitialization() (err error) {
 			fmt.Fprintln(os.Stderr, err)
 			return
 		}
-		if werr := utils.WriteJSON(pipe, &initError{Message: err.Error()}); werr != nil {
+		if werr := utils.WriteJSON(pipe, &initError{Message: err.Error(), Class: errorClassName(err)}); werr != nil {
 			fmt.Fprintln(os.Stderr, err)
 			return
 		}
@@ -439,6 +529,32 @@ func (l *LinuxFactory) validateID(id string) error {
 	return nil
 }
 
+// validateMappingTools makes sure that the ID mappings of a rootless container
+// which cannot be written by an unprivileged user can be set up using the
+// configured newuidmap and newgidmap binaries.
+func (l *LinuxFactory) validateMappingTools(config *configs.Config) error {
+	if !config.RootlessEUID || !config.Namespaces.Contains(configs.NEWUSER) || config.Namespaces.PathOf(configs.NEWUSER) != "" {
+		return nil
+	}
+	if needsMappingTool(config.UidMappings, unix.Geteuid()) && l.NewuidmapPath == "" {
+		return errors.New("uid mappings other than the current user require newuidmap")
+	}
+	if needsMappingTool(config.GidMappings, unix.Getegid()) && l.NewgidmapPath == "" {
+		return errors.New("gid mappings other than the current group require newgidmap")
+	}
+	return nil
+}
+
+// needsMappingTool reports whether the given mappings can only be written
+// by an unprivileged user through new{u,g}idmap, i.e. whether they map
+// anything but a single ID to the given (current) host ID.
+func needsMappingTool(mappings []configs.IDMap, hostID int) bool {
+	if len(mappings) != 1 {
+		return len(mappings) > 1
+	}
+	return mappings[0].HostID != hostID || mappings[0].Size != 1
+}
+
 // NewuidmapPath returns an option func to configure a LinuxFactory with the
 // provided ..
 func NewuidmapPath(newuidmapPath string) func(*LinuxFactory) error {
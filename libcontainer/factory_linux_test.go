@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/moby/sys/mountinfo"
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -78,6 +79,90 @@ func TestFactoryNewIntelRdt(t *testing.T) {
 	}
 }
 
+func TestFactoryNewSystemdFallback(t *testing.T) {
+	if systemd.IsRunningSystemd() {
+		t.Skip("Test requires systemd not to be running.")
+	}
+	root, rerr := newTestRoot()
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	defer os.RemoveAll(root)
+
+	if _, err := New(root, SystemdCgroups); err == nil {
+		t.Fatal("expected error without systemd")
+	}
+
+	factory, err := New(root, SystemdConnection(systemd.ConnPrivate, true), SystemdCgroups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lfactory := factory.(*LinuxFactory)
+	if lfactory.SystemdConnMode != systemd.ConnPrivate {
+		t.Errorf("expected connection mode %s, got %s", systemd.ConnPrivate, lfactory.SystemdConnMode)
+	}
+	// The factory keeps the systemd manager, for the containers using it.
+	m := lfactory.NewCgroupsManager(&configs.Cgroup{Path: "/test", Resources: &configs.Resources{}}, nil)
+	if typ := reflect.TypeOf(m).String(); typ != "*systemd.legacyManager" && typ != "*systemd.unifiedManager" {
+		t.Fatalf("expected systemd manager, got %s", typ)
+	}
+	if lfactory.checkSystemd == nil || lfactory.checkSystemd() == nil {
+		t.Fatal("expected new containers to fall back to cgroupfs")
+	}
+	m = lfactory.newCgroupfsManager(&configs.Cgroup{Path: "/test", Resources: &configs.Resources{}}, nil)
+	if typ := reflect.TypeOf(m).String(); typ != "*fs.manager" && typ != "*fs2.manager" {
+		t.Fatalf("expected cgroupfs manager, got %s", typ)
+	}
+}
+
+func TestFactoryLoadCgroupManager(t *testing.T) {
+	root, rerr := newTestRoot()
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	defer os.RemoveAll(root)
+
+	// The factory must not need systemd to load the containers.
+	factory, err := New(root, SystemdConnection(systemd.ConnAuto, true), SystemdCgroups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		manager  string
+		expected []string
+	}{
+		{cgroupManagerCgroupfs, []string{"*fs.manager", "*fs2.manager"}},
+		{cgroupManagerSystemd, []string{"*systemd.legacyManager", "*systemd.unifiedManager"}},
+	} {
+		id := "load-" + tc.manager
+		if err := os.Mkdir(filepath.Join(root, id), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		state := &State{
+			BaseState: BaseState{
+				ID:     id,
+				Config: configs.Config{Rootfs: "/", Cgroups: &configs.Cgroup{Path: "/" + id, Resources: &configs.Resources{}}},
+			},
+			CgroupManager: tc.manager,
+		}
+		if err := marshal(filepath.Join(root, id, stateFilename), state); err != nil {
+			t.Fatal(err)
+		}
+		container, err := factory.Load(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := container.(*linuxContainer)
+		typ := reflect.TypeOf(c.cgroupManager).String()
+		if typ != tc.expected[0] && typ != tc.expected[1] {
+			t.Errorf("%s: expected %v manager, got %s", tc.manager, tc.expected, typ)
+		}
+		if c.cgroupManagerName != tc.manager {
+			t.Errorf("expected the cgroup manager to be kept as %q, got %q", tc.manager, c.cgroupManagerName)
+		}
+	}
+}
+
 func TestFactoryNewTmpfs(t *testing.T) {
 	root, rerr := newTestRoot()
 	if rerr != nil {
@@ -228,3 +313,28 @@ type unserializableHook struct{}
 func (unserializableHook) Run(*specs.State) error {
 	return nil
 }
+
+func TestNeedsMappingTool(t *testing.T) {
+	tests := []struct {
+		mappings []configs.IDMap
+		expected bool
+	}{
+		{mappings: nil, expected: false},
+		{mappings: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}}, expected: false},
+		{mappings: []configs.IDMap{{ContainerID: 1000, HostID: 1000, Size: 1}}, expected: false},
+		{mappings: []configs.IDMap{{ContainerID: 0, HostID: 1001, Size: 1}}, expected: true},
+		{mappings: []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 65536}}, expected: true},
+		{
+			mappings: []configs.IDMap{
+				{ContainerID: 0, HostID: 1000, Size: 1},
+				{ContainerID: 1, HostID: 100000, Size: 65536},
+			},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		if got := needsMappingTool(test.mappings, 1000); got != test.expected {
+			t.Errorf("%+v: expected %v, got %v", test.mappings, test.expected, got)
+		}
+	}
+}
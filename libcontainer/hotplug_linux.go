@@ -0,0 +1,214 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runc/libcontainer/userns"
+	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// AddDevice creates the given device node inside the running container and
+// allows access to it in the container's device cgroup. The device is added
+// to the container's config, so that the change persists in its state, and
+// recorded as hot-plugged, so that it can be removed by RemoveDevice.
+func (c *linuxContainer) AddDevice(device *devices.Device) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if device.Path == "" || !device.Type.CanMknod() || !device.Type.CanCgroup() {
+		return &ConfigError{fmt.Sprintf("invalid device %+v", device)}
+	}
+	// Do not modify the caller's device, which is kept in the config.
+	dev := *device
+	device = &dev
+	device.Path = utils.CleanPath(device.Path)
+	for _, d := range c.config.Devices {
+		if d.Path == device.Path {
+			return &ConfigError{"device " + device.Path + " already exists"}
+		}
+	}
+	rule := device.Rule
+	rule.Allow = true
+	config := c.deviceConfig(device, &rule)
+	config.Devices = append(config.Devices, device)
+
+	// Bind-mounting the node from the host requires a detached mount of it,
+	// created before joining the container's mount namespace.
+	bind := c.useBindMountDevices()
+	treeFd, treeErr := system.OpenTree(unix.AT_FDCWD, device.Path, system.OPEN_TREE_CLONE|system.OPEN_TREE_CLOEXEC)
+	if treeErr != nil {
+		if bind {
+			return fmt.Errorf("unable to bind mount %s: %w", device.Path, os.NewSyscallError("open_tree", treeErr))
+		}
+	} else {
+		defer unix.Close(treeFd)
+	}
+
+	hotplug := append(append([]string(nil), c.hotplugDevices...), device.Path)
+	return c.updateDevices(config, hotplug, func() error {
+		return createHotplugDeviceNode(device, treeFd, bind)
+	})
+}
+
+// RemoveDevice removes the device node at the given path, which must have
+// been added by AddDevice, from the running container, and denies access to
+// it in the container's device cgroup. The device is removed from the
+// container's config.
+func (c *linuxContainer) RemoveDevice(path string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	path = utils.CleanPath(path)
+	var hotplug []string
+	for _, p := range c.hotplugDevices {
+		if p != path {
+			hotplug = append(hotplug, p)
+		}
+	}
+	if len(hotplug) == len(c.hotplugDevices) {
+		return &ConfigError{"device " + path + " was not added to the running container"}
+	}
+	var device *devices.Device
+	config := *c.config
+	config.Devices = nil
+	for _, d := range c.config.Devices {
+		if d.Path == path {
+			device = d
+			continue
+		}
+		config.Devices = append(config.Devices, d)
+	}
+	if device == nil {
+		return &ConfigError{"device " + path + " does not exist"}
+	}
+	rule := device.Rule
+	rule.Allow = false
+	rule.Permissions = "rwm"
+	newConfig := c.deviceConfig(device, &rule)
+	newConfig.Devices = config.Devices
+
+	return c.updateDevices(newConfig, hotplug, func() error {
+		return removeHotplugDeviceNode(path)
+	})
+}
+
+// deviceConfig returns a copy of the container's config where all the device
+// cgroup rules specific to the given device are replaced by rule.
+func (c *linuxContainer) deviceConfig(device *devices.Device, rule *devices.Rule) *configs.Config {
+	config := *c.config
+	cgroup := *config.Cgroups
+	resources := *cgroup.Resources
+	resources.Devices = nil
+	for _, r := range c.config.Cgroups.Resources.Devices {
+		if r.Type == device.Type && r.Major == device.Major && r.Minor == device.Minor {
+			continue
+		}
+		resources.Devices = append(resources.Devices, r)
+	}
+	resources.Devices = append(resources.Devices, rule)
+	cgroup.Resources = &resources
+	config.Cgroups = &cgroup
+	config.Devices = append([]*devices.Device(nil), c.config.Devices...)
+	return &config
+}
+
+// useBindMountDevices reports whether device nodes have to be bind-mounted
+// from the host, rather than created using mknod(2), as in createDevices.
+func (c *linuxContainer) useBindMountDevices() bool {
+	return userns.RunningInUserNS() || c.config.Namespaces.Contains(configs.NEWUSER)
+}
+
+// updateDevices applies the device cgroup rules of config to the running
+// container, runs fn inside the container's mount namespace and saves config
+// and hotplug as the new container's config and hot-plugged devices. If fn
+// fails, the device cgroup rules are reverted.
+func (c *linuxContainer) updateDevices(config *configs.Config, hotplug []string, fn func() error) error {
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	if c.config.RootlessEUID {
+		return errors.New("device hot-plugging is not supported for rootless containers")
+	}
+	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
+		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
+			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
+		}
+		return withClass(ErrCgroup, err)
+	}
+	path := fmt.Sprintf("/proc/%d/ns/mnt", c.initProcess.pid())
+	err = runInNS(path, configs.NEWNS, func() error {
+		unix.Umask(0)
+		return fn()
+	})
+	if err != nil {
+		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
+			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
+		}
+		return err
+	}
+	c.config = config
+	c.hotplugDevices = hotplug
+	_, err = c.updateState(nil)
+	return err
+}
+
+// createHotplugDeviceNode creates the device node in the current mount
+// namespace, whose root is the container's rootfs. If bind is set (or the
+// node cannot be created), the detached mount treeFd of the host device node
+// is moved to the node's path instead.
+func createHotplugDeviceNode(device *devices.Device, treeFd int, bind bool) error {
+	dest, err := securejoin.SecureJoin("/", device.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	if !bind {
+		err := mknodDevice(dest, device)
+		if err == nil || os.IsExist(err) {
+			return nil
+		}
+		if !os.IsPermission(err) || treeFd < 0 {
+			return err
+		}
+	}
+	f, err := os.Create(dest)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	if f != nil {
+		_ = f.Close()
+	}
+	if err := system.MoveMount(treeFd, "", unix.AT_FDCWD, dest, system.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return &os.PathError{Op: "move_mount", Path: dest, Err: err}
+	}
+	return nil
+}
+
+// removeHotplugDeviceNode unmounts (if it was bind-mounted) and removes the
+// device node from the current mount namespace.
+func removeHotplugDeviceNode(path string) error {
+	dest, err := securejoin.SecureJoin("/", path)
+	if err != nil {
+		return err
+	}
+	if err := unix.Unmount(dest, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOENT) {
+		return &os.PathError{Op: "unmount", Path: dest, Err: err}
+	}
+	if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package libcontainer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
+)
+
+func TestDeviceConfig(t *testing.T) {
+	fuse := &devices.Device{
+		Rule: devices.Rule{Type: devices.CharDevice, Major: 10, Minor: 229, Permissions: "rwm"},
+		Path: "/dev/fuse",
+	}
+	denyAll := &devices.Rule{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm"}
+	null := &devices.Rule{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true}
+	oldFuse := &devices.Rule{Type: devices.CharDevice, Major: 10, Minor: 229, Permissions: "r", Allow: true}
+	c := &linuxContainer{
+		config: &configs.Config{
+			Cgroups: &configs.Cgroup{
+				Resources: &configs.Resources{
+					Devices: []*devices.Rule{denyAll, oldFuse, null},
+				},
+			},
+		},
+	}
+
+	rule := fuse.Rule
+	rule.Allow = true
+	config := c.deviceConfig(fuse, &rule)
+
+	expected := []*devices.Rule{denyAll, null, &rule}
+	if !reflect.DeepEqual(config.Cgroups.Resources.Devices, expected) {
+		t.Errorf("expected device rules %+v, got %+v", expected, config.Cgroups.Resources.Devices)
+	}
+	// The container's own config must be left untouched.
+	if len(c.config.Cgroups.Resources.Devices) != 3 || c.config.Cgroups.Resources.Devices[1] != oldFuse {
+		t.Errorf("container config was modified: %+v", c.config.Cgroups.Resources.Devices)
+	}
+}
+
+func TestDeviceHotplugOnly(t *testing.T) {
+	null := &devices.Device{
+		Rule: devices.Rule{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true},
+		Path: "/dev/null",
+	}
+	c := &linuxContainer{
+		config: &configs.Config{
+			Devices: []*devices.Device{null},
+		},
+	}
+
+	// Devices which were not hot-plugged must not be removed.
+	err := c.RemoveDevice("/dev/null")
+	if err == nil {
+		t.Fatal("expected an error removing a device from the container's config")
+	}
+	var cErr *ConfigError
+	if !errors.As(err, &cErr) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	if len(c.config.Devices) != 1 {
+		t.Errorf("container devices were modified: %+v", c.config.Devices)
+	}
+
+	// The caller's device must be left untouched.
+	device := &devices.Device{Rule: null.Rule, Path: "/dev//null"}
+	if err := c.AddDevice(device); err == nil {
+		t.Fatal("expected an error adding an existing device")
+	}
+	if device.Path != "/dev//null" {
+		t.Errorf("device path was modified to %q", device.Path)
+	}
+}
@@ -44,8 +44,8 @@ type pid struct {
 type network struct {
 	configs.Network
 
-	// TempVethPeerName is a unique temporary veth peer name that was placed into
-	// the container's namespace.
+	// TempVethPeerName is a unique temporary veth peer (or macvlan/ipvlan
+	// interface) name that was placed into the container's namespace.
 	TempVethPeerName string `json:"temp_veth_peer_name"`
 }
 
@@ -282,24 +282,24 @@ func setupUser(config *initConfig) error {
 		Home: "/",
 	}
 
-	passwdPath, err := user.GetPasswdPath()
+	// Honor the file-backed databases listed in the container's
+	// nsswitch.conf, without loading any NSS module.
+	passwdSources, groupSources, err := user.GetSources()
 	if err != nil {
 		return err
 	}
 
-	groupPath, err := user.GetGroupPath()
+	execUser, err := user.GetExecUserSources(config.User, &defaultExecUser, passwdSources, groupSources)
 	if err != nil {
 		return err
 	}
-
-	execUser, err := user.GetExecUserPath(config.User, &defaultExecUser, passwdPath, groupPath)
-	if err != nil {
-		return err
+	if execUser.Source != "" {
+		logrus.Debugf("user %q resolved from %q database", config.User, execUser.Source)
 	}
 
 	var addGroups []int
 	if len(config.AdditionalGroups) > 0 {
-		addGroups, err = user.GetAdditionalGroupsPath(config.AdditionalGroups, groupPath)
+		addGroups, err = user.GetAdditionalGroupsSources(config.AdditionalGroups, groupSources)
 		if err != nil {
 			return err
 		}
@@ -425,33 +425,44 @@ func setupNetwork(config *initConfig) error {
 	return nil
 }
 
+// setupRoute adds the configured routes. Omitted addresses use their default,
+// i.e. a route without a destination is a default route, and a route without
+// a gateway is a route to a directly connected network.
 func setupRoute(config *configs.Config) error {
 	for _, config := range config.Routes {
-		_, dst, err := net.ParseCIDR(config.Destination)
-		if err != nil {
-			return err
+		route := &netlink.Route{
+			Scope: netlink.SCOPE_UNIVERSE,
 		}
-		src := net.ParseIP(config.Source)
-		if src == nil {
-			return fmt.Errorf("Invalid source for route: %s", config.Source)
+		if config.Destination != "" {
+			_, dst, err := net.ParseCIDR(config.Destination)
+			if err != nil {
+				return err
+			}
+			route.Dst = dst
 		}
-		gw := net.ParseIP(config.Gateway)
-		if gw == nil {
-			return fmt.Errorf("Invalid gateway for route: %s", config.Gateway)
+		if config.Source != "" {
+			route.Src = net.ParseIP(config.Source)
+			if route.Src == nil {
+				return fmt.Errorf("Invalid source for route: %s", config.Source)
+			}
 		}
-		l, err := netlink.LinkByName(config.InterfaceName)
-		if err != nil {
-			return err
+		if config.Gateway != "" {
+			route.Gw = net.ParseIP(config.Gateway)
+			if route.Gw == nil {
+				return fmt.Errorf("Invalid gateway for route: %s", config.Gateway)
+			}
+		} else {
+			route.Scope = netlink.SCOPE_LINK
 		}
-		route := &netlink.Route{
-			Scope:     netlink.SCOPE_UNIVERSE,
-			Dst:       dst,
-			Src:       src,
-			Gw:        gw,
-			LinkIndex: l.Attrs().Index,
+		if config.InterfaceName != "" {
+			l, err := netlink.LinkByName(config.InterfaceName)
+			if err != nil {
+				return err
+			}
+			route.LinkIndex = l.Attrs().Index
 		}
 		if err := netlink.RouteAdd(route); err != nil {
-			return err
+			return fmt.Errorf("unable to add route %+v: %w", config, err)
 		}
 	}
 	return nil
@@ -479,25 +490,24 @@ type siginfo struct {
 	pad [96]byte
 }
 
-// isWaitable returns true if the process has exited false otherwise.
-// Its based off blockUntilWaitable in src/os/wait_waitid.go
-func isWaitable(pid int) (bool, error) {
-	si := &siginfo{}
-	_, _, e := unix.Syscall6(unix.SYS_WAITID, _P_PID, uintptr(pid), uintptr(unsafe.Pointer(si)), unix.WEXITED|unix.WNOWAIT|unix.WNOHANG, 0, 0)
-	if e != 0 {
-		return false, os.NewSyscallError("waitid", e)
+// killAllProcesses kills all the processes inside the manager's cgroups
+// using cgroup.kill, and returns the processes to wait for.
+func killAllProcesses(m cgroups.Manager) ([]*os.Process, error) {
+	// The processes need to be found before they are killed, as they are
+	// gone from the cgroup once they exit.
+	pids, err := m.GetAllPids()
+	if err != nil {
+		return nil, err
 	}
-
-	return si.si_pid != 0, nil
+	if err := m.Kill(); err != nil {
+		return nil, err
+	}
+	return findProcesses(pids), nil
 }
 
-// signalAllProcesses freezes then iterates over all the processes inside the
-// manager's cgroups sending the signal s to them.
-// If s is SIGKILL then it will wait for each process to exit.
-// For all other signals it will check if the process is ready to report its
-// exit status and only if it is will a wait be performed.
-func signalAllProcesses(m cgroups.Manager, s os.Signal) error {
-	var procs []*os.Process
+// freezeAndSignal freezes the manager's cgroups, sends the signal s to all
+// the processes inside, and thaws them. It returns the signalled processes.
+func freezeAndSignal(m cgroups.Manager, s os.Signal) ([]*os.Process, error) {
 	if err := m.Freeze(configs.Frozen); err != nil {
 		logrus.Warn(err)
 	}
@@ -506,8 +516,22 @@ func signalAllProcesses(m cgroups.Manager, s os.Signal) error {
 		if err := m.Freeze(configs.Thawed); err != nil {
 			logrus.Warn(err)
 		}
-		return err
+		return nil, err
 	}
+	procs := findProcesses(pids)
+	for _, p := range procs {
+		if err := p.Signal(s); err != nil {
+			logrus.Warn(err)
+		}
+	}
+	if err := m.Freeze(configs.Thawed); err != nil {
+		logrus.Warn(err)
+	}
+	return procs, nil
+}
+
+func findProcesses(pids []int) []*os.Process {
+	procs := make([]*os.Process, 0, len(pids))
 	for _, pid := range pids {
 		p, err := os.FindProcess(pid)
 		if err != nil {
@@ -515,12 +539,44 @@ func signalAllProcesses(m cgroups.Manager, s os.Signal) error {
 			continue
 		}
 		procs = append(procs, p)
-		if err := p.Signal(s); err != nil {
+	}
+	return procs
+}
+
+// isWaitable returns true if the process has exited false otherwise.
+// Its based off blockUntilWaitable in src/os/wait_waitid.go
+func isWaitable(pid int) (bool, error) {
+	si := &siginfo{}
+	_, _, e := unix.Syscall6(unix.SYS_WAITID, _P_PID, uintptr(pid), uintptr(unsafe.Pointer(si)), unix.WEXITED|unix.WNOWAIT|unix.WNOHANG, 0, 0)
+	if e != 0 {
+		return false, os.NewSyscallError("waitid", e)
+	}
+
+	return si.si_pid != 0, nil
+}
+
+// signalAllProcesses sends the signal s to all the processes inside the
+// manager's cgroups. SIGKILL is sent using cgroup.kill when available;
+// otherwise, the cgroup is frozen while its processes are signalled.
+// If s is SIGKILL then it will wait for each process to exit.
+// For all other signals it will check if the process is ready to report its
+// exit status and only if it is will a wait be performed.
+func signalAllProcesses(m cgroups.Manager, s os.Signal) error {
+	var (
+		procs []*os.Process
+		err   error
+	)
+	if s == unix.SIGKILL {
+		procs, err = killAllProcesses(m)
+		if err != nil && !errors.Is(err, cgroups.ErrKillNotSupported) {
 			logrus.Warn(err)
 		}
 	}
-	if err := m.Freeze(configs.Thawed); err != nil {
-		logrus.Warn(err)
+	if s != unix.SIGKILL || err != nil {
+		procs, err = freezeAndSignal(m, s)
+	}
+	if err != nil {
+		return err
 	}
 
 	subreaper, err := system.GetSubreaper()
@@ -3,7 +3,6 @@
 package intelrdt
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -11,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,6 +36,15 @@ import (
  * indicating the percentage of maximum memory bandwidth or memory bandwidth
  * limit in MBps unit if MBA Software Controller is enabled.
  *
+ * CAT may also be available for L2 cache, and with Code and Data Prioritization
+ * (CDP), which allocates separate subsets of the cache to code and data.
+ *
+ * AMD platforms provide similar features (AMD QoS) through the same interface,
+ * where the memory bandwidth is specified in units of 1/8 GBps, and the cache
+ * capacity bitmasks may be non-contiguous. The available features are detected
+ * from the "info" directory of "resource control" filesystem, rather than from
+ * CPU flags.
+ *
  * More details about Intel RDT CAT and MBA can be found in the section 17.18
  * of Intel Software Developer Manual:
  * https://software.intel.com/en-us/articles/intel-sdm
@@ -128,6 +137,15 @@ import (
  * "MB:0=5000;1=7000" which means 5000 MBps memory bandwidth limit on socket 0
  * and 7000 MBps memory bandwidth limit on socket 1.
  *
+ * The number of groups is limited by the number of CLOS supported by the
+ * hardware ("info/L3/num_closids"). To avoid running out of them, containers
+ * may share a group by specifying its name as "closID". Such a group is only
+ * removed by runc if runc created it (which is recorded in the container's
+ * state as "intel_rdt_group_created"), and if no tasks are left in it when
+ * the container is destroyed. Monitoring (CMT and MBM) statistics of a container
+ * in a shared group can be collected separately by creating a monitoring
+ * group for it, as "mon_groups/<container_id>" under its group.
+ *
  * For more information about Intel RDT kernel interface:
  * https://www.kernel.org/doc/Documentation/x86/intel_rdt_ui.txt
  *
@@ -155,13 +173,21 @@ type Manager interface {
 	// Returns statistics for Intel RDT
 	GetStats() (*Stats, error)
 
-	// Destroys the Intel RDT 'container_id' group
+	// Destroys the Intel RDT groups created for the container
 	Destroy() error
 
 	// Returns Intel RDT path to save in a state file and to be able to
 	// restore the object later
 	GetPath() string
 
+	// Reports whether the container's resctrl group was created by Apply,
+	// rather than being an existing one, to save in a state file
+	GroupCreated() bool
+
+	// Restores whether the container's resctrl group was created by Apply,
+	// as saved in a state file
+	SetGroupCreated(created bool)
+
 	// Set Intel RDT "resource control" filesystem as configured.
 	Set(container *configs.Config) error
 }
@@ -172,6 +198,8 @@ type intelRdtManager struct {
 	config *configs.Config
 	id     string
 	path   string
+	// groupCreated is set if the resctrl group did not exist before Apply.
+	groupCreated bool
 }
 
 func NewManager(config *configs.Config, id string, path string) Manager {
@@ -191,8 +219,15 @@ var (
 	intelRdtRoot     string
 	intelRdtRootLock sync.Mutex
 
+	// The allocation resources available in "resource control" filesystem
+	enabledResources map[string]bool
+
 	// The flag to indicate if Intel RDT/CAT is enabled
 	catEnabled bool
+	// The flag to indicate if L2 CAT is enabled
+	l2CatEnabled bool
+	// The flag to indicate if Code and Data Prioritization is enabled
+	cdpEnabled bool
 	// The flag to indicate if Intel RDT/MBA is enabled
 	mbaEnabled bool
 	// The flag to indicate if Intel RDT/MBA Software Controller is enabled
@@ -213,41 +248,31 @@ type intelRdtData struct {
 // Check if Intel RDT sub-features are enabled in featuresInit()
 func featuresInit() {
 	initOnce.Do(func() {
-		// 1. Check if hardware and kernel support Intel RDT sub-features
-		flagsSet, err := parseCpuInfoFile("/proc/cpuinfo")
-		if err != nil {
-			return
-		}
-
-		// 2. Check if Intel RDT "resource control" filesystem is mounted
+		// 1. Check if Intel RDT "resource control" filesystem is mounted
 		// The user guarantees to mount the filesystem
 		if !isIntelRdtMounted() {
 			return
 		}
 
-		// 3. Double check if Intel RDT sub-features are available in
-		// "resource control" filesystem. Intel RDT sub-features can be
-		// selectively disabled or enabled by kernel command line
-		// (e.g., rdt=!l3cat,mba) in 4.14 and newer kernel
-		if flagsSet.CAT {
-			if _, err := os.Stat(filepath.Join(intelRdtRoot, "info", "L3")); err == nil {
-				catEnabled = true
-			}
-		}
-		if mbaScEnabled {
-			// We confirm MBA Software Controller is enabled in step 2,
-			// MBA should be enabled because MBA Software Controller
-			// depends on MBA
-			mbaEnabled = true
-		} else if flagsSet.MBA {
-			if _, err := os.Stat(filepath.Join(intelRdtRoot, "info", "MB")); err == nil {
-				mbaEnabled = true
-			}
+		// 2. Check which resources are available in "resource control"
+		// filesystem. It only exposes the resources supported by both
+		// the hardware (Intel or AMD) and the kernel, and not disabled
+		// by kernel command line (e.g., rdt=!l3cat,mba) in 4.14 and
+		// newer kernel. With CDP enabled (through mount options "-o cdp"
+		// and "-o cdpl2"), L3 and L2 are replaced by their CODE and DATA
+		// variants.
+		resources, monitoring, err := getResources(intelRdtRoot)
+		if err != nil {
+			return
 		}
-		if flagsSet.MBMTotal || flagsSet.MBMLocal || flagsSet.CMT {
-			if _, err := os.Stat(filepath.Join(intelRdtRoot, "info", "L3_MON")); err != nil {
-				return
-			}
+		enabledResources = resources
+		catEnabled = resources["L3"] || resources["L3CODE"]
+		l2CatEnabled = resources["L2"] || resources["L2CODE"]
+		cdpEnabled = resources["L3CODE"] || resources["L2CODE"]
+		// MBA Software Controller depends on MBA.
+		mbaEnabled = resources["MB"] || mbaScEnabled
+
+		if monitoring {
 			enabledMonFeatures, err = getMonFeatures(intelRdtRoot)
 			if err != nil {
 				return
@@ -262,6 +287,31 @@ func featuresInit() {
 	})
 }
 
+// getResources returns the allocation resources (such as "L3", "L2CODE" and
+// "MB") available in the "resource control" filesystem at root, and whether
+// L3 monitoring is available.
+func getResources(root string) (map[string]bool, bool, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(root, "info"))
+	if err != nil {
+		return nil, false, err
+	}
+	resources := make(map[string]bool)
+	monitoring := false
+	for _, info := range infos {
+		switch name := info.Name(); {
+		case !info.IsDir():
+			// e.g. last_cmd_status
+		case name == "L3_MON":
+			monitoring = true
+		case strings.HasSuffix(name, "_MON"):
+			// Other monitoring resources are not supported.
+		default:
+			resources[name] = true
+		}
+	}
+	return resources, monitoring, nil
+}
+
 // Return the mount point path of Intel RDT "resource control" filesysem
 func findIntelRdtMountpointDir(f io.Reader) (string, error) {
 	mi, err := mountinfo.GetMountsFromReader(f, func(m *mountinfo.Info) (bool, bool) {
@@ -318,58 +368,6 @@ func isIntelRdtMounted() bool {
 	return err == nil
 }
 
-type cpuInfoFlags struct {
-	CAT bool // Cache Allocation Technology
-	MBA bool // Memory Bandwidth Allocation
-
-	// Memory Bandwidth Monitoring related.
-	MBMTotal bool
-	MBMLocal bool
-
-	CMT bool // Cache Monitoring Technology
-}
-
-func parseCpuInfoFile(path string) (cpuInfoFlags, error) {
-	infoFlags := cpuInfoFlags{}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return infoFlags, err
-	}
-	defer f.Close()
-
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		line := s.Text()
-
-		// Search "cat_l3" and "mba" flags in first "flags" line
-		if strings.HasPrefix(line, "flags") {
-			flags := strings.Split(line, " ")
-			// "cat_l3" flag for CAT and "mba" flag for MBA
-			for _, flag := range flags {
-				switch flag {
-				case "cat_l3":
-					infoFlags.CAT = true
-				case "mba":
-					infoFlags.MBA = true
-				case "cqm_mbm_total":
-					infoFlags.MBMTotal = true
-				case "cqm_mbm_local":
-					infoFlags.MBMLocal = true
-				case "cqm_occup_llc":
-					infoFlags.CMT = true
-				}
-			}
-			return infoFlags, nil
-		}
-	}
-	if err := s.Err(); err != nil {
-		return infoFlags, err
-	}
-
-	return infoFlags, nil
-}
-
 // Gets a single uint64 value from the specified file.
 func getIntelRdtParamUint(path, file string) (uint64, error) {
 	fileName := filepath.Join(path, file)
@@ -417,38 +415,50 @@ func getIntelRdtData(c *configs.Config, pid int) (*intelRdtData, error) {
 	}, nil
 }
 
-// Get the read-only L3 cache information
-func getL3CacheInfo() (*L3CacheInfo, error) {
-	l3CacheInfo := &L3CacheInfo{}
+// GetCacheInfo returns the read-only information of a cache resource, such
+// as "L3", "L2" or "L3CODE".
+func GetCacheInfo(resource string) (*CacheInfo, error) {
+	cacheInfo := &CacheInfo{}
 
 	rootPath, err := getIntelRdtRoot()
 	if err != nil {
-		return l3CacheInfo, err
+		return cacheInfo, err
 	}
 
-	path := filepath.Join(rootPath, "info", "L3")
+	path := filepath.Join(rootPath, "info", resource)
 	cbmMask, err := getIntelRdtParamString(path, "cbm_mask")
 	if err != nil {
-		return l3CacheInfo, err
+		return cacheInfo, err
 	}
 	minCbmBits, err := getIntelRdtParamUint(path, "min_cbm_bits")
 	if err != nil {
-		return l3CacheInfo, err
+		return cacheInfo, err
 	}
 	numClosids, err := getIntelRdtParamUint(path, "num_closids")
 	if err != nil {
-		return l3CacheInfo, err
+		return cacheInfo, err
 	}
 
-	l3CacheInfo.CbmMask = cbmMask
-	l3CacheInfo.MinCbmBits = minCbmBits
-	l3CacheInfo.NumClosids = numClosids
+	cacheInfo.CbmMask = cbmMask
+	cacheInfo.MinCbmBits = minCbmBits
+	cacheInfo.NumClosids = numClosids
 
-	return l3CacheInfo, nil
+	return cacheInfo, nil
 }
 
-// Get the read-only memory bandwidth information
-func getMemBwInfo() (*MemBwInfo, error) {
+// HasSparseMasks reports whether the capacity bitmasks of a cache resource
+// may be non-contiguous, as on AMD (and Linux 6.4 or newer for Intel).
+func HasSparseMasks(resource string) bool {
+	rootPath, err := getIntelRdtRoot()
+	if err != nil {
+		return false
+	}
+	sparse, err := getIntelRdtParamUint(filepath.Join(rootPath, "info", resource), "sparse_masks")
+	return err == nil && sparse == 1
+}
+
+// GetMemBwInfo returns the read-only memory bandwidth information.
+func GetMemBwInfo() (*MemBwInfo, error) {
 	memBwInfo := &MemBwInfo{}
 
 	rootPath, err := getIntelRdtRoot()
@@ -519,6 +529,35 @@ func IsCATEnabled() bool {
 	return catEnabled
 }
 
+// Check if L2 CAT is enabled
+func IsL2CATEnabled() bool {
+	featuresInit()
+	return l2CatEnabled
+}
+
+// Check if Code and Data Prioritization (of L3 or L2 cache) is enabled
+func IsCDPEnabled() bool {
+	featuresInit()
+	return cdpEnabled
+}
+
+// IsResourceEnabled reports whether the resource (the name of a schema line,
+// such as "L3", "L2DATA" or "MB") is available.
+func IsResourceEnabled(resource string) bool {
+	featuresInit()
+	return enabledResources[resource]
+}
+
+// IsCacheResource reports whether resource is a cache resource, whose schema
+// values are capacity bitmasks.
+func IsCacheResource(resource string) bool {
+	switch resource {
+	case "L3", "L3CODE", "L3DATA", "L2", "L2CODE", "L2DATA":
+		return true
+	}
+	return false
+}
+
 // Check if Intel RDT/MBA is enabled
 func IsMBAEnabled() bool {
 	featuresInit()
@@ -531,6 +570,12 @@ func IsMBAScEnabled() bool {
 	return mbaScEnabled
 }
 
+// GetMonGroupPath returns the path of the container's monitoring group,
+// given the path of its resctrl group.
+func GetMonGroupPath(path, id string) string {
+	return filepath.Join(path, "mon_groups", id)
+}
+
 // Get the 'container_id' path in Intel RDT "resource control" filesystem
 func GetIntelRdtPath(id string) (string, error) {
 	rootPath, err := getIntelRdtRoot()
@@ -555,21 +600,59 @@ func (m *intelRdtManager) Apply(pid int) (err error) {
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	path, err := d.join(m.id)
+	path, created, err := d.join(m.groupName(), m.mustExist())
 	if err != nil {
 		return err
 	}
+	if created {
+		m.groupCreated = true
+	}
+	if m.config.IntelRdt.EnableMonitoring {
+		if err := d.joinMonGroup(path, m.id); err != nil {
+			return err
+		}
+	}
 
 	m.path = path
 	return nil
 }
 
-// Destroys the Intel RDT 'container_id' group
+// groupName returns the name of the container's resctrl group.
+func (m *intelRdtManager) groupName() string {
+	if m.config.IntelRdt != nil && m.config.IntelRdt.ClosID != "" {
+		return m.config.IntelRdt.ClosID
+	}
+	return m.id
+}
+
+// mustExist reports whether the container's resctrl group has to be created
+// beforehand, which is the case for a shared group without any schema.
+func (m *intelRdtManager) mustExist() bool {
+	c := m.config.IntelRdt
+	return c.ClosID != "" && c.L3CacheSchema == "" && c.MemBwSchema == ""
+}
+
+// Destroys the container's monitoring group, and its resctrl group unless it
+// is a shared one (which may still be used by other containers). A group
+// named by ClosID is only removed if it was created by Apply, and no other
+// tasks have joined it since.
 func (m *intelRdtManager) Destroy() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if err := os.RemoveAll(m.GetPath()); err != nil {
-		return err
+	if m.config.IntelRdt != nil && m.config.IntelRdt.EnableMonitoring && m.GetPath() != "" {
+		if err := os.RemoveAll(GetMonGroupPath(m.GetPath(), m.id)); err != nil {
+			return err
+		}
+	}
+	remove := m.config.IntelRdt == nil || m.config.IntelRdt.ClosID == ""
+	if !remove && m.groupCreated {
+		tasks, err := getIntelRdtParamString(m.GetPath(), intelRdtTasks)
+		remove = err == nil && tasks == ""
+	}
+	if remove {
+		if err := os.RemoveAll(m.GetPath()); err != nil {
+			return err
+		}
 	}
 	m.path = ""
 	return nil
@@ -579,11 +662,23 @@ func (m *intelRdtManager) Destroy() error {
 // restore the object later
 func (m *intelRdtManager) GetPath() string {
 	if m.path == "" {
-		m.path, _ = GetIntelRdtPath(m.id)
+		m.path, _ = GetIntelRdtPath(m.groupName())
 	}
 	return m.path
 }
 
+func (m *intelRdtManager) GroupCreated() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.groupCreated
+}
+
+func (m *intelRdtManager) SetGroupCreated(created bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groupCreated = created
+}
+
 // Returns statistics for Intel RDT
 func (m *intelRdtManager) GetStats() (*Stats, error) {
 	// If intelRdt is not specified in config
@@ -599,69 +694,74 @@ func (m *intelRdtManager) GetStats() (*Stats, error) {
 	if err != nil {
 		return nil, err
 	}
-	// The read-only L3 cache and memory bandwidth schemata in root
+	// The read-only schemata in root
 	tmpRootStrings, err := getIntelRdtParamString(rootPath, "schemata")
 	if err != nil {
 		return nil, err
 	}
-	schemaRootStrings := strings.Split(tmpRootStrings, "\n")
+	schemataRoot, err := parseSchemata(strings.Split(tmpRootStrings, "\n"))
+	if err != nil {
+		return nil, err
+	}
 
-	// The L3 cache and memory bandwidth schemata in 'container_id' group
+	// The schemata in 'container_id' group
 	containerPath := m.GetPath()
 	tmpStrings, err := getIntelRdtParamString(containerPath, "schemata")
 	if err != nil {
 		return nil, err
 	}
-	schemaStrings := strings.Split(tmpStrings, "\n")
+	schemata, err := parseSchemata(strings.Split(tmpStrings, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	stats.SchemataRoot = schemataRoot
+	stats.Schemata = schemata
 
-	if IsCATEnabled() {
-		// The read-only L3 cache information
-		l3CacheInfo, err := getL3CacheInfo()
+	// The read-only information of all the cache resources
+	stats.CacheInfo = make(map[string]*CacheInfo)
+	for resource := range enabledResources {
+		if !IsCacheResource(resource) {
+			continue
+		}
+		cacheInfo, err := GetCacheInfo(resource)
 		if err != nil {
 			return nil, err
 		}
-		stats.L3CacheInfo = l3CacheInfo
+		stats.CacheInfo[resource] = cacheInfo
+	}
 
-		// The read-only L3 cache schema in root
-		for _, schemaRoot := range schemaRootStrings {
-			if strings.Contains(schemaRoot, "L3") {
-				stats.L3CacheSchemaRoot = strings.TrimSpace(schemaRoot)
-			}
+	if IsCATEnabled() {
+		// The read-only L3 cache information (of L3CODE if CDP is
+		// enabled, as it is the same for L3DATA)
+		if stats.L3CacheInfo = stats.CacheInfo["L3"]; stats.L3CacheInfo == nil {
+			stats.L3CacheInfo = stats.CacheInfo["L3CODE"]
 		}
 
-		// The L3 cache schema in 'container_id' group
-		for _, schema := range schemaStrings {
-			if strings.Contains(schema, "L3") {
-				stats.L3CacheSchema = strings.TrimSpace(schema)
-			}
-		}
+		// The L3 cache schema in root, and in 'container_id' group
+		stats.L3CacheSchemaRoot = formatSchemata(schemataRoot, "L3", "L3CODE", "L3DATA")
+		stats.L3CacheSchema = formatSchemata(schemata, "L3", "L3CODE", "L3DATA")
 	}
 
 	if IsMBAEnabled() {
 		// The read-only memory bandwidth information
-		memBwInfo, err := getMemBwInfo()
+		memBwInfo, err := GetMemBwInfo()
 		if err != nil {
 			return nil, err
 		}
 		stats.MemBwInfo = memBwInfo
 
-		// The read-only memory bandwidth information
-		for _, schemaRoot := range schemaRootStrings {
-			if strings.Contains(schemaRoot, "MB") {
-				stats.MemBwSchemaRoot = strings.TrimSpace(schemaRoot)
-			}
-		}
-
-		// The memory bandwidth schema in 'container_id' group
-		for _, schema := range schemaStrings {
-			if strings.Contains(schema, "MB") {
-				stats.MemBwSchema = strings.TrimSpace(schema)
-			}
-		}
+		// The memory bandwidth schema in root, and in 'container_id'
+		// group
+		stats.MemBwSchemaRoot = formatSchemata(schemataRoot, "MB")
+		stats.MemBwSchema = formatSchemata(schemata, "MB")
 	}
 
 	if IsMBMEnabled() || IsCMTEnabled() {
-		err = getMonitoringStats(containerPath, stats)
+		monPath := containerPath
+		if m.config.IntelRdt.EnableMonitoring {
+			monPath = GetMonGroupPath(containerPath, m.id)
+		}
+		err = getMonitoringStats(monPath, stats)
 		if err != nil {
 			return nil, err
 		}
@@ -717,46 +817,150 @@ func (m *intelRdtManager) Set(container *configs.Config) error {
 	// For example, on a two-socket machine, the schema line could be
 	// "MB:0=5000;1=7000" which means 5000 MBps memory bandwidth limit on
 	// socket 0 and 7000 MBps memory bandwidth limit on socket 1.
+	//
+	//
+	// Other resources (L2 cache, L3 and L2 cache with Code and Data
+	// Prioritization enabled, as "L3CODE", "L3DATA", "L2CODE", "L2DATA",
+	// or resources specific to AMD) can be set through additional schema
+	// lines.
 	if container.IntelRdt != nil {
 		path := m.GetPath()
-		l3CacheSchema := container.IntelRdt.L3CacheSchema
-		memBwSchema := container.IntelRdt.MemBwSchema
+		var schemata []string
+		if container.IntelRdt.L3CacheSchema != "" {
+			schemata = append(schemata, container.IntelRdt.L3CacheSchema)
+		}
+		if container.IntelRdt.MemBwSchema != "" {
+			schemata = append(schemata, container.IntelRdt.MemBwSchema)
+		}
+		schemata = append(schemata, container.IntelRdt.Schemata...)
 
 		// Write a single joint schema string to schemata file
-		if l3CacheSchema != "" && memBwSchema != "" {
-			if err := writeFile(path, "schemata", l3CacheSchema+"\n"+memBwSchema); err != nil {
+		if len(schemata) > 0 {
+			if err := writeFile(path, "schemata", strings.Join(schemata, "\n")); err != nil {
 				return err
 			}
 		}
+	}
 
-		// Write only L3 cache schema string to schemata file
-		if l3CacheSchema != "" && memBwSchema == "" {
-			if err := writeFile(path, "schemata", l3CacheSchema); err != nil {
-				return err
-			}
+	return nil
+}
+
+// ParseSchema parses a schema line, in the format
+// "<resource>:<domain_id0>=<value0>;<domain_id1>=<value1>;...", and returns
+// the name of the resource and its values by domain id.
+func ParseSchema(line string) (string, map[string]string, error) {
+	line = strings.TrimSpace(line)
+	i := strings.IndexByte(line, ':')
+	if i <= 0 {
+		return "", nil, fmt.Errorf("invalid schema %q: no resource name", line)
+	}
+	resource := strings.TrimSpace(line[:i])
+	domains := make(map[string]string)
+	for _, domain := range strings.Split(line[i+1:], ";") {
+		j := strings.IndexByte(domain, '=')
+		if j < 0 {
+			return "", nil, fmt.Errorf("invalid schema %q: invalid domain %q", line, domain)
+		}
+		id, value := strings.TrimSpace(domain[:j]), strings.TrimSpace(domain[j+1:])
+		if id == "" || value == "" {
+			return "", nil, fmt.Errorf("invalid schema %q: invalid domain %q", line, domain)
 		}
+		if _, ok := domains[id]; ok {
+			return "", nil, fmt.Errorf("invalid schema %q: duplicated domain %s", line, id)
+		}
+		domains[id] = value
+	}
+	return resource, domains, nil
+}
 
-		// Write only memory bandwidth schema string to schemata file
-		if l3CacheSchema == "" && memBwSchema != "" {
-			if err := writeFile(path, "schemata", memBwSchema); err != nil {
-				return err
-			}
+// parseSchemata parses schema lines, and returns the values by resource name
+// and domain id. Empty lines are ignored.
+func parseSchemata(lines []string) (map[string]map[string]string, error) {
+	schemata := make(map[string]map[string]string)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
 		}
+		resource, domains, err := ParseSchema(line)
+		if err != nil {
+			return nil, err
+		}
+		schemata[resource] = domains
 	}
+	return schemata, nil
+}
 
-	return nil
+// GetRootSchemata returns the schemata of the root group by resource name and
+// domain id, which lists all the domains of each resource.
+func GetRootSchemata() (map[string]map[string]string, error) {
+	rootPath, err := getIntelRdtRoot()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := getIntelRdtParamString(rootPath, "schemata")
+	if err != nil {
+		return nil, err
+	}
+	return parseSchemata(strings.Split(contents, "\n"))
 }
 
-func (raw *intelRdtData) join(id string) (string, error) {
-	path := filepath.Join(raw.root, id)
-	if err := os.MkdirAll(path, 0o755); err != nil {
-		return "", newLastCmdError(err)
+// formatSchemata returns the schema lines of the given resources, sorted by
+// domain id, and separated by newlines.
+func formatSchemata(schemata map[string]map[string]string, resources ...string) string {
+	var lines []string
+	for _, resource := range resources {
+		domains, ok := schemata[resource]
+		if !ok {
+			continue
+		}
+		ids := make([]string, 0, len(domains))
+		for id := range domains {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, errA := strconv.Atoi(ids[i])
+			b, errB := strconv.Atoi(ids[j])
+			if errA != nil || errB != nil {
+				return ids[i] < ids[j]
+			}
+			return a < b
+		})
+		values := make([]string, 0, len(ids))
+		for _, id := range ids {
+			values = append(values, id+"="+domains[id])
+		}
+		lines = append(lines, resource+":"+strings.Join(values, ";"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (raw *intelRdtData) join(name string, mustExist bool) (_ string, created bool, _ error) {
+	path := filepath.Join(raw.root, name)
+	if _, err := os.Stat(path); err != nil {
+		if mustExist {
+			return "", false, fmt.Errorf("intelrdt: group %s must exist when no schema is specified: %w", name, err)
+		}
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return "", false, newLastCmdError(err)
+		}
+		created = true
 	}
 
 	if err := WriteIntelRdtTasks(path, raw.pid); err != nil {
-		return "", err
+		return "", created, err
 	}
-	return path, nil
+	return path, created, nil
+}
+
+// joinMonGroup creates the container's monitoring group in the resctrl group
+// at path, and moves the process into it. The process must already be in the
+// resctrl group.
+func (raw *intelRdtData) joinMonGroup(path, id string) error {
+	monPath := GetMonGroupPath(path, id)
+	if err := os.MkdirAll(monPath, 0o755); err != nil {
+		return newLastCmdError(err)
+	}
+	return WriteIntelRdtTasks(monPath, raw.pid)
 }
 
 func newLastCmdError(err error) error {
@@ -5,6 +5,10 @@ package intelrdt
 import (
 	"errors"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -251,3 +255,186 @@ func TestFindIntelRdtMountpointDir(t *testing.T) {
 		})
 	}
 }
+
+func TestIntelRdtSharedGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+
+	d := helper.IntelRdtData
+	d.pid = -1
+	d.config.IntelRdt.ClosID = "shared"
+	d.config.IntelRdt.EnableMonitoring = true
+
+	// A shared group without schemata has to exist.
+	if _, _, err := d.join("shared", true); err == nil {
+		t.Fatal("expected an error for a missing shared group")
+	}
+	if err := os.Mkdir(filepath.Join(d.root, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, created, err := d.join("shared", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("expected an existing group not to be reported as created")
+	}
+	if err := d.joinMonGroup(path, "ctr"); err != nil {
+		t.Fatal(err)
+	}
+	monPath := filepath.Join(d.root, "shared", "mon_groups", "ctr")
+	if _, err := os.Stat(monPath); err != nil {
+		t.Fatalf("expected the monitoring group to be created: %v", err)
+	}
+
+	// Only the monitoring group is removed.
+	m := NewManager(d.config, "ctr", path)
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(monPath); !os.IsNotExist(err) {
+		t.Errorf("expected the monitoring group to be removed, got %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the shared group to be kept: %v", err)
+	}
+
+	// The container's own group is removed.
+	d.config.IntelRdt.ClosID = ""
+	path, _, err = d.join("ctr", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = NewManager(d.config, "ctr", path)
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the container's group to be removed, got %v", err)
+	}
+}
+
+func TestIntelRdtCreatedSharedGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+
+	d := helper.IntelRdtData
+	d.pid = -1
+	d.config.IntelRdt.ClosID = "new"
+
+	// A group with schemata is created if it does not exist.
+	path, created, err := d.join("new", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatal("expected the group to be reported as created")
+	}
+	if err := writeFile(path, intelRdtTasks, "42\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The group created by runc is kept while other tasks use it, ...
+	m := NewManager(d.config, "ctr", path)
+	m.SetGroupCreated(true)
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the group to be kept while in use: %v", err)
+	}
+
+	// ... and removed otherwise.
+	if err := writeFile(path, intelRdtTasks, ""); err != nil {
+		t.Fatal(err)
+	}
+	m = NewManager(d.config, "ctr", path)
+	m.SetGroupCreated(true)
+	if err := m.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the created group to be removed, got %v", err)
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	testCases := []struct {
+		line     string
+		resource string
+		domains  map[string]string
+		isError  bool
+	}{
+		{line: "L3:0=7f0;1=1f", resource: "L3", domains: map[string]string{"0": "7f0", "1": "1f"}},
+		{line: "    MB:0=100;1=100", resource: "MB", domains: map[string]string{"0": "100", "1": "100"}},
+		{line: "L2CODE:0 = ff", resource: "L2CODE", domains: map[string]string{"0": "ff"}},
+		{line: "0=ff", isError: true},
+		{line: "L3:", isError: true},
+		{line: "L3:0", isError: true},
+		{line: "L3:0=", isError: true},
+		{line: "L3:0=f;0=f0", isError: true},
+	}
+	for _, tc := range testCases {
+		resource, domains, err := ParseSchema(tc.line)
+		if tc.isError {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.line, err)
+			continue
+		}
+		if resource != tc.resource || !reflect.DeepEqual(domains, tc.domains) {
+			t.Errorf("%q: expected %s %v, got %s %v", tc.line, tc.resource, tc.domains, resource, domains)
+		}
+	}
+}
+
+func TestFormatSchemata(t *testing.T) {
+	schemata, err := parseSchemata([]string{
+		"L3CODE:10=f;2=f0",
+		"L3DATA:2=ff;10=ff",
+		"",
+		"MB:0=50",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := formatSchemata(schemata, "L3", "L3CODE", "L3DATA"); s != "L3CODE:2=f0;10=f\nL3DATA:2=ff;10=ff" {
+		t.Errorf("unexpected L3 schemata %q", s)
+	}
+	if s := formatSchemata(schemata, "MB"); s != "MB:0=50" {
+		t.Errorf("unexpected MB schemata %q", s)
+	}
+	if s := formatSchemata(schemata, "L2"); s != "" {
+		t.Errorf("expected no L2 schemata, got %q", s)
+	}
+}
+
+func TestGetResources(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	defer helper.cleanup()
+
+	root := helper.IntelRdtPath
+	for _, dir := range []string{"L3CODE", "L3DATA", "L2", "MB", "L3_MON"} {
+		if err := os.MkdirAll(filepath.Join(root, "info", dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "info", "last_cmd_status"), []byte("ok\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resources, monitoring, err := getResources(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"L3CODE": true, "L3DATA": true, "L2": true, "MB": true}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("expected resources %v, got %v", expected, resources)
+	}
+	if !monitoring {
+		t.Error("expected monitoring to be available")
+	}
+}
@@ -2,12 +2,16 @@
 
 package intelrdt
 
-type L3CacheInfo struct {
+// CacheInfo is the read-only information of a cache resource.
+type CacheInfo struct {
 	CbmMask    string `json:"cbm_mask,omitempty"`
 	MinCbmBits uint64 `json:"min_cbm_bits,omitempty"`
 	NumClosids uint64 `json:"num_closids,omitempty"`
 }
 
+// L3CacheInfo is the read-only information of the L3 cache.
+type L3CacheInfo = CacheInfo
+
 type MemBwInfo struct {
 	BandwidthGran uint64 `json:"bandwidth_gran,omitempty"`
 	DelayLinear   uint64 `json:"delay_linear,omitempty"`
@@ -52,6 +56,16 @@ type Stats struct {
 
 	// The cache monitoring technology statistics from NUMA nodes in 'container_id' group
 	CMTStats *[]CMTNumaNodeStats `json:"cmt_stats,omitempty"`
+
+	// The read-only information of all the cache resources (e.g. "L3",
+	// "L2CODE") by resource name
+	CacheInfo map[string]*CacheInfo `json:"cache_info,omitempty"`
+
+	// The read-only schemata in root, by resource name and domain id
+	SchemataRoot map[string]map[string]string `json:"schemata_root,omitempty"`
+
+	// The schemata in 'container_id' group, by resource name and domain id
+	Schemata map[string]map[string]string `json:"schemata,omitempty"`
 }
 
 func newStats() *Stats {
@@ -0,0 +1,41 @@
+package libcontainer
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+// runInNS runs fn on a dedicated OS thread which has joined the namespace of
+// the given type at path. The thread is terminated once fn returns, as it
+// can not be reused by the Go runtime.
+func runInNS(path string, nsType configs.NamespaceType, fn func() error) error {
+	ns := configs.Namespace{Type: nsType}
+	errCh := make(chan error, 1)
+	go func() {
+		// Do not call runtime.UnlockOSThread, so that the thread exits
+		// together with this goroutine.
+		runtime.LockOSThread()
+		errCh <- func() error {
+			fd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return &os.PathError{Op: "open", Path: path, Err: err}
+			}
+			defer unix.Close(fd)
+			if nsType == configs.NEWNS {
+				// Joining a mount namespace requires the filesystem
+				// attributes not to be shared with any other thread.
+				if err := unix.Unshare(unix.CLONE_FS); err != nil {
+					return os.NewSyscallError("unshare", err)
+				}
+			}
+			if err := unix.Setns(fd, ns.Syscall()); err != nil {
+				return os.NewSyscallError("setns", err)
+			}
+			return fn()
+		}()
+	}()
+	return <-errCh
+}
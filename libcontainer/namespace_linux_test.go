@@ -0,0 +1,48 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// errSkip can be wrapped by the error returned from an inPrivateNS function
+// to skip the test, since t.Skip must not be called from another goroutine.
+var errSkip = errors.New("skipping test")
+
+// inPrivateNS runs fn on a dedicated OS thread in the new namespaces given by
+// flags (CLONE_NEW*). A new mount namespace has all its mounts made private.
+// The test is skipped if the namespaces cannot be created, or if fn returns
+// an error wrapping errSkip.
+func inPrivateNS(t *testing.T, flags int, fn func() error) {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		// Do not unlock the thread, so that it exits (together with its
+		// namespaces) with this goroutine.
+		runtime.LockOSThread()
+		errCh <- func() error {
+			if flags&unix.CLONE_NEWNS != 0 {
+				flags |= unix.CLONE_FS
+			}
+			if err := unix.Unshare(flags); err != nil {
+				return fmt.Errorf("%w: unable to create namespaces: %v", errSkip, err)
+			}
+			if flags&unix.CLONE_NEWNS != 0 {
+				if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+					return err
+				}
+			}
+			return fn()
+		}()
+	}()
+	if err := <-errCh; err != nil {
+		if errors.Is(err, errSkip) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+}
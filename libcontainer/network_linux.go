@@ -3,19 +3,35 @@
 package libcontainer
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/types"
+	"github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 var strategies = map[string]networkStrategy{
-	"loopback": &loopback{},
+	"veth":        &veth{},
+	"host-device": &hostDevice{},
+	"macvlan":     &subInterface{newLink: newMacvlan},
+	"ipvlan":      &subInterface{newLink: newIPVlan},
+	"loopback":    &loopback{},
 }
 
 // networkStrategy represents a specific network configuration for
@@ -25,6 +41,7 @@ type networkStrategy interface {
 	initialize(*network) error
 	detach(*configs.Network) error
 	attach(*configs.Network) error
+	destroy(n *configs.Network, nsPath string) error
 }
 
 // getStrategy returns the specific network strategy for the
@@ -100,3 +117,519 @@ func (l *loopback) attach(n *configs.Network) (err error) {
 func (l *loopback) detach(n *configs.Network) (err error) {
 	return nil
 }
+
+func (l *loopback) destroy(n *configs.Network, nsPath string) error {
+	return nil
+}
+
+// veth is a network strategy that uses a bridge and creates
+// a veth pair, one that is attached to the bridge on the host and the other
+// is placed inside the container's namespace
+type veth struct{}
+
+// detach the host side of the veth pair from the bridge, blocking any
+// external network activity of the container.
+func (v *veth) detach(n *configs.Network) error {
+	host, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetNoMaster(host)
+}
+
+// attach the host side of the veth pair to the bridge.
+func (v *veth) attach(n *configs.Network) error {
+	brl, err := netlink.LinkByName(n.Bridge)
+	if err != nil {
+		return err
+	}
+	br, ok := brl.(*netlink.Bridge)
+	if !ok {
+		return fmt.Errorf("%s is not a bridge (%s)", n.Bridge, brl.Type())
+	}
+	host, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetMaster(host, br); err != nil {
+		return err
+	}
+	if n.Mtu != 0 {
+		if err := netlink.LinkSetMTU(host, n.Mtu); err != nil {
+			return err
+		}
+	}
+	if n.HairpinMode {
+		if err := netlink.LinkSetHairpin(host, true); err != nil {
+			return err
+		}
+	}
+	return netlink.LinkSetUp(host)
+}
+
+func (v *veth) create(n *network, nspid int) (err error) {
+	if n.Bridge == "" {
+		return errors.New("bridge is not specified")
+	}
+	n.TempVethPeerName, err = generateTempPeerName()
+	if err != nil {
+		return err
+	}
+	link := &netlink.Veth{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:   n.HostInterfaceName,
+			MTU:    n.Mtu,
+			TxQLen: n.TxQueueLen,
+		},
+		PeerName: n.TempVethPeerName,
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("unable to create veth pair %s: %w", n.HostInterfaceName, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = netlink.LinkDel(link)
+		}
+	}()
+	if err := v.attach(&n.Network); err != nil {
+		return err
+	}
+	peer, err := netlink.LinkByName(n.TempVethPeerName)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetNsPid(peer, nspid)
+}
+
+func (v *veth) initialize(config *network) error {
+	if config.TempVethPeerName == "" {
+		return errors.New("peer is not specified")
+	}
+	link, err := netlink.LinkByName(config.TempVethPeerName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetName(link, config.Name); err != nil {
+		return err
+	}
+	return configureLink(link, &config.Network)
+}
+
+// destroy removes the veth pair, by deleting its host side. The pair is
+// usually gone already, together with the container's network namespace.
+func (v *veth) destroy(n *configs.Network, nsPath string) error {
+	host, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		if isLinkNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return netlink.LinkDel(host)
+}
+
+func isLinkNotFound(err error) bool {
+	var notFound netlink.LinkNotFoundError
+	return errors.As(err, &notFound)
+}
+
+// generateTempPeerName returns a random name for the container side of a veth
+// pair, which is used until it is renamed inside the container.
+func generateTempPeerName() (string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return "veth" + hex.EncodeToString(id), nil
+}
+
+// configureLink sets the MAC address, MTU, addresses and gateways of the
+// given link inside the container, and brings it up.
+func configureLink(link netlink.Link, config *configs.Network) error {
+	if config.MacAddress != "" {
+		mac, err := net.ParseMAC(config.MacAddress)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetHardwareAddr(link, mac); err != nil {
+			return err
+		}
+	}
+	if config.Mtu != 0 {
+		if err := netlink.LinkSetMTU(link, config.Mtu); err != nil {
+			return err
+		}
+	}
+	for _, address := range []string{config.Address, config.IPv6Address} {
+		if address == "" {
+			continue
+		}
+		addr, err := netlink.ParseAddr(address)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(link, addr); err != nil {
+			return fmt.Errorf("unable to add address %s to %s: %w", address, config.Name, err)
+		}
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return err
+	}
+	for _, gateway := range []string{config.Gateway, config.IPv6Gateway} {
+		if gateway == "" {
+			continue
+		}
+		gw := net.ParseIP(gateway)
+		if gw == nil {
+			return fmt.Errorf("invalid gateway %q", gateway)
+		}
+		if err := netlink.RouteAdd(&netlink.Route{
+			Scope:     netlink.SCOPE_UNIVERSE,
+			LinkIndex: link.Attrs().Index,
+			Gw:        gw,
+		}); err != nil {
+			return fmt.Errorf("unable to add default route via %s: %w", gateway, err)
+		}
+	}
+	return nil
+}
+
+var (
+	macvlanModes = map[string]netlink.MacvlanMode{
+		"":         netlink.MACVLAN_MODE_BRIDGE,
+		"private":  netlink.MACVLAN_MODE_PRIVATE,
+		"vepa":     netlink.MACVLAN_MODE_VEPA,
+		"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+		"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+	}
+	ipvlanModes = map[string]netlink.IPVlanMode{
+		"":    netlink.IPVLAN_MODE_L2,
+		"l2":  netlink.IPVLAN_MODE_L2,
+		"l3":  netlink.IPVLAN_MODE_L3,
+		"l3s": netlink.IPVLAN_MODE_L3S,
+	}
+)
+
+func newMacvlan(attrs netlink.LinkAttrs, mode string) (netlink.Link, error) {
+	m, ok := macvlanModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("invalid macvlan mode %q", mode)
+	}
+	return &netlink.Macvlan{LinkAttrs: attrs, Mode: m}, nil
+}
+
+func newIPVlan(attrs netlink.LinkAttrs, mode string) (netlink.Link, error) {
+	m, ok := ipvlanModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("invalid ipvlan mode %q", mode)
+	}
+	return &netlink.IPVlan{LinkAttrs: attrs, Mode: m}, nil
+}
+
+// subInterface is a network strategy that creates a sub-interface (such as
+// a macvlan or ipvlan interface) of a host interface, and places it inside
+// the container's namespace. The sub-interface goes away together with the
+// container's namespace.
+type subInterface struct {
+	newLink func(attrs netlink.LinkAttrs, mode string) (netlink.Link, error)
+}
+
+func (s *subInterface) create(n *network, nspid int) (err error) {
+	parent, err := netlink.LinkByName(n.Parent)
+	if err != nil {
+		return fmt.Errorf("unable to find parent interface %q: %w", n.Parent, err)
+	}
+	n.TempVethPeerName, err = generateTempPeerName()
+	if err != nil {
+		return err
+	}
+	link, err := s.newLink(netlink.LinkAttrs{
+		Name:        n.TempVethPeerName,
+		ParentIndex: parent.Attrs().Index,
+		MTU:         n.Mtu,
+		TxQLen:      n.TxQueueLen,
+	}, n.Mode)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		return fmt.Errorf("unable to create %s interface on %s: %w", link.Type(), n.Parent, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = netlink.LinkDel(link)
+		}
+	}()
+	return netlink.LinkSetNsPid(link, nspid)
+}
+
+func (s *subInterface) initialize(config *network) error {
+	if config.TempVethPeerName == "" {
+		return errors.New("interface is not specified")
+	}
+	link, err := netlink.LinkByName(config.TempVethPeerName)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetName(link, config.Name); err != nil {
+		return err
+	}
+	return configureLink(link, &config.Network)
+}
+
+func (s *subInterface) attach(n *configs.Network) error {
+	return nil
+}
+
+func (s *subInterface) detach(n *configs.Network) error {
+	return nil
+}
+
+func (s *subInterface) destroy(n *configs.Network, nsPath string) error {
+	return nil
+}
+
+// hostDevice is a network strategy that moves an existing host interface
+// (such as a physical NIC or an SR-IOV VF) into the container's namespace,
+// optionally renaming it, and moves it back to the host on destroy.
+type hostDevice struct{}
+
+// hostDeviceName returns the name of a host-device interface inside the
+// container.
+func hostDeviceName(n *configs.Network) string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.HostInterfaceName
+}
+
+// create moves the interface into the container. Its index, and an
+// alternative name given to it (if supported by the kernel), are recorded to
+// find it again once it is back to the host, as its name may have changed.
+func (d *hostDevice) create(n *network, nspid int) error {
+	link, err := netlink.LinkByName(n.HostInterfaceName)
+	if err != nil {
+		return fmt.Errorf("unable to find host interface %q: %w", n.HostInterfaceName, err)
+	}
+	n.HostInterfaceIndex = link.Attrs().Index
+	n.HostInterfaceAltName = ""
+	altName, err := generateAltName()
+	if err != nil {
+		return err
+	}
+	if err := linkAltName(unix.RTM_NEWLINKPROP, link, altName); err == nil {
+		n.HostInterfaceAltName = altName
+	} else {
+		logrus.Debugf("unable to add alternative name to %s, using its index: %v", n.HostInterfaceName, err)
+	}
+	if err := netlink.LinkSetNsPid(link, nspid); err != nil {
+		if n.HostInterfaceAltName != "" {
+			_ = linkAltName(unix.RTM_DELLINKPROP, link, n.HostInterfaceAltName)
+		}
+		return fmt.Errorf("unable to move %s into the container: %w", n.HostInterfaceName, err)
+	}
+	return nil
+}
+
+// generateAltName returns a random alternative name for a host-device
+// interface. It is short enough to be used as a regular interface name in
+// lookups.
+func generateAltName() (string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return "runc" + hex.EncodeToString(id), nil
+}
+
+// linkAltName adds (with RTM_NEWLINKPROP) or removes (with RTM_DELLINKPROP)
+// an alternative name of the link. This requires Linux 5.5.
+func linkAltName(cmd int, link netlink.Link, name string) error {
+	req := nl.NewNetlinkRequest(cmd, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+	props := nl.NewRtAttr(unix.IFLA_PROP_LIST|unix.NLA_F_NESTED, nil)
+	props.AddRtAttr(unix.IFLA_ALT_IFNAME, nl.ZeroTerminated(name))
+	req.AddData(props)
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// hostDeviceLink returns the host-device interface in the current network
+// namespace, using what was recorded when it was moved into the container.
+func hostDeviceLink(n *configs.Network) (netlink.Link, error) {
+	if n.HostInterfaceAltName != "" {
+		return netlink.LinkByName(n.HostInterfaceAltName)
+	}
+	return netlink.LinkByIndex(n.HostInterfaceIndex)
+}
+
+func (d *hostDevice) initialize(config *network) error {
+	link, err := netlink.LinkByName(config.HostInterfaceName)
+	if err != nil {
+		return err
+	}
+	if name := hostDeviceName(&config.Network); name != config.HostInterfaceName {
+		if err := netlink.LinkSetDown(link); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetName(link, name); err != nil {
+			return err
+		}
+	}
+	return configureLink(link, &config.Network)
+}
+
+func (d *hostDevice) attach(n *configs.Network) error {
+	return nil
+}
+
+func (d *hostDevice) detach(n *configs.Network) error {
+	return nil
+}
+
+// hostDeviceReturnTimeout is how long to wait for the kernel to move a
+// physical interface back to the host, once the network namespace it was in
+// is gone. This happens asynchronously.
+const hostDeviceReturnTimeout = 5 * time.Second
+
+// destroy moves the interface back to the host and restores its name. If the
+// container's network namespace is still around (i.e. it was an existing one
+// at nsPath), the interface is moved out of it. Otherwise, the kernel moves
+// physical interfaces back to the host on its own (while virtual ones are
+// deleted), so it is only renamed. On the host, the interface is looked up by
+// its alternative name or index, never by its name inside the container,
+// which may be used by another host interface, and which the kernel changes
+// to dev%d on conflict.
+func (d *hostDevice) destroy(n *configs.Network, nsPath string) error {
+	if n.HostInterfaceAltName == "" && n.HostInterfaceIndex == 0 {
+		// The interface was not moved into the container.
+		return nil
+	}
+	if nsPath != "" {
+		hostNS, err := unix.Open("/proc/thread-self/ns/net", unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: "/proc/thread-self/ns/net", Err: err}
+		}
+		defer unix.Close(hostNS)
+		err = runInNS(nsPath, configs.NEWNET, func() error {
+			// The index may have changed on the way in, but the name
+			// inside the container can be used here.
+			name := n.HostInterfaceAltName
+			if name == "" {
+				name = hostDeviceName(n)
+			}
+			link, err := netlink.LinkByName(name)
+			if err != nil {
+				return err
+			}
+			if err := netlink.LinkSetDown(link); err != nil {
+				return err
+			}
+			return netlink.LinkSetNsFd(link, hostNS)
+		})
+		if err != nil && !isLinkNotFound(err) {
+			return fmt.Errorf("unable to move %s back to the host: %w", n.HostInterfaceName, err)
+		}
+	}
+	deadline := time.Now().Add(hostDeviceReturnTimeout)
+	for {
+		link, err := hostDeviceLink(n)
+		if err == nil {
+			return restoreHostDevice(link, n)
+		}
+		if !isLinkNotFound(err) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			logrus.Warnf("interface %s did not come back to the host", n.HostInterfaceName)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// restoreHostDevice restores the host name of a host-device interface which
+// is back to the host, and removes its alternative name.
+func restoreHostDevice(link netlink.Link, n *configs.Network) error {
+	if link.Attrs().Name != n.HostInterfaceName {
+		if err := netlink.LinkSetDown(link); err != nil {
+			return err
+		}
+		if err := netlink.LinkSetName(link, n.HostInterfaceName); err != nil {
+			return fmt.Errorf("unable to rename %s back to %s: %w", link.Attrs().Name, n.HostInterfaceName, err)
+		}
+	}
+	if n.HostInterfaceAltName != "" {
+		if err := linkAltName(unix.RTM_DELLINKPROP, link, n.HostInterfaceAltName); err != nil {
+			logrus.Warnf("unable to remove alternative name %s of %s: %v", n.HostInterfaceAltName, n.HostInterfaceName, err)
+		}
+	}
+	return nil
+}
+
+// getNetNSInterfaceStats returns the statistics of the named network
+// interface in the network namespace of the given process.
+func getNetNSInterfaceStats(pid int, interfaceName string) (*types.NetworkInterface, error) {
+	path := fmt.Sprintf("/proc/%d/net/dev", pid)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseNetDev(f, interfaceName)
+}
+
+// parseNetDev returns the statistics of the named interface from the
+// contents of /proc/net/dev.
+func parseNetDev(r io.Reader, interfaceName string) (*types.NetworkInterface, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 || strings.TrimSpace(line[:colon]) != interfaceName {
+			continue
+		}
+		fields := strings.Fields(line[colon+1:])
+		if len(fields) < 12 {
+			return nil, fmt.Errorf("invalid /proc/net/dev entry for %s: %q", interfaceName, line)
+		}
+		out := &types.NetworkInterface{Name: interfaceName}
+		// Receive bytes, packets, errs and drop, then (after fifo, frame,
+		// compressed and multicast) the same for transmit.
+		for i, v := range []*uint64{
+			&out.RxBytes, &out.RxPackets, &out.RxErrors, &out.RxDropped,
+			nil, nil, nil, nil,
+			&out.TxBytes, &out.TxPackets, &out.TxErrors, &out.TxDropped,
+		} {
+			if v == nil {
+				continue
+			}
+			var err error
+			if *v, err = strconv.ParseUint(fields[i], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid /proc/net/dev entry for %s: %w", interfaceName, err)
+			}
+		}
+		return out, nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// destroyNetworks releases the host resources of the container's networks.
+func destroyNetworks(config *configs.Config) error {
+	nsPath := config.Namespaces.PathOf(configs.NEWNET)
+	for _, n := range config.Networks {
+		strategy, err := getStrategy(n.Type)
+		if err != nil {
+			return err
+		}
+		if err := strategy.destroy(n, nsPath); err != nil {
+			return fmt.Errorf("unable to destroy %s network %s: %w", n.Type, n.Name, err)
+		}
+	}
+	return nil
+}
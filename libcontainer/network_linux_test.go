@@ -0,0 +1,402 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/types"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// inPrivateNetNS runs fn in a new network namespace, which contains a dummy
+// interface named dummy0. The test is skipped if the interface cannot be
+// created, or as documented by inPrivateNS.
+func inPrivateNetNS(t *testing.T, fn func() error) {
+	t.Helper()
+	inPrivateNS(t, unix.CLONE_NEWNET, func() error {
+		dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "dummy0"}}
+		if err := netlink.LinkAdd(dummy); err != nil {
+			if errors.Is(err, unix.EOPNOTSUPP) {
+				return fmt.Errorf("%w: dummy interfaces are not supported: %v", errSkip, err)
+			}
+			return err
+		}
+		if err := netlink.LinkSetUp(dummy); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+func TestSubInterfaceStrategies(t *testing.T) {
+	for _, tpe := range []string{"macvlan", "ipvlan"} {
+		t.Run(tpe, func(t *testing.T) {
+			inPrivateNetNS(t, func() error {
+				strategy, err := getStrategy(tpe)
+				if err != nil {
+					return err
+				}
+				n := &network{
+					Network: configs.Network{
+						Type:    tpe,
+						Name:    "eth0",
+						Parent:  "dummy0",
+						Address: "192.0.2.10/24",
+						Gateway: "192.0.2.1",
+						Mtu:     1400,
+					},
+				}
+				// Use the current thread's network namespace as the
+				// container's one.
+				if err := strategy.create(n, unix.Gettid()); err != nil {
+					if errors.Is(err, unix.EOPNOTSUPP) {
+						return fmt.Errorf("%w: %s is not supported: %v", errSkip, tpe, err)
+					}
+					return err
+				}
+				if err := strategy.initialize(n); err != nil {
+					return err
+				}
+				link, err := netlink.LinkByName("eth0")
+				if err != nil {
+					return err
+				}
+				if link.Type() != tpe {
+					t.Errorf("expected %s interface, got %s", tpe, link.Type())
+				}
+				if mtu := link.Attrs().MTU; mtu != 1400 {
+					t.Errorf("expected mtu 1400, got %d", mtu)
+				}
+				addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+				if err != nil {
+					return err
+				}
+				if len(addrs) != 1 || addrs[0].IPNet.String() != "192.0.2.10/24" {
+					t.Errorf("expected address 192.0.2.10/24, got %v", addrs)
+				}
+
+				config := &configs.Config{
+					Routes: []*configs.Route{
+						{Destination: "198.51.100.0/24", Gateway: "192.0.2.2", InterfaceName: "eth0"},
+						{Destination: "203.0.113.0/24", InterfaceName: "eth0"},
+					},
+				}
+				if err := setupRoute(config); err != nil {
+					return err
+				}
+				routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+				if err != nil {
+					return err
+				}
+				expected := map[string]string{
+					"<nil>":           "192.0.2.1",
+					"198.51.100.0/24": "192.0.2.2",
+					"203.0.113.0/24":  "<nil>",
+				}
+				for _, r := range routes {
+					dst := "<nil>"
+					if r.Dst != nil {
+						dst = r.Dst.String()
+					}
+					if gw, ok := expected[dst]; ok {
+						if r.Gw.String() != gw {
+							t.Errorf("route to %s: expected gateway %s, got %v", dst, gw, r.Gw)
+						}
+						delete(expected, dst)
+					}
+				}
+				if len(expected) != 0 {
+					t.Errorf("missing routes %v, got %v", expected, routes)
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func TestSubInterfaceMissingParent(t *testing.T) {
+	inPrivateNetNS(t, func() error {
+		n := &network{
+			Network: configs.Network{
+				Type:   "macvlan",
+				Name:   "eth0",
+				Parent: "missing0",
+			},
+		}
+		if err := strategies["macvlan"].create(n, unix.Gettid()); err == nil {
+			t.Error("expected an error for a missing parent interface")
+		}
+		return nil
+	})
+}
+
+// withContainerNetNS runs fn with the id of another thread in a new network
+// namespace, used as the container's one, and the path of that namespace.
+func withContainerNetNS(fn func(tid int, nsPath string) error) error {
+	tidCh := make(chan int)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// Do not unlock the thread, so that it exits (together with its
+		// network namespace) with this goroutine.
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			tidCh <- -1
+			return
+		}
+		tidCh <- unix.Gettid()
+		<-done
+	}()
+	tid := <-tidCh
+	if tid < 0 {
+		return errors.New("unable to create the container's network namespace")
+	}
+	return fn(tid, fmt.Sprintf("/proc/%d/ns/net", tid))
+}
+
+func TestVethStrategy(t *testing.T) {
+	inPrivateNS(t, unix.CLONE_NEWNET, func() error {
+		bridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "br0"}}
+		if err := netlink.LinkAdd(bridge); err != nil {
+			if errors.Is(err, unix.EOPNOTSUPP) {
+				return fmt.Errorf("%w: bridges are not supported: %v", errSkip, err)
+			}
+			return err
+		}
+		br, err := netlink.LinkByName("br0")
+		if err != nil {
+			return err
+		}
+		return withContainerNetNS(func(tid int, nsPath string) error {
+			n := &network{
+				Network: configs.Network{
+					Type:              "veth",
+					Name:              "eth0",
+					Bridge:            "br0",
+					HostInterfaceName: "veth0",
+					Address:           "192.0.2.10/24",
+					Gateway:           "192.0.2.1",
+					Mtu:               1400,
+				},
+			}
+			strategy := strategies["veth"]
+			if err := strategy.create(n, tid); err != nil {
+				if errors.Is(err, unix.EOPNOTSUPP) {
+					return fmt.Errorf("%w: veth is not supported: %v", errSkip, err)
+				}
+				return err
+			}
+			host, err := netlink.LinkByName("veth0")
+			if err != nil {
+				return err
+			}
+			if host.Attrs().MasterIndex != br.Attrs().Index {
+				t.Errorf("expected veth0 to be attached to br0, got master %d", host.Attrs().MasterIndex)
+			}
+			if mtu := host.Attrs().MTU; mtu != 1400 {
+				t.Errorf("expected veth0 mtu 1400, got %d", mtu)
+			}
+			if _, err := netlink.LinkByName(n.TempVethPeerName); err == nil {
+				t.Errorf("expected the peer %s to be moved out of the host", n.TempVethPeerName)
+			}
+
+			err = runInNS(nsPath, configs.NEWNET, func() error {
+				if err := strategy.initialize(n); err != nil {
+					return err
+				}
+				if _, err := netlink.LinkByName(n.TempVethPeerName); err == nil {
+					t.Errorf("expected the peer %s to be renamed", n.TempVethPeerName)
+				}
+				link, err := netlink.LinkByName("eth0")
+				if err != nil {
+					return err
+				}
+				if link.Type() != "veth" {
+					t.Errorf("expected veth interface, got %s", link.Type())
+				}
+				if link.Attrs().Flags&net.FlagUp == 0 {
+					t.Error("expected eth0 to be up")
+				}
+				if mtu := link.Attrs().MTU; mtu != 1400 {
+					t.Errorf("expected eth0 mtu 1400, got %d", mtu)
+				}
+				addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+				if err != nil {
+					return err
+				}
+				if len(addrs) != 1 || addrs[0].IPNet.String() != "192.0.2.10/24" {
+					t.Errorf("expected address 192.0.2.10/24, got %v", addrs)
+				}
+				config := &configs.Config{
+					Routes: []*configs.Route{
+						{Destination: "198.51.100.0/24", Gateway: "192.0.2.2", InterfaceName: "eth0"},
+					},
+				}
+				if err := setupRoute(config); err != nil {
+					return err
+				}
+				routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+				if err != nil {
+					return err
+				}
+				expected := map[string]string{
+					"<nil>":           "192.0.2.1",
+					"198.51.100.0/24": "192.0.2.2",
+				}
+				for _, r := range routes {
+					dst := "<nil>"
+					if r.Dst != nil {
+						dst = r.Dst.String()
+					}
+					if gw, ok := expected[dst]; ok && r.Gw.String() == gw {
+						delete(expected, dst)
+					}
+				}
+				if len(expected) != 0 {
+					t.Errorf("missing routes %v, got %v", expected, routes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// Detaching and attaching again the host side.
+			if err := strategy.detach(&n.Network); err != nil {
+				return err
+			}
+			if host, err = netlink.LinkByName("veth0"); err != nil {
+				return err
+			}
+			if host.Attrs().MasterIndex != 0 {
+				t.Errorf("expected veth0 to be detached, got master %d", host.Attrs().MasterIndex)
+			}
+			if err := strategy.attach(&n.Network); err != nil {
+				return err
+			}
+			if host, err = netlink.LinkByName("veth0"); err != nil {
+				return err
+			}
+			if host.Attrs().MasterIndex != br.Attrs().Index {
+				t.Errorf("expected veth0 to be attached again, got master %d", host.Attrs().MasterIndex)
+			}
+
+			// Deleting the host side deletes the whole pair.
+			if err := strategy.destroy(&n.Network, nsPath); err != nil {
+				return err
+			}
+			if _, err := netlink.LinkByName("veth0"); err == nil {
+				t.Error("expected veth0 to be deleted")
+			}
+			err = runInNS(nsPath, configs.NEWNET, func() error {
+				if _, err := netlink.LinkByName("eth0"); err == nil {
+					t.Error("expected eth0 to be deleted")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// Destroying is idempotent.
+			return strategy.destroy(&n.Network, nsPath)
+		})
+	})
+}
+
+func TestHostDeviceStrategy(t *testing.T) {
+	inPrivateNetNS(t, func() error {
+		// Use a network namespace of another thread as the container's one.
+		return withContainerNetNS(func(tid int, nsPath string) error {
+			n := &network{
+				Network: configs.Network{
+					Type:              "host-device",
+					Name:              "eth0",
+					HostInterfaceName: "dummy0",
+					Address:           "192.0.2.10/24",
+				},
+			}
+			strategy := strategies["host-device"]
+			if err := strategy.create(n, tid); err != nil {
+				return err
+			}
+			if _, err := netlink.LinkByName("dummy0"); err == nil {
+				t.Error("expected dummy0 to be moved out of the host")
+			}
+			err := runInNS(nsPath, configs.NEWNET, func() error {
+				if err := strategy.initialize(n); err != nil {
+					return err
+				}
+				link, err := netlink.LinkByName("eth0")
+				if err != nil {
+					return err
+				}
+				if link.Attrs().Flags&net.FlagUp == 0 {
+					t.Error("expected eth0 to be up")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// A host interface named like the one inside the container must
+			// be left alone.
+			hostEth0 := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "eth0"}}
+			if err := netlink.LinkAdd(hostEth0); err != nil {
+				return err
+			}
+			if err := strategy.destroy(&n.Network, nsPath); err != nil {
+				return err
+			}
+			link, err := netlink.LinkByName("dummy0")
+			if err != nil {
+				t.Errorf("expected dummy0 to be back on the host: %v", err)
+			} else if n.HostInterfaceAltName != "" {
+				if _, err := netlink.LinkByName(n.HostInterfaceAltName); err == nil {
+					t.Errorf("expected the alternative name of %s to be removed", link.Attrs().Name)
+				}
+			}
+			if _, err := netlink.LinkByName("eth0"); err != nil {
+				t.Errorf("expected the host's eth0 to be kept: %v", err)
+			}
+			return nil
+		})
+	})
+}
+
+func TestParseNetDev(t *testing.T) {
+	const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    4152      48    0    0    0     0          0         0     4152      48    0    0    0     0       0          0
+  eth0: 1286522    1024    1    2    0     0          0         3   155402     911    4    5    0     0       0          0
+`
+	stats, err := parseNetDev(strings.NewReader(netDev), "eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &types.NetworkInterface{
+		Name:      "eth0",
+		RxBytes:   1286522,
+		RxPackets: 1024,
+		RxErrors:  1,
+		RxDropped: 2,
+		TxBytes:   155402,
+		TxPackets: 911,
+		TxErrors:  4,
+		TxDropped: 5,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+
+	if _, err := parseNetDev(strings.NewReader(netDev), "eth1"); err == nil {
+		t.Error("expected an error for a missing interface")
+	}
+}
@@ -67,6 +67,7 @@ type setnsProcess struct {
 	rootlessCgroups bool
 	manager         cgroups.Manager
 	intelRdtPath    string
+	intelRdtMonPath string
 	config          *initConfig
 	fds             []string
 	process         *Process
@@ -148,7 +149,7 @@ func (p *setnsProcess) start() (retErr error) {
 				}
 			}
 			if err != nil {
-				return fmt.Errorf("error adding pid %d to cgroups: %w", p.pid(), err)
+				return withClass(ErrCgroup, fmt.Errorf("error adding pid %d to cgroups: %w", p.pid(), err))
 			}
 		}
 	}
@@ -159,6 +160,13 @@ func (p *setnsProcess) start() (retErr error) {
 			if err := intelrdt.WriteIntelRdtTasks(p.intelRdtPath, p.pid()); err != nil {
 				return fmt.Errorf("error adding pid %d to Intel RDT: %w", p.pid(), err)
 			}
+			// The process has to be in the resctrl group before
+			// joining its monitoring group.
+			if p.intelRdtMonPath != "" {
+				if err := intelrdt.WriteIntelRdtTasks(p.intelRdtMonPath, p.pid()); err != nil {
+					return fmt.Errorf("error adding pid %d to Intel RDT monitoring group: %w", p.pid(), err)
+				}
+			}
 		}
 	}
 	// set rlimits, this has to be done here because we lose permissions
@@ -382,7 +390,7 @@ func (p *initProcess) start() (retErr error) {
 	// cgroup. We don't need to worry about not doing this and not being root
 	// because we'd be using the rootless cgroup manager in that case.
 	if err := p.manager.Apply(p.pid()); err != nil {
-		return fmt.Errorf("unable to apply cgroup configuration: %w", err)
+		return withClass(ErrCgroup, fmt.Errorf("unable to apply cgroup configuration: %w", err))
 	}
 	if p.intelRdtManager != nil {
 		if err := p.intelRdtManager.Apply(p.pid()); err != nil {
@@ -449,7 +457,7 @@ func (p *initProcess) start() (retErr error) {
 			if !p.config.Config.Namespaces.Contains(configs.NEWNS) {
 				// Setup cgroup before the hook, so that the prestart and CreateRuntime hook could apply cgroup permissions.
 				if err := p.manager.Set(p.config.Config.Cgroups.Resources); err != nil {
-					return fmt.Errorf("error setting cgroup config for ready process: %w", err)
+					return withClass(ErrCgroup, fmt.Errorf("error setting cgroup config for ready process: %w", err))
 				}
 				if p.intelRdtManager != nil {
 					if err := p.intelRdtManager.Set(p.config.Config); err != nil {
@@ -468,10 +476,10 @@ func (p *initProcess) start() (retErr error) {
 					hooks := p.config.Config.Hooks
 
 					if err := hooks[configs.Prestart].RunHooks(s); err != nil {
-						return err
+						return withClass(ErrHook, err)
 					}
 					if err := hooks[configs.CreateRuntime].RunHooks(s); err != nil {
-						return err
+						return withClass(ErrHook, err)
 					}
 				}
 			}
@@ -505,7 +513,7 @@ func (p *initProcess) start() (retErr error) {
 		case procHooks:
 			// Setup cgroup before prestart hook, so that the prestart hook could apply cgroup permissions.
 			if err := p.manager.Set(p.config.Config.Cgroups.Resources); err != nil {
-				return fmt.Errorf("error setting cgroup config for procHooks process: %w", err)
+				return withClass(ErrCgroup, fmt.Errorf("error setting cgroup config for procHooks process: %w", err))
 			}
 			if p.intelRdtManager != nil {
 				if err := p.intelRdtManager.Set(p.config.Config); err != nil {
@@ -523,10 +531,10 @@ func (p *initProcess) start() (retErr error) {
 				hooks := p.config.Config.Hooks
 
 				if err := hooks[configs.Prestart].RunHooks(s); err != nil {
-					return err
+					return withClass(ErrHook, err)
 				}
 				if err := hooks[configs.CreateRuntime].RunHooks(s); err != nil {
-					return err
+					return withClass(ErrHook, err)
 				}
 			}
 			// Sync with child.
@@ -613,6 +621,9 @@ func (p *initProcess) createNetworkInterfaces() error {
 		if err := strategy.create(n, p.pid()); err != nil {
 			return err
 		}
+		// Keep what the strategy recorded in the container's config, so
+		// that it is saved in the state, for destroy.
+		*config = n.Network
 		p.config.Networks = append(p.config.Networks, n)
 	}
 	return nil
@@ -0,0 +1,73 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+// rootfsLayersFilename is the name of the file in a checkpoint's image
+// directory which records the layers of the container's overlay rootfs.
+const rootfsLayersFilename = "rootfs-layers.json"
+
+// overlayMountData returns the overlay filesystem mount options for layers.
+func overlayMountData(l *configs.RootfsLayers) string {
+	data := "lowerdir=" + strings.Join(l.Lower, ":") + ",upperdir=" + l.Upper + ",workdir=" + l.Work
+	if l.UserXattr {
+		data += ",userxattr"
+	}
+	return data
+}
+
+// mountRootfsLayers mounts the overlay filesystem described by l at target.
+func mountRootfsLayers(l *configs.RootfsLayers, target string) error {
+	return mount("overlay", target, "", "overlay", 0, overlayMountData(l))
+}
+
+// setupRootfsLayers creates the upper and work directories of the container's
+// overlay rootfs, if any, and mounts it at the container's rootfs unless it
+// has to be mounted from inside of the container's user namespace (in which
+// case prepareRoot does it).
+func setupRootfsLayers(config *configs.Config) error {
+	l := config.RootfsLayers
+	if l == nil {
+		return nil
+	}
+	for _, dir := range []string{l.Upper, l.Work} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if l.UserXattr {
+		return nil
+	}
+	return mountRootfsLayers(l, config.Rootfs)
+}
+
+// teardownRootfsLayers unmounts the overlay rootfs mounted by
+// setupRootfsLayers. It does nothing if the rootfs is not (or no longer) an
+// overlay filesystem, so it is safe to call it more than once.
+func teardownRootfsLayers(config *configs.Config) error {
+	if config.RootfsLayers == nil || config.RootfsLayers.UserXattr {
+		return nil
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(config.Rootfs, &st); err != nil {
+		if errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		return &os.PathError{Op: "statfs", Path: config.Rootfs, Err: err}
+	}
+	if st.Type != unix.OVERLAYFS_SUPER_MAGIC {
+		return nil
+	}
+	// The rootfs directory may itself be on an overlay filesystem, in
+	// which case it is not a mount point.
+	if err := unmount(config.Rootfs, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) {
+		return err
+	}
+	return nil
+}
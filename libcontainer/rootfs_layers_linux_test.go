@@ -0,0 +1,84 @@
+package libcontainer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
+)
+
+func TestOverlayMountData(t *testing.T) {
+	l := &configs.RootfsLayers{
+		Lower: []string{"/l/2", "/l/1"},
+		Upper: "/l/upper",
+		Work:  "/l/work",
+	}
+	expected := "lowerdir=/l/2:/l/1,upperdir=/l/upper,workdir=/l/work"
+	if data := overlayMountData(l); data != expected {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+	l.UserXattr = true
+	if data := overlayMountData(l); data != expected+",userxattr" {
+		t.Errorf("expected %q, got %q", expected+",userxattr", data)
+	}
+}
+
+func TestSetupRootfsLayers(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	dir, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	config := &configs.Config{
+		Rootfs: filepath.Join(dir, "rootfs"),
+		RootfsLayers: &configs.RootfsLayers{
+			Lower: []string{filepath.Join(dir, "2"), filepath.Join(dir, "1")},
+			Upper: filepath.Join(dir, "upper"),
+			Work:  filepath.Join(dir, "work"),
+		},
+	}
+	for _, d := range append([]string{config.Rootfs}, config.RootfsLayers.Lower...) {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "1", "file"), []byte("1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "2", "file"), []byte("2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inPrivateNS(t, unix.CLONE_NEWNS, func() error {
+		if err := setupRootfsLayers(config); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(filepath.Join(config.Rootfs, "file"))
+		if err != nil {
+			return err
+		}
+		if string(data) != "2" {
+			t.Errorf("expected the file from the uppermost lower layer, got %q", data)
+		}
+		if err := ioutil.WriteFile(filepath.Join(config.Rootfs, "new"), nil, 0o644); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(config.RootfsLayers.Upper, "new")); err != nil {
+			t.Errorf("expected the change in the upper directory: %v", err)
+		}
+		if err := teardownRootfsLayers(config); err != nil {
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(config.Rootfs, "file")); !os.IsNotExist(err) {
+			t.Errorf("expected the rootfs to be unmounted, got %v", err)
+		}
+		// Tearing down is idempotent.
+		return teardownRootfsLayers(config)
+	})
+}
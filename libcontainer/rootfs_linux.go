@@ -22,6 +22,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/userns"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -67,6 +68,12 @@ func prepareRootfs(pipe io.ReadWriter, iConfig *initConfig) (err error) {
 		cgroupns:        config.Namespaces.Contains(configs.NEWCGROUP),
 	}
 	setupDev := needsSetupDev(config)
+	// Writable paths are set up first, so that the mounts from the config
+	// can be placed on top of them. The validator rejects writable paths
+	// beneath a mount destination, which would be hidden by the mount.
+	if err := mountWritablePaths(config, mountConfig); err != nil {
+		return err
+	}
 	for _, m := range config.Mounts {
 		for _, precmd := range m.PremountCmds {
 			if err := mountCmd(precmd); err != nil {
@@ -76,6 +83,13 @@ func prepareRootfs(pipe io.ReadWriter, iConfig *initConfig) (err error) {
 		if err := mountToRootfs(m, mountConfig); err != nil {
 			return fmt.Errorf("error mounting %q to rootfs at %q: %w", m.Source, m.Destination, err)
 		}
+		// Like MS_RDONLY, the attributes of /dev are set in finalizeRootfs,
+		// after the device nodes are created.
+		if utils.CleanPath(m.Destination) != "/dev" {
+			if err := setRecAttr(m, config.Rootfs); err != nil {
+				return err
+			}
+		}
 
 		for _, postcmd := range m.PostmountCmds {
 			if err := mountCmd(postcmd); err != nil {
@@ -121,7 +135,7 @@ func prepareRootfs(pipe io.ReadWriter, iConfig *initConfig) (err error) {
 	s.Pid = unix.Getpid()
 	s.Status = specs.StateCreating
 	if err := iConfig.Config.Hooks[configs.CreateContainer].RunHooks(s); err != nil {
-		return err
+		return withClass(ErrHook, err)
 	}
 
 	if config.NoPivotRoot {
@@ -163,6 +177,9 @@ func finalizeRootfs(config *configs.Config) (err error) {
 					return err
 				}
 			}
+			if err := setRecAttr(m, "/"); err != nil {
+				return err
+			}
 			break
 		}
 	}
@@ -377,6 +394,96 @@ func doTmpfsCopyUp(m *configs.Mount, rootfs, mountLabel string) (Err error) {
 	})
 }
 
+// mountWritablePaths makes the writable paths of a readonly rootfs writable,
+// either by a tmpfs copy-up or by an overlay filesystem. Paths which are the
+// destination of a mount from the config are skipped.
+func mountWritablePaths(config *configs.Config, c *mountConfig) error {
+	dests := make(map[string]bool)
+	for _, m := range config.Mounts {
+		dests[utils.CleanPath(m.Destination)] = true
+	}
+	for _, p := range config.WritablePaths {
+		if dests[utils.CleanPath(p.Path)] {
+			continue
+		}
+		var err error
+		if p.Overlay {
+			err = mountWritableOverlay(p.Path, c, config.Namespaces.Contains(configs.NEWUSER))
+		} else {
+			err = mountToRootfs(&configs.Mount{
+				Source:      "tmpfs",
+				Destination: p.Path,
+				Device:      "tmpfs",
+				Flags:       unix.MS_NOSUID | unix.MS_NODEV,
+				Extensions:  configs.EXT_COPYUP,
+			}, c)
+		}
+		if err != nil {
+			return fmt.Errorf("error making %q writable: %w", p.Path, err)
+		}
+	}
+	return nil
+}
+
+// mountWritableOverlay mounts an overlay filesystem on the directory at path
+// in the rootfs, using the directory as the lower layer, and a scratch tmpfs
+// for the upper layer. The overlay filesystem keeps the tmpfs alive after it
+// is detached from the host's tmpdir.
+func mountWritableOverlay(path string, c *mountConfig, userxattr bool) (Err error) {
+	dest, err := securejoin.SecureJoin(c.root, path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	tmpdir, err := prepareTmp("/tmp")
+	if err != nil {
+		return fmt.Errorf("failed to setup tmpdir: %w", err)
+	}
+	defer cleanupTmp(tmpdir)
+	scratch, err := ioutil.TempDir(tmpdir, "runcoverlay")
+	if err != nil {
+		return fmt.Errorf("failed to create tmpdir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	if err := mount("tmpfs", scratch, "", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, label.FormatMountLabel("", c.label)); err != nil {
+		return err
+	}
+	defer func() {
+		if err := unmount(scratch, unix.MNT_DETACH); err != nil && Err == nil {
+			Err = err
+		}
+	}()
+	upper, work := filepath.Join(scratch, "upper"), filepath.Join(scratch, "work")
+	for _, dir := range []string{upper, work} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return utils.WithProcfd(c.root, path, func(procfd string) error {
+		// The root of the overlay filesystem has the mode and owner of
+		// the upper directory, so make them match the original ones.
+		var st unix.Stat_t
+		if err := unix.Stat(procfd, &st); err != nil {
+			return &os.PathError{Op: "stat", Path: dest, Err: err}
+		}
+		if err := unix.Chmod(upper, st.Mode&0o7777); err != nil {
+			return &os.PathError{Op: "chmod", Path: upper, Err: err}
+		}
+		if err := os.Lchown(upper, int(st.Uid), int(st.Gid)); err != nil {
+			return err
+		}
+		// The procfd path contains neither commas nor colons, so it can
+		// be used as is in the overlay mount options.
+		data := "lowerdir=" + procfd + ",upperdir=" + upper + ",workdir=" + work
+		if userxattr {
+			data += ",userxattr"
+		}
+		return mount("overlay", dest, procfd, "overlay", 0, label.FormatMountLabel(data, c.label))
+	})
+}
+
 func mountToRootfs(m *configs.Mount, c *mountConfig) error {
 	rootfs := c.root
 	mountLabel := c.label
@@ -444,7 +551,7 @@ func mountToRootfs(m *configs.Mount, c *mountConfig) error {
 		if err := prepareBindMount(m, rootfs); err != nil {
 			return err
 		}
-		if err := mountPropagate(m, rootfs, mountLabel); err != nil {
+		if err := bindMount(m, rootfs, mountLabel); err != nil {
 			return err
 		}
 		// bind mount won't change mount options, we need remount to make mount options effective.
@@ -781,6 +888,15 @@ func prepareRoot(config *configs.Config) error {
 		return err
 	}
 
+	// An overlay rootfs mounted with userxattr has to be mounted from
+	// inside of the container's user namespace. Do it once the mount
+	// propagation of / is set, so that it does not leak to the host.
+	if l := config.RootfsLayers; l != nil && l.UserXattr {
+		if err := mountRootfsLayers(l, config.Rootfs); err != nil {
+			return err
+		}
+	}
+
 	// Make parent mount private to make sure following bind mount does
 	// not propagate in other namespaces. Also it will help with kernel
 	// check pass in pivot_root. (IS_SHARED(new_mnt->mnt_parent))
@@ -1045,6 +1161,91 @@ func remount(m *configs.Mount, rootfs string) error {
 	})
 }
 
+// errNoNewMountAPI is returned by bindMountFd if the kernel does not support
+// (or does not allow us to use) the syscalls it relies on.
+var errNoNewMountAPI = errors.New("new mount API is not available")
+
+// bindMount bind-mounts m.Source onto its destination inside rootfs, and
+// applies its propagation flags. The new mount API is used if available (see
+// bindMountFd), and mountPropagate otherwise.
+func bindMount(m *configs.Mount, rootfs, mountLabel string) error {
+	err := bindMountFd(m, rootfs)
+	if errors.Is(err, errNoNewMountAPI) {
+		return mountPropagate(m, rootfs, mountLabel)
+	}
+	if err != nil {
+		return err
+	}
+	return setPropagation(m, rootfs)
+}
+
+// bindMountFd creates a detached bind mount of m.Source using open_tree(2),
+// and attaches it using move_mount(2) to its destination, opened using
+// openat2(2) with RESOLVE_IN_ROOT. Unlike a path-based mount(2), this
+// guarantees that the destination cannot be swapped with a symlink to outside
+// of rootfs between resolving it and mounting on it.
+func bindMountFd(m *configs.Mount, rootfs string) error {
+	flags := uint(system.OPEN_TREE_CLONE | system.OPEN_TREE_CLOEXEC)
+	if m.Flags&unix.MS_REC != 0 {
+		flags |= system.AT_RECURSIVE
+	}
+	srcFd, err := system.OpenTree(unix.AT_FDCWD, m.Source, flags)
+	if err != nil {
+		// EPERM is what seccomp filters usually return for unknown syscalls.
+		if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) {
+			logrus.Debugf("open_tree: %v", err)
+			return errNoNewMountAPI
+		}
+		return &os.PathError{Op: "open_tree", Path: m.Source, Err: err}
+	}
+	defer unix.Close(srcFd)
+
+	rootFd, err := unix.Open(rootfs, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: rootfs, Err: err}
+	}
+	defer unix.Close(rootFd)
+	dstFd, err := unix.Openat2(rootFd, m.Destination, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err != nil {
+		if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) {
+			logrus.Debugf("openat2: %v", err)
+			return errNoNewMountAPI
+		}
+		return &os.PathError{Op: "openat2", Path: m.Destination, Err: err}
+	}
+	defer unix.Close(dstFd)
+
+	if err := system.MoveMount(srcFd, "", dstFd, "", system.MOVE_MOUNT_F_EMPTY_PATH|system.MOVE_MOUNT_T_EMPTY_PATH); err != nil {
+		return &os.PathError{Op: "move_mount", Path: m.Destination, Err: err}
+	}
+	return nil
+}
+
+// setRecAttr applies the recursive mount attributes of m, if any, to the mount
+// at its destination and all of its submounts.
+func setRecAttr(m *configs.Mount, rootfs string) error {
+	if m.RecAttr == nil {
+		return nil
+	}
+	attr := &system.MountAttr{
+		AttrSet: m.RecAttr.AttrSet,
+		AttrClr: m.RecAttr.AttrClr,
+	}
+	return utils.WithProcfd(rootfs, m.Destination, func(procfd string) error {
+		err := system.MountSetattr(-1, procfd, system.AT_RECURSIVE, attr)
+		if errors.Is(err, unix.ENOSYS) {
+			return fmt.Errorf("unable to set recursive mount attributes of %q: mount_setattr(2) requires Linux 5.12 or later", m.Destination)
+		}
+		if err != nil {
+			return &os.PathError{Op: "mount_setattr", Path: m.Destination, Err: err}
+		}
+		return nil
+	})
+}
+
 // Do the mount operation followed by additional mounts required to take care
 // of propagation flags. This will always be scoped inside the container rootfs.
 func mountPropagate(m *configs.Mount, rootfs string, mountLabel string) error {
@@ -1072,6 +1273,12 @@ func mountPropagate(m *configs.Mount, rootfs string, mountLabel string) error {
 	// We have to apply mount propagation flags in a separate WithProcfd() call
 	// because the previous call invalidates the passed procfd -- the mount
 	// target needs to be re-opened.
+	return setPropagation(m, rootfs)
+}
+
+// setPropagation applies the propagation flags of m to the mount at its
+// destination inside rootfs.
+func setPropagation(m *configs.Mount, rootfs string) error {
 	if err := utils.WithProcfd(rootfs, m.Destination, func(procfd string) error {
 		for _, pflag := range m.PropagationFlags {
 			if err := mount("", m.Destination, procfd, "", uintptr(pflag), ""); err != nil {
@@ -3,9 +3,16 @@
 package libcontainer
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/moby/sys/mountinfo"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"golang.org/x/sys/unix"
 )
 
 func TestCheckMountDestOnProc(t *testing.T) {
@@ -99,3 +106,152 @@ func TestNeedsSetupDevStrangeSourceDest(t *testing.T) {
 		t.Fatal("expected needsSetupDev to be true, got false")
 	}
 }
+
+func TestBindMountFdInRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	dir, err := ioutil.TempDir("", "bindmount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		rootfs  = filepath.Join(dir, "rootfs")
+		source  = filepath.Join(dir, "source")
+		outside = filepath.Join(dir, "outside")
+	)
+	for _, d := range []string{filepath.Join(rootfs, outside), source, outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(source, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A malicious image could point the destination outside of the rootfs.
+	if err := os.Symlink(outside, filepath.Join(rootfs, "volume")); err != nil {
+		t.Fatal(err)
+	}
+
+	inPrivateNS(t, unix.CLONE_NEWNS, func() error {
+		m := &configs.Mount{
+			Source:      source,
+			Destination: "/volume",
+			Device:      "bind",
+			Flags:       unix.MS_BIND | unix.MS_REC,
+		}
+		if err := bindMountFd(m, rootfs); err != nil {
+			if errors.Is(err, errNoNewMountAPI) {
+				return fmt.Errorf("%w: the new mount API is not supported", errSkip)
+			}
+			return err
+		}
+		if _, err := os.Stat(filepath.Join(outside, "file")); err == nil {
+			t.Error("bind mount escaped the rootfs")
+		}
+		if _, err := os.Stat(filepath.Join(rootfs, outside, "file")); err != nil {
+			t.Errorf("expected the bind mount inside the rootfs: %v", err)
+		}
+		return nil
+	})
+}
+
+func TestMountWritablePaths(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Test requires root.")
+	}
+	rootfs, err := ioutil.TempDir("", "writable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+	for _, d := range []string{"tmp", "var/lib", "mnt"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(rootfs, d, "file"), []byte(d), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(filepath.Join(rootfs, "tmp"), 0o777|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	config := &configs.Config{
+		Rootfs: rootfs,
+		WritablePaths: []configs.WritablePath{
+			{Path: "/tmp"},
+			{Path: "/var/lib", Overlay: true},
+			{Path: "/run"},
+			{Path: "/mnt", Overlay: true},
+		},
+		Mounts: []*configs.Mount{
+			{Source: "tmpfs", Destination: "/mnt", Device: "tmpfs"},
+		},
+	}
+
+	inPrivateNS(t, unix.CLONE_NEWNS, func() error {
+		if err := mountWritablePaths(config, &mountConfig{root: rootfs}); err != nil {
+			return err
+		}
+		expected := map[string]int64{
+			"tmp":     unix.TMPFS_MAGIC,
+			"var/lib": unix.OVERLAYFS_SUPER_MAGIC,
+			"run":     unix.TMPFS_MAGIC,
+		}
+		for d, fsType := range expected {
+			var st unix.Statfs_t
+			if err := unix.Statfs(filepath.Join(rootfs, d), &st); err != nil {
+				return err
+			}
+			if st.Type != fsType {
+				t.Errorf("/%s: expected filesystem type %#x, got %#x", d, fsType, st.Type)
+			}
+			if err := ioutil.WriteFile(filepath.Join(rootfs, d, "new"), nil, 0o644); err != nil {
+				t.Errorf("/%s: expected to be writable: %v", d, err)
+			}
+		}
+		for _, d := range []string{"tmp", "var/lib"} {
+			data, err := ioutil.ReadFile(filepath.Join(rootfs, d, "file"))
+			if err != nil {
+				return err
+			}
+			if string(data) != d {
+				t.Errorf("/%s: expected the original contents, got %q", d, data)
+			}
+		}
+		fi, err := os.Stat(filepath.Join(rootfs, "tmp"))
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSticky == 0 {
+			t.Errorf("/tmp: expected the original mode, got %v", fi.Mode())
+		}
+
+		// The mount from the config takes precedence, so no overlay is
+		// mounted beneath it.
+		mnt := filepath.Join(rootfs, "mnt")
+		if mounted, err := mountinfo.Mounted(mnt); err != nil {
+			return err
+		} else if mounted {
+			t.Error("/mnt: expected the writable path to be skipped")
+		}
+		mc := &mountConfig{root: rootfs}
+		for _, m := range config.Mounts {
+			if err := mountToRootfs(m, mc); err != nil {
+				return err
+			}
+		}
+		var st unix.Statfs_t
+		if err := unix.Statfs(mnt, &st); err != nil {
+			return err
+		}
+		if st.Type != unix.TMPFS_MAGIC {
+			t.Errorf("/mnt: expected the tmpfs from the config, got filesystem type %#x", st.Type)
+		}
+		if _, err := os.Stat(filepath.Join(mnt, "file")); !os.IsNotExist(err) {
+			t.Errorf("/mnt: expected the lower layer to be hidden, got %v", err)
+		}
+		return nil
+	})
+}
@@ -74,7 +74,7 @@ func (l *linuxSetnsInit) Init() error {
 	// just before execve so as few syscalls take place after it as possible.
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
 		if err := seccomp.InitSeccomp(l.config.Config.Seccomp); err != nil {
-			return err
+			return withClass(ErrSeccomp, err)
 		}
 	}
 	if err := finalizeNamespace(l.config); err != nil {
@@ -88,7 +88,7 @@ func (l *linuxSetnsInit) Init() error {
 	// enable in their seccomp profiles).
 	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
 		if err := seccomp.InitSeccomp(l.config.Config.Seccomp); err != nil {
-			return fmt.Errorf("unable to init seccomp: %w", err)
+			return withClass(ErrSeccomp, fmt.Errorf("unable to init seccomp: %w", err))
 		}
 	}
 	logrus.Debugf("setns_init: about to exec")
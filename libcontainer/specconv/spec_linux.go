@@ -7,9 +7,12 @@ package specconv
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +22,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/devices"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/system"
 	libcontainerUtils "github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
@@ -200,6 +204,7 @@ type CreateOpts struct {
 	Spec             *specs.Spec
 	RootlessEUID     bool
 	RootlessCgroups  bool
+	DevicesAudit     bool
 }
 
 // CreateLibcontainerConfig creates a new libcontainer configuration from a
@@ -301,11 +306,21 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		}
 		if spec.Linux.IntelRdt != nil {
 			config.IntelRdt = &configs.IntelRdt{
+				ClosID:        spec.Linux.IntelRdt.ClosID,
 				L3CacheSchema: spec.Linux.IntelRdt.L3CacheSchema,
 				MemBwSchema:   spec.Linux.IntelRdt.MemBwSchema,
 			}
 		}
 	}
+	if d := config.Cgroups.Delegate; d != nil {
+		// The delegated cgroup is owned by the container root.
+		if d.UID, err = config.HostRootUID(); err != nil {
+			return nil, err
+		}
+		if d.GID, err = config.HostRootGID(); err != nil {
+			return nil, err
+		}
+	}
 	if spec.Process != nil {
 		config.OomScoreAdj = spec.Process.OOMScoreAdj
 		config.NoNewPrivileges = spec.Process.NoNewPrivileges
@@ -333,10 +348,10 @@ func createLibcontainerMount(cwd string, m specs.Mount) (*configs.Mount, error)
 		// return nil, fmt.Errorf("mount destination %s is not absolute", m.Destination)
 		logrus.Warnf("mount destination %s is not absolute. Support for non-absolute mount destinations will be removed in a future release.", m.Destination)
 	}
-	flags, pgflags, data, ext := parseMountOptions(m.Options)
+	mnt := parseMountOptions(m.Options)
 	source := m.Source
 	device := m.Type
-	if flags&unix.MS_BIND != 0 {
+	if mnt.Flags&unix.MS_BIND != 0 {
 		// Any "type" the user specified is meaningless (and ignored) for
 		// bind-mounts -- so we set it to "bind" because rootfs_linux.go
 		// (incorrectly) relies on this for some checks.
@@ -345,15 +360,10 @@ func createLibcontainerMount(cwd string, m specs.Mount) (*configs.Mount, error)
 			source = filepath.Join(cwd, m.Source)
 		}
 	}
-	return &configs.Mount{
-		Device:           device,
-		Source:           source,
-		Destination:      m.Destination,
-		Data:             data,
-		Flags:            flags,
-		PropagationFlags: pgflags,
-		Extensions:       ext,
-	}, nil
+	mnt.Device = device
+	mnt.Source = source
+	mnt.Destination = m.Destination
+	return mnt, nil
 }
 
 // systemd property name check: latin letters only, at least 3 of them
@@ -391,6 +401,147 @@ func convertSecToUSec(value dbus.Variant) (dbus.Variant, error) {
 	return dbus.MakeVariant(sec), nil
 }
 
+// systemdPropType describes a systemd unit property which, in addition to
+// the GVariant text format, can be set using the unit file syntax (see
+// systemd.resource-control(5)).
+type systemdPropType struct {
+	sig   string                            // D-Bus signature.
+	parse func(string) (interface{}, error) // Unit file syntax parser.
+}
+
+var systemdPropTypes = map[string]systemdPropType{
+	"AllowedCPUs":         {"ay", parseBitmask},
+	"AllowedMemoryNodes":  {"ay", parseBitmask},
+	"CPUWeight":           {"t", parseUint},
+	"StartupCPUWeight":    {"t", parseUint},
+	"Delegate":            {"b", parseBool},
+	"DelegateControllers": {"as", parseList},
+	"IOWeight":            {"t", parseUint},
+	"StartupIOWeight":     {"t", parseUint},
+	"IODeviceWeight":      {"a(st)", parseDeviceValues(parseUint)},
+	"IOReadBandwidthMax":  {"a(st)", parseDeviceValues(parseBandwidth)},
+	"IOWriteBandwidthMax": {"a(st)", parseDeviceValues(parseBandwidth)},
+	"IOReadIOPSMax":       {"a(st)", parseDeviceValues(parseLimit)},
+	"IOWriteIOPSMax":      {"a(st)", parseDeviceValues(parseLimit)},
+	"MemoryMin":           {"t", parseMemory},
+	"MemoryLow":           {"t", parseMemory},
+	"MemoryHigh":          {"t", parseMemory},
+	"MemoryMax":           {"t", parseMemory},
+	"MemorySwapMax":       {"t", parseMemory},
+	"TasksMax":            {"t", parseLimit},
+}
+
+// parseSystemdPropValue parses the value v of the systemd property name.
+// For the properties listed in systemdPropTypes, values are parsed as
+// GVariants of the property type, then using the unit file syntax.
+// Values of any other properties are parsed as GVariants.
+func parseSystemdPropValue(name, v string) (dbus.Variant, error) {
+	t, ok := systemdPropTypes[name]
+	if !ok {
+		return dbus.ParseVariant(v, dbus.Signature{})
+	}
+	if value, err := dbus.ParseVariant(v, dbus.ParseSignatureMust(t.sig)); err == nil {
+		return value, nil
+	}
+	value, err := t.parse(v)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	return dbus.MakeVariant(value), nil
+}
+
+func parseBitmask(v string) (interface{}, error) {
+	return cgroups.RangeToBits(v)
+}
+
+func parseUint(v string) (interface{}, error) {
+	return strconv.ParseUint(v, 10, 64)
+}
+
+func parseBool(v string) (interface{}, error) {
+	switch v {
+	case "1", "yes", "y", "true", "t", "on":
+		return true, nil
+	case "0", "no", "n", "false", "f", "off":
+		return false, nil
+	}
+	return nil, fmt.Errorf("invalid boolean value %q", v)
+}
+
+func parseList(v string) (interface{}, error) {
+	return strings.Fields(v), nil
+}
+
+// parseLimit parses a number or "infinity".
+func parseLimit(v string) (interface{}, error) {
+	if v == "infinity" {
+		return uint64(math.MaxUint64), nil
+	}
+	return parseUint(v)
+}
+
+// parseBytes parses "infinity", or a number of bytes with an optional K, M,
+// G or T suffix, to the given base.
+func parseBytes(v string, base uint64) (interface{}, error) {
+	if v == "infinity" {
+		return uint64(math.MaxUint64), nil
+	}
+	mult := uint64(1)
+	if i := strings.IndexAny(v, "KMGT"); i != -1 && i == len(v)-1 {
+		for _, suffix := range "KMGT" {
+			mult *= base
+			if rune(v[i]) == suffix {
+				break
+			}
+		}
+		v = v[:i]
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxUint64/mult {
+		return nil, fmt.Errorf("value %s is too large", v)
+	}
+	return n * mult, nil
+}
+
+// parseMemory parses memory sizes, where suffixes are to the base of 1024.
+func parseMemory(v string) (interface{}, error) {
+	return parseBytes(v, 1024)
+}
+
+// parseBandwidth parses io bandwidths, where suffixes are to the base of 1000.
+func parseBandwidth(v string) (interface{}, error) {
+	return parseBytes(v, 1000)
+}
+
+// ioDeviceValue is a per-device value of io properties, like IOReadBandwidthMax.
+type ioDeviceValue struct {
+	Path  string
+	Value uint64
+}
+
+// parseDeviceValues returns a parser of comma-separated lists of
+// "<device path> <value>" pairs, with values parsed by parse.
+func parseDeviceValues(parse func(string) (interface{}, error)) func(string) (interface{}, error) {
+	return func(v string) (interface{}, error) {
+		var values []ioDeviceValue
+		for _, dv := range strings.Split(v, ",") {
+			fields := strings.Fields(dv)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid device value %q (expected <path> <value>)", dv)
+			}
+			value, err := parse(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, ioDeviceValue{Path: fields[0], Value: value.(uint64)})
+		}
+		return values, nil
+	}
+}
+
 func initSystemdProps(spec *specs.Spec) ([]systemdDbus.Property, error) {
 	const keyPrefix = "org.systemd.property."
 	var sp []systemdDbus.Property
@@ -403,7 +554,7 @@ func initSystemdProps(spec *specs.Spec) ([]systemdDbus.Property, error) {
 		if !isValidName(name) {
 			return nil, fmt.Errorf("Annotation %s name incorrect: %s", k, name)
 		}
-		value, err := dbus.ParseVariant(v, dbus.Signature{})
+		value, err := parseSystemdPropValue(name, v)
 		if err != nil {
 			return nil, fmt.Errorf("Annotation %s=%s value parse error: %w", k, v, err)
 		}
@@ -420,6 +571,81 @@ func initSystemdProps(spec *specs.Spec) ([]systemdDbus.Property, error) {
 	return sp, nil
 }
 
+// delegateAnnotation enables cgroup delegation for the container (see
+// configs.CgroupDelegate). Its value is a comma-separated list of
+// controllers to delegate; if empty, all available controllers are.
+const delegateAnnotation = "org.opencontainers.runc.cgroup.delegate"
+
+func initDelegate(spec *specs.Spec) *configs.CgroupDelegate {
+	v, ok := spec.Annotations[delegateAnnotation]
+	if !ok {
+		return nil
+	}
+	d := &configs.CgroupDelegate{}
+	if v != "" {
+		d.Controllers = strings.Split(v, ",")
+	}
+	return d
+}
+
+// hugetlbRsvdAnnotation, if set to true, makes the hugepage limits also
+// limit hugepage reservations (see configs.HugepageLimit.RsvdLimit).
+const hugetlbRsvdAnnotation = "org.opencontainers.runc.hugetlb.rsvd"
+
+func initHugetlbRsvd(spec *specs.Spec) (bool, error) {
+	v, ok := spec.Annotations[hugetlbRsvdAnnotation]
+	if !ok {
+		return false, nil
+	}
+	rsvd, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("Annotation %s=%s value parse error: %w", hugetlbRsvdAnnotation, v, err)
+	}
+	return rsvd, nil
+}
+
+// systemdUnitAnnotationPrefix is the prefix of annotations setting the
+// systemd unit description and dependencies (see configs.SystemdUnit).
+// Dependencies are lists of space-separated unit names, e.g.
+// "org.opencontainers.runc.systemd.after": "network-online.target".
+const systemdUnitAnnotationPrefix = "org.opencontainers.runc.systemd."
+
+func initSystemdUnit(spec *specs.Spec) *configs.SystemdUnit {
+	u := &configs.SystemdUnit{}
+	found := false
+	for k, v := range spec.Annotations {
+		name := strings.TrimPrefix(k, systemdUnitAnnotationPrefix)
+		if len(name) == len(k) { // prefix not there
+			continue
+		}
+		found = true
+		switch name {
+		case "description":
+			u.Description = v
+		case "after":
+			u.After = strings.Fields(v)
+		case "before":
+			u.Before = strings.Fields(v)
+		case "wants":
+			u.Wants = strings.Fields(v)
+		case "requires":
+			u.Requires = strings.Fields(v)
+		case "binds-to":
+			u.BindsTo = strings.Fields(v)
+		case "part-of":
+			u.PartOf = strings.Fields(v)
+		case "collect-mode":
+			u.CollectMode = v
+		default:
+			logrus.Warnf("ignoring unknown annotation %s", k)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return u
+}
+
 func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*configs.Cgroup, error) {
 	var (
 		myCgroupPath string
@@ -430,7 +656,9 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	)
 
 	c := &configs.Cgroup{
-		Resources: &configs.Resources{},
+		Resources: &configs.Resources{
+			DevicesAudit: opts.DevicesAudit,
+		},
 	}
 
 	if useSystemdCgroup {
@@ -439,8 +667,11 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 			return nil, err
 		}
 		c.SystemdProps = sp
+		c.Unit = initSystemdUnit(spec)
 	}
 
+	c.Delegate = initDelegate(spec)
+
 	if spec.Linux != nil && spec.Linux.CgroupsPath != "" {
 		if useSystemdCgroup {
 			myCgroupPath = spec.Linux.CgroupsPath
@@ -477,36 +708,11 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	if spec.Linux != nil {
 		r := spec.Linux.Resources
 		if r != nil {
-			for i, d := range spec.Linux.Resources.Devices {
-				var (
-					t     = "a"
-					major = int64(-1)
-					minor = int64(-1)
-				)
-				if d.Type != "" {
-					t = d.Type
-				}
-				if d.Major != nil {
-					major = *d.Major
-				}
-				if d.Minor != nil {
-					minor = *d.Minor
-				}
-				if d.Access == "" {
-					return nil, fmt.Errorf("device access at %d field cannot be empty", i)
-				}
-				dt, err := stringToCgroupDeviceRune(t)
-				if err != nil {
-					return nil, err
-				}
-				c.Resources.Devices = append(c.Resources.Devices, &devices.Rule{
-					Type:        dt,
-					Major:       major,
-					Minor:       minor,
-					Permissions: devices.Permissions(d.Access),
-					Allow:       d.Allow,
-				})
+			rules, err := CreateDeviceRules(r.Devices)
+			if err != nil {
+				return nil, err
 			}
+			c.Resources.Devices = append(c.Resources.Devices, rules...)
 			if r.Memory != nil {
 				if r.Memory.Limit != nil {
 					c.Resources.Memory = *r.Memory.Limit
@@ -601,11 +807,20 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 					}
 				}
 			}
+			rsvd, err := initHugetlbRsvd(spec)
+			if err != nil {
+				return nil, err
+			}
 			for _, l := range r.HugepageLimits {
-				c.Resources.HugetlbLimit = append(c.Resources.HugetlbLimit, &configs.HugepageLimit{
+				h := &configs.HugepageLimit{
 					Pagesize: l.Pagesize,
 					Limit:    l.Limit,
-				})
+				}
+				if rsvd {
+					limit := l.Limit
+					h.RsvdLimit = &limit
+				}
+				c.Resources.HugetlbLimit = append(c.Resources.HugetlbLimit, h)
 			}
 			if r.Network != nil {
 				if r.Network.ClassID != nil {
@@ -624,6 +839,12 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 				for k, v := range r.Unified {
 					c.Resources.Unified[k] = v
 				}
+				if err := SetMemoryFromUnified(c.Resources); err != nil {
+					return nil, err
+				}
+				if err := SetCPUFromUnified(c.Resources); err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
@@ -635,6 +856,134 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*confi
 	return c, nil
 }
 
+// SetMemoryFromUnified moves the memory.high, memory.low, memory.min,
+// memory.swap.max and memory.oom.group entries of r.Unified (if any) to the
+// corresponding fields of r, so that these can be emulated on cgroup v1, and
+// converted to systemd unit properties.
+func SetMemoryFromUnified(r *configs.Resources) error {
+	parse := func(key string) (int64, error) {
+		v := r.Unified[key]
+		if v == "max" {
+			return -1, nil
+		}
+		num, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || num < 0 {
+			return 0, fmt.Errorf("invalid unified resource %q value %q", key, v)
+		}
+		return num, nil
+	}
+	for key, dest := range map[string]**int64{
+		"memory.high": &r.MemoryHigh,
+		"memory.low":  &r.MemoryLow,
+		"memory.min":  &r.MemoryMin,
+	} {
+		if _, ok := r.Unified[key]; !ok {
+			continue
+		}
+		num, err := parse(key)
+		if err != nil {
+			return err
+		}
+		*dest = &num
+		delete(r.Unified, key)
+	}
+	if _, ok := r.Unified["memory.swap.max"]; ok {
+		num, err := parse("memory.swap.max")
+		if err != nil {
+			return err
+		}
+		r.MemorySwapMax = &num
+		delete(r.Unified, "memory.swap.max")
+	}
+	if v, ok := r.Unified["memory.oom.group"]; ok {
+		var oomGroup bool
+		switch v {
+		case "0":
+		case "1":
+			oomGroup = true
+		default:
+			return fmt.Errorf("invalid unified resource %q value %q", "memory.oom.group", v)
+		}
+		r.MemoryOomGroup = &oomGroup
+		delete(r.Unified, "memory.oom.group")
+	}
+	if len(r.Unified) == 0 {
+		r.Unified = nil
+	}
+	return nil
+}
+
+// SetCPUFromUnified moves the cpu.max.burst, cpu.idle, cpu.uclamp.min and
+// cpu.uclamp.max entries of r.Unified (if any) to the corresponding fields of
+// r, so that these can be set on cgroup v1 as well.
+func SetCPUFromUnified(r *configs.Resources) error {
+	if v, ok := r.Unified["cpu.max.burst"]; ok {
+		burst, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unified resource %q value %q", "cpu.max.burst", v)
+		}
+		r.CpuBurst = &burst
+		delete(r.Unified, "cpu.max.burst")
+	}
+	if v, ok := r.Unified["cpu.idle"]; ok {
+		idle, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid unified resource %q value %q", "cpu.idle", v)
+		}
+		r.CpuIdle = &idle
+		delete(r.Unified, "cpu.idle")
+	}
+	if v, ok := r.Unified["cpu.uclamp.min"]; ok {
+		r.CpuUclampMin = v
+		delete(r.Unified, "cpu.uclamp.min")
+	}
+	if v, ok := r.Unified["cpu.uclamp.max"]; ok {
+		r.CpuUclampMax = v
+		delete(r.Unified, "cpu.uclamp.max")
+	}
+	if len(r.Unified) == 0 {
+		r.Unified = nil
+	}
+	return nil
+}
+
+// CreateDeviceRules converts the given spec device cgroup entries into
+// device rules.
+func CreateDeviceRules(devs []specs.LinuxDeviceCgroup) ([]*devices.Rule, error) {
+	var rules []*devices.Rule
+	for i, d := range devs {
+		var (
+			t     = "a"
+			major = int64(-1)
+			minor = int64(-1)
+		)
+		if d.Type != "" {
+			t = d.Type
+		}
+		if d.Major != nil {
+			major = *d.Major
+		}
+		if d.Minor != nil {
+			minor = *d.Minor
+		}
+		if d.Access == "" {
+			return nil, fmt.Errorf("device access at %d field cannot be empty", i)
+		}
+		dt, err := stringToCgroupDeviceRune(t)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &devices.Rule{
+			Type:        dt,
+			Major:       major,
+			Minor:       minor,
+			Permissions: devices.Permissions(d.Access),
+			Allow:       d.Allow,
+		})
+	}
+	return rules, nil
+}
+
 func stringToCgroupDeviceRune(s string) (devices.Type, error) {
 	switch s {
 	case "a":
@@ -749,16 +1098,8 @@ func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
 	return nil
 }
 
-// parseMountOptions parses the string and returns the flags, propagation
-// flags and any mount data that it contains.
-func parseMountOptions(options []string) (int, []int, string, int) {
-	var (
-		flag     int
-		pgflag   []int
-		data     []string
-		extFlags int
-	)
-	flags := map[string]struct {
+var (
+	mountFlags = map[string]struct {
 		clear bool
 		flag  int
 	}{
@@ -796,7 +1137,7 @@ func parseMountOptions(options []string) (int, []int, string, int) {
 		"suid":          {true, unix.MS_NOSUID},
 		"sync":          {false, unix.MS_SYNCHRONOUS},
 	}
-	propagationFlags := map[string]int{
+	mountPropagationFlags = map[string]int{
 		"private":     unix.MS_PRIVATE,
 		"shared":      unix.MS_SHARED,
 		"slave":       unix.MS_SLAVE,
@@ -806,35 +1147,102 @@ func parseMountOptions(options []string) (int, []int, string, int) {
 		"rslave":      unix.MS_SLAVE | unix.MS_REC,
 		"runbindable": unix.MS_UNBINDABLE | unix.MS_REC,
 	}
-	extensionFlags := map[string]struct {
+	// recAttrFlags are the recursive variants of mountFlags, applied to the
+	// mount and all its submounts using mount_setattr(2).
+	recAttrFlags = map[string]struct {
+		clear bool
+		flag  uint64
+	}{
+		"rro":          {false, system.MOUNT_ATTR_RDONLY},
+		"rrw":          {true, system.MOUNT_ATTR_RDONLY},
+		"rnosuid":      {false, system.MOUNT_ATTR_NOSUID},
+		"rsuid":        {true, system.MOUNT_ATTR_NOSUID},
+		"rnodev":       {false, system.MOUNT_ATTR_NODEV},
+		"rdev":         {true, system.MOUNT_ATTR_NODEV},
+		"rnoexec":      {false, system.MOUNT_ATTR_NOEXEC},
+		"rexec":        {true, system.MOUNT_ATTR_NOEXEC},
+		"rnodiratime":  {false, system.MOUNT_ATTR_NODIRATIME},
+		"rdiratime":    {true, system.MOUNT_ATTR_NODIRATIME},
+		"rrelatime":    {false, system.MOUNT_ATTR_RELATIME},
+		"rnoatime":     {false, system.MOUNT_ATTR_NOATIME},
+		"rstrictatime": {false, system.MOUNT_ATTR_STRICTATIME},
+		"rnosymfollow": {false, system.MOUNT_ATTR_NOSYMFOLLOW},
+		"rsymfollow":   {true, system.MOUNT_ATTR_NOSYMFOLLOW},
+	}
+	extensionFlags = map[string]struct {
 		clear bool
 		flag  int
 	}{
 		"tmpcopyup": {false, configs.EXT_COPYUP},
 	}
+)
+
+// KnownMountOptions returns the list of the known mount options, i.e. the
+// ones which are not passed as data to the filesystem.
+func KnownMountOptions() []string {
+	var res []string
+	for k := range mountFlags {
+		res = append(res, k)
+	}
+	for k := range mountPropagationFlags {
+		res = append(res, k)
+	}
+	for k := range recAttrFlags {
+		res = append(res, k)
+	}
+	for k := range extensionFlags {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// parseMountOptions parses the string and returns a mount with the flags,
+// propagation flags, recursive mount attributes and any mount data that it
+// contains set.
+func parseMountOptions(options []string) *configs.Mount {
+	var (
+		m    configs.Mount
+		data []string
+	)
 	for _, o := range options {
 		// If the option does not exist in the flags table or the flag
 		// is not supported on the platform,
 		// then it is a data value for a specific fs type
-		if f, exists := flags[o]; exists && f.flag != 0 {
+		if f, exists := mountFlags[o]; exists && f.flag != 0 {
 			if f.clear {
-				flag &= ^f.flag
+				m.Flags &= ^f.flag
 			} else {
-				flag |= f.flag
+				m.Flags |= f.flag
+			}
+		} else if f, exists := mountPropagationFlags[o]; exists && f != 0 {
+			m.PropagationFlags = append(m.PropagationFlags, f)
+		} else if f, exists := recAttrFlags[o]; exists {
+			if m.RecAttr == nil {
+				m.RecAttr = &configs.MountAttr{}
+			}
+			if f.clear {
+				m.RecAttr.AttrClr |= f.flag
+			} else {
+				m.RecAttr.AttrSet |= f.flag
+				if f.flag&system.MOUNT_ATTR__ATIME == f.flag {
+					// The access time setting can only be changed
+					// by clearing MOUNT_ATTR__ATIME as well.
+					m.RecAttr.AttrClr |= system.MOUNT_ATTR__ATIME
+				}
 			}
-		} else if f, exists := propagationFlags[o]; exists && f != 0 {
-			pgflag = append(pgflag, f)
 		} else if f, exists := extensionFlags[o]; exists && f.flag != 0 {
 			if f.clear {
-				extFlags &= ^f.flag
+				m.Extensions &= ^f.flag
 			} else {
-				extFlags |= f.flag
+				m.Extensions |= f.flag
 			}
 		} else {
 			data = append(data, o)
 		}
 	}
-	return flag, pgflag, strings.Join(data, ","), extFlags
+	m.Data = strings.Join(data, ",")
+	return &m
 }
 
 func SetupSeccomp(config *specs.LinuxSeccomp) (*configs.Seccomp, error) {
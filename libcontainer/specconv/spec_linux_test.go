@@ -3,7 +3,9 @@
 package specconv
 
 import (
+	"math"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/devices"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -571,6 +574,61 @@ func TestInitSystemdProps(t *testing.T) {
 			in:  inT{"org.systemd.property.CollectMode", "'inactive-or-failed'"},
 			exp: expT{false, "CollectMode", "inactive-or-failed"},
 		},
+		{
+			desc: "typed property (GVariant)",
+			in:   inT{"org.systemd.property.TasksMax", "100"},
+			exp:  expT{false, "TasksMax", uint64(100)},
+		},
+		{
+			desc: "typed property (infinity)",
+			in:   inT{"org.systemd.property.TasksMax", "infinity"},
+			exp:  expT{false, "TasksMax", uint64(math.MaxUint64)},
+		},
+		{
+			desc: "typed property (size)",
+			in:   inT{"org.systemd.property.MemoryHigh", "512M"},
+			exp:  expT{false, "MemoryHigh", uint64(512 << 20)},
+		},
+		{
+			desc: "typed property (invalid size)",
+			in:   inT{"org.systemd.property.MemoryHigh", "512X"},
+			exp:  expT{true, "", ""},
+		},
+		{
+			desc: "typed property (bool)",
+			in:   inT{"org.systemd.property.Delegate", "yes"},
+			exp:  expT{false, "Delegate", true},
+		},
+		{
+			desc: "typed property (list)",
+			in:   inT{"org.systemd.property.DelegateControllers", "cpu memory"},
+			exp:  expT{false, "DelegateControllers", []string{"cpu", "memory"}},
+		},
+		{
+			desc: "typed property (bitmask)",
+			in:   inT{"org.systemd.property.AllowedCPUs", "0-3,5"},
+			exp:  expT{false, "AllowedCPUs", []byte{0x2f}},
+		},
+		{
+			desc: "typed property (device values)",
+			in:   inT{"org.systemd.property.IOReadBandwidthMax", "/dev/sda 1M, /dev/sdb 2000"},
+			exp:  expT{false, "IOReadBandwidthMax", []ioDeviceValue{{"/dev/sda", 1000000}, {"/dev/sdb", 2000}}},
+		},
+		{
+			desc: "typed property (list, GVariant)",
+			in:   inT{"org.systemd.property.DelegateControllers", `["cpu", "io"]`},
+			exp:  expT{false, "DelegateControllers", []string{"cpu", "io"}},
+		},
+		{
+			desc: "typed property (device values, infinity)",
+			in:   inT{"org.systemd.property.IOWriteIOPSMax", "/dev/sda infinity"},
+			exp:  expT{false, "IOWriteIOPSMax", []ioDeviceValue{{"/dev/sda", math.MaxUint64}}},
+		},
+		{
+			desc: "typed property (invalid device values)",
+			in:   inT{"org.systemd.property.IOWriteIOPSMax", "/dev/sda"},
+			exp:  expT{true, "", ""},
+		},
 		{
 			desc: "unrelated property",
 			in:   inT{"some.other.annotation", "0"},
@@ -742,3 +800,231 @@ func TestCreateDevices(t *testing.T) {
 		t.Errorf("device /dev/ram0 not found in config devices; got %v", conf.Devices)
 	}
 }
+
+func TestCreateLibcontainerMountRecAttr(t *testing.T) {
+	m, err := createLibcontainerMount("/bundle", specs.Mount{
+		Destination: "/data",
+		Type:        "bind",
+		Source:      "/srv/data",
+		Options:     []string{"rbind", "ro", "rro", "rnosuid", "rnoatime", "rexec", "mode=0755"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Device != "bind" || m.Flags != unix.MS_BIND|unix.MS_REC|unix.MS_RDONLY || m.Data != "mode=0755" {
+		t.Errorf("unexpected mount %+v", m)
+	}
+	expected := &configs.MountAttr{
+		AttrSet: system.MOUNT_ATTR_RDONLY | system.MOUNT_ATTR_NOSUID | system.MOUNT_ATTR_NOATIME,
+		AttrClr: system.MOUNT_ATTR_NOEXEC | system.MOUNT_ATTR__ATIME,
+	}
+	if m.RecAttr == nil || *m.RecAttr != *expected {
+		t.Errorf("expected recursive attributes %+v, got %+v", expected, m.RecAttr)
+	}
+
+	m, err = createLibcontainerMount("/bundle", specs.Mount{
+		Destination: "/data",
+		Type:        "bind",
+		Source:      "/srv/data",
+		Options:     []string{"rbind", "ro"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RecAttr != nil {
+		t.Errorf("expected no recursive attributes, got %+v", m.RecAttr)
+	}
+}
+
+func TestSetMemoryFromUnified(t *testing.T) {
+	r := &configs.Resources{
+		Unified: map[string]string{
+			"memory.high":      "max",
+			"memory.low":       "1048576",
+			"memory.min":       "524288",
+			"memory.swap.max":  "0",
+			"memory.oom.group": "1",
+			"pids.max":         "100",
+		},
+	}
+	if err := SetMemoryFromUnified(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.MemoryHigh == nil || *r.MemoryHigh != -1 ||
+		r.MemoryLow == nil || *r.MemoryLow != 1048576 ||
+		r.MemoryMin == nil || *r.MemoryMin != 524288 {
+		t.Errorf("unexpected memory high/low/min: %v/%v/%v", r.MemoryHigh, r.MemoryLow, r.MemoryMin)
+	}
+	if r.MemorySwapMax == nil || *r.MemorySwapMax != 0 {
+		t.Errorf("expected swap max to be 0, got %v", r.MemorySwapMax)
+	}
+	if r.MemoryOomGroup == nil || !*r.MemoryOomGroup {
+		t.Errorf("expected oom group to be set, got %v", r.MemoryOomGroup)
+	}
+	if len(r.Unified) != 1 || r.Unified["pids.max"] != "100" {
+		t.Errorf("expected only pids.max to be left, got %v", r.Unified)
+	}
+
+	// Explicit zeros are kept, so that a value can be reset.
+	r = &configs.Resources{Unified: map[string]string{"memory.low": "0", "memory.min": "0", "memory.high": "0"}}
+	if err := SetMemoryFromUnified(r); err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]*int64{"high": r.MemoryHigh, "low": r.MemoryLow, "min": r.MemoryMin} {
+		if v == nil || *v != 0 {
+			t.Errorf("expected memory %s to be 0, got %v", name, v)
+		}
+	}
+
+	for _, unified := range []map[string]string{
+		{"memory.high": "-1"},
+		{"memory.low": "1M"},
+		{"memory.oom.group": "yes"},
+	} {
+		if err := SetMemoryFromUnified(&configs.Resources{Unified: unified}); err == nil {
+			t.Errorf("%v: expected error, got nil", unified)
+		}
+	}
+}
+
+func TestSetCPUFromUnified(t *testing.T) {
+	r := &configs.Resources{
+		Unified: map[string]string{
+			"cpu.max.burst":  "20000",
+			"cpu.idle":       "1",
+			"cpu.uclamp.min": "10.50",
+			"cpu.uclamp.max": "max",
+		},
+	}
+	if err := SetCPUFromUnified(r); err != nil {
+		t.Fatal(err)
+	}
+	if r.CpuBurst == nil || *r.CpuBurst != 20000 {
+		t.Errorf("expected burst to be 20000, got %v", r.CpuBurst)
+	}
+	if r.CpuIdle == nil || *r.CpuIdle != 1 {
+		t.Errorf("expected idle to be 1, got %v", r.CpuIdle)
+	}
+	if r.CpuUclampMin != "10.50" || r.CpuUclampMax != "max" {
+		t.Errorf("unexpected uclamp min/max: %q/%q", r.CpuUclampMin, r.CpuUclampMax)
+	}
+	if r.Unified != nil {
+		t.Errorf("expected no unified resources to be left, got %v", r.Unified)
+	}
+
+	if err := SetCPUFromUnified(&configs.Resources{Unified: map[string]string{"cpu.max.burst": "max"}}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestCgroupDelegate(t *testing.T) {
+	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
+		t.Skip("Test requires userns.")
+	}
+
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{delegateAnnotation: "cpu,memory"}
+	spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.UserNamespace})
+	spec.Linux.UIDMappings = []specs.LinuxIDMapping{{HostID: 100000, ContainerID: 0, Size: 65536}}
+	spec.Linux.GIDMappings = []specs.LinuxIDMapping{{HostID: 200000, ContainerID: 0, Size: 65536}}
+
+	opts := &CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	}
+
+	config, err := CreateLibcontainerConfig(opts)
+	if err != nil {
+		t.Fatalf("Couldn't create libcontainer config: %v", err)
+	}
+	d := config.Cgroups.Delegate
+	if d == nil {
+		t.Fatal("expected cgroup delegation to be enabled")
+	}
+	if strings.Join(d.Controllers, " ") != "cpu memory" {
+		t.Errorf("expected cpu and memory controllers to be delegated, got %v", d.Controllers)
+	}
+	if d.UID != 100000 || d.GID != 200000 {
+		t.Errorf("expected delegated cgroup owner to be 100000:200000, got %d:%d", d.UID, d.GID)
+	}
+
+	spec.Annotations[delegateAnnotation] = ""
+	c, err := CreateCgroupConfig(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Delegate == nil || c.Delegate.Controllers != nil {
+		t.Errorf("expected all controllers to be delegated, got %+v", c.Delegate)
+	}
+}
+
+func TestCgroupSystemdUnit(t *testing.T) {
+	spec := Example()
+	spec.Annotations = map[string]string{
+		systemdUnitAnnotationPrefix + "description":  "my container",
+		systemdUnitAnnotationPrefix + "after":        "network-online.target local-fs.target",
+		systemdUnitAnnotationPrefix + "binds-to":     "foo.service",
+		systemdUnitAnnotationPrefix + "collect-mode": "inactive-or-failed",
+	}
+	opts := &CreateOpts{
+		CgroupName:       "ContainerID",
+		UseSystemdCgroup: true,
+		Spec:             spec,
+	}
+
+	c, err := CreateCgroupConfig(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.SystemdUnit{
+		Description: "my container",
+		After:       []string{"network-online.target", "local-fs.target"},
+		BindsTo:     []string{"foo.service"},
+		CollectMode: "inactive-or-failed",
+	}
+	if !reflect.DeepEqual(c.Unit, expected) {
+		t.Errorf("expected unit %+v, got %+v", expected, c.Unit)
+	}
+
+	// The annotations are ignored unless systemd manages cgroups.
+	opts.UseSystemdCgroup = false
+	c, err = CreateCgroupConfig(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Unit != nil {
+		t.Errorf("expected no unit settings, got %+v", c.Unit)
+	}
+}
+
+func TestHugetlbRsvd(t *testing.T) {
+	spec := Example()
+	spec.Linux.Resources.HugepageLimits = []specs.LinuxHugepageLimit{{Pagesize: "2MB", Limit: 1 << 30}}
+	opts := &CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+	}
+
+	c, err := CreateCgroupConfig(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Resources.HugetlbLimit[0]; h.RsvdLimit != nil {
+		t.Errorf("expected no reservation limit, got %d", *h.RsvdLimit)
+	}
+
+	spec.Annotations = map[string]string{hugetlbRsvdAnnotation: "true"}
+	c, err = CreateCgroupConfig(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Resources.HugetlbLimit[0]; h.RsvdLimit == nil || *h.RsvdLimit != 1<<30 {
+		t.Errorf("expected reservation limit to be %d, got %v", 1<<30, h.RsvdLimit)
+	}
+
+	spec.Annotations[hugetlbRsvdAnnotation] = "sure"
+	if _, err := CreateCgroupConfig(opts, nil); err == nil {
+		t.Error("expected error for invalid annotation value, got nil")
+	}
+}
@@ -159,7 +159,7 @@ func (l *linuxStandardInit) Init() error {
 	// just before execve so as few syscalls take place after it as possible.
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
 		if err := seccomp.InitSeccomp(l.config.Config.Seccomp); err != nil {
-			return err
+			return withClass(ErrSeccomp, err)
 		}
 	}
 	if err := finalizeNamespace(l.config); err != nil {
@@ -216,7 +216,7 @@ func (l *linuxStandardInit) Init() error {
 	// enable in their seccomp profiles).
 	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
 		if err := seccomp.InitSeccomp(l.config.Config.Seccomp); err != nil {
-			return fmt.Errorf("unable to init seccomp: %w", err)
+			return withClass(ErrSeccomp, fmt.Errorf("unable to init seccomp: %w", err))
 		}
 	}
 
@@ -224,7 +224,7 @@ func (l *linuxStandardInit) Init() error {
 	s.Pid = unix.Getpid()
 	s.Status = specs.StateCreated
 	if err := l.config.Config.Hooks[configs.StartContainer].RunHooks(s); err != nil {
-		return err
+		return withClass(ErrHook, err)
 	}
 
 	if err := system.Exec(name, l.config.Args[0:], os.Environ()); err != nil {
@@ -44,12 +44,18 @@ func destroy(c *linuxContainer) error {
 			logrus.Warn(err)
 		}
 	}
-	err := c.cgroupManager.Destroy()
+	err := withClass(ErrCgroup, c.cgroupManager.Destroy())
 	if c.intelRdtManager != nil {
 		if ierr := c.intelRdtManager.Destroy(); err == nil {
 			err = ierr
 		}
 	}
+	if nerr := destroyNetworks(c.config); err == nil {
+		err = nerr
+	}
+	if lerr := teardownRootfsLayers(c.config); err == nil {
+		err = lerr
+	}
 	if rerr := os.RemoveAll(c.root); err == nil {
 		err = rerr
 	}
@@ -74,7 +80,7 @@ func runPoststopHooks(c *linuxContainer) error {
 	s.Status = specs.StateStopped
 
 	if err := hooks[configs.Poststop].RunHooks(s); err != nil {
-		return err
+		return withClass(ErrHook, err)
 	}
 
 	return nil
@@ -186,7 +192,7 @@ func (p *pausedState) destroy() error {
 	t := p.c.runType()
 	if t != Running && t != Created {
 		if err := p.c.cgroupManager.Freeze(configs.Thawed); err != nil {
-			return err
+			return withClass(ErrCgroup, err)
 		}
 		return destroy(p.c)
 	}
@@ -38,12 +38,19 @@ type syncT struct {
 // as encoding/json can't unmarshal into error type.
 type initError struct {
 	Message string `json:"message,omitempty"`
+	Class   string `json:"class,omitempty"`
 }
 
 func (i initError) Error() string {
 	return i.Message
 }
 
+// Is reports whether target is the class of the original error.
+func (i initError) Is(target error) bool {
+	class := errorClass(i.Class)
+	return class != nil && target == class //nolint:errorlint // classes are sentinels
+}
+
 // writeSync is used to write to a synchronisation pipe. An error is returned
 // if there was a problem writing the payload.
 func writeSync(pipe io.Writer, sync syncType) error {
@@ -0,0 +1,106 @@
+// +build linux
+
+package system
+
+import (
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flags for the new mount API syscalls, which are not (yet) provided by
+// golang.org/x/sys/unix.
+const (
+	OPEN_TREE_CLONE   = 0x1
+	OPEN_TREE_CLOEXEC = unix.O_CLOEXEC
+
+	MOVE_MOUNT_F_SYMLINKS   = 0x1
+	MOVE_MOUNT_F_AUTOMOUNTS = 0x2
+	MOVE_MOUNT_F_EMPTY_PATH = 0x4
+	MOVE_MOUNT_T_SYMLINKS   = 0x10
+	MOVE_MOUNT_T_AUTOMOUNTS = 0x20
+	MOVE_MOUNT_T_EMPTY_PATH = 0x40
+
+	MOUNT_ATTR_RDONLY      = 0x1
+	MOUNT_ATTR_NOSUID      = 0x2
+	MOUNT_ATTR_NODEV       = 0x4
+	MOUNT_ATTR_NOEXEC      = 0x8
+	MOUNT_ATTR__ATIME      = 0x70
+	MOUNT_ATTR_RELATIME    = 0x0
+	MOUNT_ATTR_NOATIME     = 0x10
+	MOUNT_ATTR_STRICTATIME = 0x20
+	MOUNT_ATTR_NODIRATIME  = 0x80
+	MOUNT_ATTR_NOSYMFOLLOW = 0x200000
+
+	AT_RECURSIVE = 0x8000
+)
+
+// OpenTree is a wrapper for open_tree(2), available since Linux 5.2.
+func OpenTree(dirfd int, path string, flags uint) (int, error) {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return -1, err
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_OPEN_TREE, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags))
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// MoveMount is a wrapper for move_mount(2), available since Linux 5.2.
+func MoveMount(fromDirfd int, fromPath string, toDirfd int, toPath string, flags uint) error {
+	from, err := unix.BytePtrFromString(fromPath)
+	if err != nil {
+		return err
+	}
+	to, err := unix.BytePtrFromString(toPath)
+	if err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_MOVE_MOUNT, uintptr(fromDirfd), uintptr(unsafe.Pointer(from)), uintptr(toDirfd), uintptr(unsafe.Pointer(to)), uintptr(flags), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// MountAttr is struct mount_attr, as used by mount_setattr(2).
+type MountAttr struct {
+	AttrSet     uint64
+	AttrClr     uint64
+	Propagation uint64
+	UsernsFd    uint64
+}
+
+// MountSetattr is a wrapper for mount_setattr(2), available since Linux 5.12.
+func MountSetattr(dirfd int, path string, flags uint, attr *MountAttr) error {
+	p, err := unix.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_MOUNT_SETATTR, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(flags), uintptr(unsafe.Pointer(attr)), unsafe.Sizeof(*attr), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+var (
+	haveMountSetattr     bool
+	haveMountSetattrOnce sync.Once
+)
+
+// HaveMountSetattr reports whether mount_setattr(2) is supported by the
+// kernel, and is not blocked by a seccomp filter.
+func HaveMountSetattr() bool {
+	haveMountSetattrOnce.Do(func() {
+		// An invalid fd makes the call fail early with EBADF if the
+		// syscall is implemented. EPERM is what seccomp filters usually
+		// return for unknown syscalls.
+		err := MountSetattr(-1, "", unix.AT_EMPTY_PATH, &MountAttr{})
+		haveMountSetattr = err != unix.ENOSYS && err != unix.EPERM //nolint:errorlint // unix errors are bare
+	})
+	return haveMountSetattr
+}
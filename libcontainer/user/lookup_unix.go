@@ -12,8 +12,9 @@ import (
 
 // Unix-specific path to the passwd and group formatted files.
 const (
-	unixPasswdPath = "/etc/passwd"
-	unixGroupPath  = "/etc/group"
+	unixPasswdPath   = "/etc/passwd"
+	unixGroupPath    = "/etc/group"
+	unixNsswitchPath = "/etc/nsswitch.conf"
 )
 
 // LookupUser looks up a user by their username in /etc/passwd. If the user
@@ -114,6 +115,12 @@ func GetGroup() (io.ReadCloser, error) {
 	return os.Open(unixGroupPath)
 }
 
+// GetSources returns the passwd and group sources configured in
+// /etc/nsswitch.conf.
+func GetSources() (passwd, group []Source, err error) {
+	return ParseNsswitchFile(unixNsswitchPath)
+}
+
 // CurrentUser looks up the current user by their user id in /etc/passwd. If the
 // user cannot be found (or there is no /etc/passwd file on the filesystem),
 // then CurrentUser returns an error.
@@ -0,0 +1,421 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Status is the result of consulting a Source, as named by the action items
+// of nsswitch.conf.
+type Status string
+
+const (
+	StatusSuccess  Status = "SUCCESS"
+	StatusNotFound Status = "NOTFOUND"
+	StatusUnavail  Status = "UNAVAIL"
+	StatusTryAgain Status = "TRYAGAIN"
+)
+
+var statuses = []Status{StatusSuccess, StatusNotFound, StatusUnavail, StatusTryAgain}
+
+// Action is what a lookup does after consulting a Source, as named by the
+// action items of nsswitch.conf.
+type Action string
+
+const (
+	ActionReturn   Action = "return"
+	ActionContinue Action = "continue"
+	// ActionMerge is only meaningful for the group database. Since the
+	// entries of all the consulted sources are combined anyway, it behaves
+	// like ActionContinue.
+	ActionMerge Action = "merge"
+)
+
+// Source is a passwd or group formatted database consulted during a lookup,
+// as configured by a "passwd:" or "group:" line in nsswitch.conf.
+type Source struct {
+	// Service is the nsswitch service name providing the database (such as
+	// "files" or "extrausers").
+	Service string
+	// Path is the path of the passwd or group formatted file.
+	Path string
+	// Actions holds the action items following the service, if any. A
+	// status without an action item returns on StatusSuccess, and
+	// continues to the next source otherwise.
+	Actions map[Status]Action
+}
+
+// returns reports whether a lookup stops after getting st from s.
+func (s *Source) returns(st Status) bool {
+	if a, ok := s.Actions[st]; ok {
+		return a == ActionReturn
+	}
+	return st == StatusSuccess
+}
+
+// service describes where an nsswitch service keeps its databases.
+type service struct {
+	passwdPath string
+	groupPath  string
+}
+
+var (
+	servicesMu sync.RWMutex
+	// services contains the nsswitch services which are backed by plain
+	// files, and thus can be resolved without loading any NSS module.
+	// Other services (such as "ldap", "sss" or "systemd") are ignored.
+	services = map[string]service{
+		"files":      {passwdPath: unixPasswdPath, groupPath: unixGroupPath},
+		"compat":     {passwdPath: unixPasswdPath, groupPath: unixGroupPath},
+		"altfiles":   {passwdPath: "/usr/lib/passwd", groupPath: "/usr/lib/group"},
+		"extrausers": {passwdPath: "/var/lib/extrausers/passwd", groupPath: "/var/lib/extrausers/group"},
+	}
+)
+
+// RegisterService registers a file-backed nsswitch service, so that it is
+// honored by ParseNsswitch. An already registered service is overridden.
+func RegisterService(name, passwdPath, groupPath string) {
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	services[name] = service{passwdPath: passwdPath, groupPath: groupPath}
+}
+
+// ParseNsswitchFile is a wrapper for ParseNsswitch. If the file does not
+// exist, the "files" service is used for both databases.
+func ParseNsswitchFile(path string) (passwd, group []Source, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ParseNsswitch(nil)
+		}
+		return nil, nil, err
+	}
+	defer f.Close()
+	return ParseNsswitch(f)
+}
+
+// ParseNsswitch parses nsswitch.conf formatted data and returns, in lookup
+// order, the sources to consult for the passwd and group databases, along
+// with their action items (such as "[NOTFOUND=return]"). Services which are
+// not backed by plain files are skipped, and so are their action items. An
+// error is returned for malformed or unsupported action items. If no usable
+// service is configured for a database (or r is nil), the "files" service is
+// used for it.
+func ParseNsswitch(r io.Reader) (passwd, group []Source, err error) {
+	if r != nil {
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			line := s.Bytes()
+			if i := bytes.IndexByte(line, '#'); i >= 0 {
+				line = line[:i]
+			}
+			// see: man 5 nsswitch.conf
+			//  database: service [[action] service]...
+			//  passwd: files [NOTFOUND=return] extrausers
+			parts := bytes.SplitN(line, []byte(":"), 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch string(bytes.TrimSpace(parts[0])) {
+			case "passwd":
+				passwd, err = parseServices(string(parts[1]), func(s service) string { return s.passwdPath })
+			case "group":
+				group, err = parseServices(string(parts[1]), func(s service) string { return s.groupPath })
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(passwd) == 0 {
+		passwd = []Source{{Service: "files", Path: unixPasswdPath}}
+	}
+	if len(group) == 0 {
+		group = []Source{{Service: "files", Path: unixGroupPath}}
+	}
+	return passwd, group, nil
+}
+
+func parseServices(line string, path func(service) string) ([]Source, error) {
+	servicesMu.RLock()
+	defer servicesMu.RUnlock()
+
+	var (
+		out  []Source
+		seen = make(map[string]struct{})
+		// last is the source the next action items apply to, or nil if
+		// the preceding service is not consulted.
+		last *Source
+	)
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated nsswitch action item %q", line)
+			}
+			actions, err := parseActions(line[1:end])
+			if err != nil {
+				return nil, err
+			}
+			line = line[end+1:]
+			if last == nil {
+				continue
+			}
+			if last.Actions == nil {
+				last.Actions = make(map[Status]Action)
+			}
+			for st, a := range actions {
+				last.Actions[st] = a
+			}
+			continue
+		}
+
+		end := strings.IndexAny(line, " \t[")
+		if end < 0 {
+			end = len(line)
+		}
+		name := line[:end]
+		line = line[end:]
+		last = nil
+
+		s, ok := services[name]
+		if !ok {
+			continue
+		}
+		p := path(s)
+		// "files" and "compat" share a database; only consult it once.
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, Source{Service: name, Path: p})
+		last = &out[len(out)-1]
+	}
+	return out, nil
+}
+
+// parseActions parses the contents of an nsswitch.conf action item, such as
+// "NOTFOUND=return !UNAVAIL=continue".
+func parseActions(item string) (map[Status]Action, error) {
+	fields := strings.Fields(strings.ReplaceAll(item, "=", " = "))
+	actions := make(map[Status]Action)
+	for ; len(fields) > 0; fields = fields[3:] {
+		if len(fields) < 3 || fields[1] != "=" {
+			return nil, fmt.Errorf("invalid nsswitch action item [%s]", item)
+		}
+		name := strings.ToUpper(fields[0])
+		negate := strings.HasPrefix(name, "!")
+		st := Status(strings.TrimPrefix(name, "!"))
+		if !validStatus(st) {
+			return nil, fmt.Errorf("unsupported status %q in nsswitch action item [%s]", st, item)
+		}
+		a := Action(strings.ToLower(fields[2]))
+		switch a {
+		case ActionReturn, ActionContinue, ActionMerge:
+		default:
+			return nil, fmt.Errorf("unsupported action %q in nsswitch action item [%s]", a, item)
+		}
+		if !negate {
+			actions[st] = a
+			continue
+		}
+		for _, other := range statuses {
+			if other != st {
+				actions[other] = a
+			}
+		}
+	}
+	return actions, nil
+}
+
+func validStatus(st Status) bool {
+	for _, s := range statuses {
+		if s == st {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup consults the sources in order, as the C library would when looking
+// up an entry for which found reports whether a source contains it. A source
+// which cannot be opened is UNAVAIL. It returns the sources which were
+// consulted, and the first of them containing the entry (or nil). If
+// successReturns is false, a SUCCESS never stops the lookup, as for the
+// supplementary groups of a user.
+func lookup(sources []Source, found func(io.Reader) (bool, error), successReturns bool) ([]Source, *Source, error) {
+	var (
+		consulted []Source
+		match     *Source
+	)
+	for i := range sources {
+		s := &sources[i]
+		st := StatusUnavail
+		if f, err := os.Open(s.Path); err == nil {
+			ok, err := found(f)
+			f.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			consulted = append(consulted, *s)
+			st = StatusNotFound
+			if ok {
+				st = StatusSuccess
+				if match == nil {
+					match = s
+				}
+			}
+		}
+		if st == StatusSuccess && !successReturns {
+			continue
+		}
+		if s.returns(st) {
+			break
+		}
+	}
+	return consulted, match, nil
+}
+
+// openSources opens every existing source file and returns a reader over
+// their concatenated contents, or nil if none of them could be opened. The
+// returned closer must be called once the reader is no longer needed.
+func openSources(sources []Source) (io.Reader, func()) {
+	var (
+		readers []io.Reader
+		files   []*os.File
+	)
+	for _, s := range sources {
+		f, err := os.Open(s.Path)
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+		// Make sure the last line of a file is never joined with the
+		// first line of the next one.
+		readers = append(readers, f, strings.NewReader("\n"))
+	}
+	closer := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	if len(readers) == 0 {
+		return nil, closer
+	}
+	return io.MultiReader(readers...), closer
+}
+
+// GetExecUserSources is like GetExecUserPath, but reads the passwd and group
+// data from the given sources in order, honouring their action items, so
+// that the first source containing a matching entry wins. Sources which
+// cannot be opened are UNAVAIL. The returned ExecUser's Source field is set
+// to the service which resolved the user, if any.
+func GetExecUserSources(userSpec string, defaults *ExecUser, passwd, group []Source) (*ExecUser, error) {
+	if defaults == nil {
+		defaults = new(ExecUser)
+	}
+
+	var userArg, groupArg string
+	parseLine([]byte(userSpec), &userArg, &groupArg)
+	uidArg, uidErr := strconv.Atoi(userArg)
+	gidArg, gidErr := strconv.Atoi(groupArg)
+
+	// Find the sources GetExecUser has to consider, with the same matching
+	// rules as it uses.
+	var userName string
+	passwd, source, err := lookup(passwd, func(r io.Reader) (bool, error) {
+		users, err := ParsePasswdFilter(r, func(u User) bool {
+			if userArg == "" {
+				return u.Uid == defaults.Uid
+			}
+			if uidErr == nil {
+				return u.Uid == uidArg
+			}
+			return u.Name == userArg
+		})
+		if len(users) > 0 && userName == "" {
+			userName = users[0].Name
+		}
+		return len(users) > 0, err
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	group, _, err = lookup(group, func(r io.Reader) (bool, error) {
+		groups, err := ParseGroupFilter(r, func(g Group) bool {
+			if groupArg == "" {
+				for _, u := range g.List {
+					if u == userName {
+						return true
+					}
+				}
+				return false
+			}
+			if gidErr == nil {
+				return g.Gid == gidArg
+			}
+			return g.Name == groupArg
+		})
+		return len(groups) > 0, err
+	}, groupArg != "")
+	if err != nil {
+		return nil, err
+	}
+
+	passwdReader, closePasswd := openSources(passwd)
+	defer closePasswd()
+	groupReader, closeGroup := openSources(group)
+	defer closeGroup()
+
+	execUser, err := GetExecUser(userSpec, defaults, passwdReader, groupReader)
+	if err != nil {
+		return nil, err
+	}
+	if source != nil {
+		execUser.Source = source.Service
+	}
+	return execUser, nil
+}
+
+// GetAdditionalGroupsSources is like GetAdditionalGroupsPath, but looks up
+// each group in the given sources in order, honouring their action items.
+func GetAdditionalGroupsSources(additionalGroups []string, group []Source) ([]int, error) {
+	gids := []int{}
+	seen := make(map[int]struct{})
+	for _, ag := range additionalGroups {
+		consulted, _, err := lookup(group, func(r io.Reader) (bool, error) {
+			groups, err := ParseGroupFilter(r, func(g Group) bool {
+				return g.Name == ag || strconv.Itoa(g.Gid) == ag
+			})
+			return len(groups) > 0, err
+		}, true)
+		if err != nil {
+			return nil, err
+		}
+		groupReader, closeGroup := openSources(consulted)
+		ids, err := GetAdditionalGroups([]string{ag}, groupReader)
+		closeGroup()
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				gids = append(gids, id)
+			}
+		}
+	}
+	return gids, nil
+}
@@ -0,0 +1,215 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package user
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNsswitch(t *testing.T) {
+	const nsswitchContent = `
+# comment: passwd: ldap
+passwd:     files [NOTFOUND=return] extrausers sss # trailing comment
+shadow:     files
+group:      compat [ !UNAVAIL=return ] altfiles files [SUCCESS=continue] systemd [notfound=return]
+hosts:      files dns
+`
+	passwd, group, err := ParseNsswitch(strings.NewReader(nsswitchContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedPasswd := []Source{
+		{Service: "files", Path: "/etc/passwd", Actions: map[Status]Action{StatusNotFound: ActionReturn}},
+		{Service: "extrausers", Path: "/var/lib/extrausers/passwd"},
+	}
+	if !reflect.DeepEqual(passwd, expectedPasswd) {
+		t.Errorf("expected passwd sources %+v, got %+v", expectedPasswd, passwd)
+	}
+
+	// The action items of skipped services are dropped.
+	expectedGroup := []Source{
+		{Service: "compat", Path: "/etc/group", Actions: map[Status]Action{
+			StatusSuccess:  ActionReturn,
+			StatusNotFound: ActionReturn,
+			StatusTryAgain: ActionReturn,
+		}},
+		{Service: "altfiles", Path: "/usr/lib/group"},
+	}
+	if !reflect.DeepEqual(group, expectedGroup) {
+		t.Errorf("expected group sources %+v, got %+v", expectedGroup, group)
+	}
+}
+
+func TestParseNsswitchInvalidActions(t *testing.T) {
+	for _, content := range []string{
+		"passwd: files [NOTFOUND=return extrausers\n",
+		"passwd: files [NOTFOUND] extrausers\n",
+		"passwd: files [NOTFOUND=return=continue] extrausers\n",
+		"passwd: files [MISSING=return] extrausers\n",
+		"passwd: files [NOTFOUND=exit] extrausers\n",
+	} {
+		if _, _, err := ParseNsswitch(strings.NewReader(content)); err == nil {
+			t.Errorf("%q: expected error, got nil", content)
+		}
+	}
+}
+
+func TestParseNsswitchDefaults(t *testing.T) {
+	for _, content := range []string{"", "passwd: ldap\ngroup: sss\n"} {
+		passwd, group, err := ParseNsswitch(strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(passwd) != 1 || passwd[0].Service != "files" || passwd[0].Path != "/etc/passwd" {
+			t.Errorf("%q: expected default passwd source, got %+v", content, passwd)
+		}
+		if len(group) != 1 || group[0].Service != "files" || group[0].Path != "/etc/group" {
+			t.Errorf("%q: expected default group source, got %+v", content, group)
+		}
+	}
+
+	passwd, group, err := ParseNsswitchFile("/does/not/exist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passwd) != 1 || len(group) != 1 {
+		t.Errorf("expected default sources for missing file, got %+v and %+v", passwd, group)
+	}
+}
+
+func TestGetExecUserSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nsswitch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		// No trailing newline, to check that sources are not joined.
+		"passwd":            "root:x:0:0:root:/root:/bin/sh\nadm:x:42:43:adm:/var/adm:/bin/false",
+		"group":             "root:x:0:\nadm:x:43:",
+		"extrausers/passwd": "adm:x:1000:1000:shadowed:/home/adm:/bin/sh\nalice:x:1001:1001:alice:/home/alice:/bin/sh\n",
+		"extrausers/group":  "alice:x:1001:\nusers:x:100:alice\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	passwd := []Source{
+		{Service: "files", Path: filepath.Join(dir, "passwd")},
+		{Service: "extrausers", Path: filepath.Join(dir, "extrausers/passwd")},
+		{Service: "altfiles", Path: filepath.Join(dir, "missing")},
+	}
+	group := []Source{
+		{Service: "files", Path: filepath.Join(dir, "group")},
+		{Service: "extrausers", Path: filepath.Join(dir, "extrausers/group")},
+	}
+
+	tests := []struct {
+		ref      string
+		expected ExecUser
+	}{
+		{
+			ref: "adm",
+			expected: ExecUser{
+				Uid:    42,
+				Gid:    43,
+				Sgids:  []int{},
+				Home:   "/var/adm",
+				Source: "files",
+			},
+		},
+		{
+			ref: "alice",
+			expected: ExecUser{
+				Uid:    1001,
+				Gid:    1001,
+				Sgids:  []int{100},
+				Home:   "/home/alice",
+				Source: "extrausers",
+			},
+		},
+		{
+			ref: "1001:adm",
+			expected: ExecUser{
+				Uid:    1001,
+				Gid:    43,
+				Sgids:  []int{},
+				Home:   "/home/alice",
+				Source: "extrausers",
+			},
+		},
+		{
+			ref: "4242",
+			expected: ExecUser{
+				Uid:   4242,
+				Sgids: []int{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		execUser, err := GetExecUserSources(test.ref, nil, passwd, group)
+		if err != nil {
+			t.Errorf("got unexpected error when parsing '%s': %s", test.ref, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, *execUser) {
+			t.Errorf("ref %v: expected %+v, got %+v", test.ref, test.expected, *execUser)
+		}
+	}
+
+	gids, err := GetAdditionalGroupsSources([]string{"adm", "users"}, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gids, []int{43, 100}) {
+		t.Errorf("expected additional groups [43 100], got %v", gids)
+	}
+
+	// With [NOTFOUND=return], the lookup does not go past "files".
+	passwd[0].Actions = map[Status]Action{StatusNotFound: ActionReturn}
+	group[0].Actions = map[Status]Action{StatusNotFound: ActionReturn}
+	if _, err := GetExecUserSources("alice", nil, passwd, group); err == nil {
+		t.Error("expected alice not to be found with [NOTFOUND=return]")
+	}
+	execUser, err := GetExecUserSources("adm", nil, passwd, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execUser.Source != "files" || execUser.Uid != 42 {
+		t.Errorf("expected adm from files, got %+v", *execUser)
+	}
+	if _, err := GetAdditionalGroupsSources([]string{"users"}, group); err == nil {
+		t.Error("expected group users not to be found with [NOTFOUND=return]")
+	}
+
+	// A missing file is UNAVAIL, so [UNAVAIL=return] stops the lookup.
+	passwd = []Source{
+		{Service: "altfiles", Path: filepath.Join(dir, "missing"), Actions: map[Status]Action{StatusUnavail: ActionReturn}},
+		passwd[0],
+	}
+	if _, err := GetExecUserSources("adm", nil, passwd, group); err == nil {
+		t.Error("expected adm not to be found with [UNAVAIL=return]")
+	}
+	passwd[0].Actions[StatusUnavail] = ActionContinue
+	execUser, err = GetExecUserSources("adm", nil, passwd, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execUser.Source != "files" {
+		t.Errorf("expected adm from files with [UNAVAIL=continue], got %+v", *execUser)
+	}
+}
@@ -23,6 +23,9 @@ var (
 	ErrNoGroupEntries = errors.New("no matching entries in group file")
 	// ErrRange is returned if a UID or GID is outside of the valid range.
 	ErrRange = fmt.Errorf("uids and gids must be in range %d-%d", minID, maxID)
+	// ErrNoSubIDRange is returned if no free subordinate ID range of the
+	// requested size could be found.
+	ErrNoSubIDRange = errors.New("no free subordinate id range")
 )
 
 type User struct {
@@ -249,6 +252,9 @@ type ExecUser struct {
 	Gid   int
 	Sgids []int
 	Home  string
+	// Source is the nsswitch service which resolved the user. It is only
+	// set by GetExecUserSources.
+	Source string
 }
 
 // GetExecUserPath is a wrapper for GetExecUser. It reads data from each of the
@@ -551,6 +557,50 @@ func ParseSubIDFilter(r io.Reader, filter func(SubID) bool) ([]SubID, error) {
 	return out, nil
 }
 
+// SubIDMappings returns ID mappings which map the container ID 0 to hostID
+// and the following container IDs to each of the given subordinate ID ranges,
+// in order. Such mappings can only be set up by an unprivileged user with the
+// help of newuidmap(1) and newgidmap(1).
+func SubIDMappings(hostID int64, subIDs []SubID) []IDMap {
+	mappings := []IDMap{{ID: 0, ParentID: hostID, Count: 1}}
+	next := int64(1)
+	for _, s := range subIDs {
+		if s.Count <= 0 {
+			continue
+		}
+		mappings = append(mappings, IDMap{ID: next, ParentID: s.SubID, Count: s.Count})
+		next += s.Count
+	}
+	return mappings
+}
+
+// FindFreeSubIDRange returns the first ID of a range of size contiguous IDs
+// which lies within one of the given subordinate ID ranges and does not
+// overlap with the parent IDs of any of the used mappings.
+func FindFreeSubIDRange(subIDs []SubID, used []IDMap, size int64) (int64, error) {
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid subordinate id range size %d", size)
+	}
+	for _, s := range subIDs {
+		start := s.SubID
+		for start+size <= s.SubID+s.Count {
+			free := true
+			for _, u := range used {
+				if start < u.ParentID+u.Count && u.ParentID < start+size {
+					// Retry right after the overlapping range.
+					start = u.ParentID + u.Count
+					free = false
+					break
+				}
+			}
+			if free {
+				return start, nil
+			}
+		}
+	}
+	return 0, ErrNoSubIDRange
+}
+
 func ParseIDMapFile(path string) ([]IDMap, error) {
 	r, err := os.Open(path)
 	if err != nil {
@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -528,3 +529,73 @@ func largeGroup() (res string) {
 	}
 	return b.String()
 }
+
+func TestSubIDMappings(t *testing.T) {
+	subIDs := []SubID{
+		{Name: "user", SubID: 100000, Count: 65536},
+		{Name: "user", SubID: 300000, Count: 0},
+		{Name: "user", SubID: 500000, Count: 1000},
+	}
+	expected := []IDMap{
+		{ID: 0, ParentID: 1000, Count: 1},
+		{ID: 1, ParentID: 100000, Count: 65536},
+		{ID: 65537, ParentID: 500000, Count: 1000},
+	}
+	mappings := SubIDMappings(1000, subIDs)
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("expected %+v, got %+v", expected, mappings)
+	}
+}
+
+func TestFindFreeSubIDRange(t *testing.T) {
+	subIDs := []SubID{
+		{Name: "user", SubID: 100000, Count: 65536},
+		{Name: "user", SubID: 200000, Count: 131072},
+	}
+
+	tests := []struct {
+		used     []IDMap
+		size     int64
+		expected int64
+		err      error
+	}{
+		{
+			size:     65536,
+			expected: 100000,
+		},
+		{
+			used:     []IDMap{{ID: 0, ParentID: 100000, Count: 1000}},
+			size:     1000,
+			expected: 101000,
+		},
+		{
+			used: []IDMap{
+				{ID: 0, ParentID: 100000, Count: 65536},
+				{ID: 0, ParentID: 200000, Count: 65536},
+			},
+			size:     65536,
+			expected: 265536,
+		},
+		{
+			used:     []IDMap{{ID: 0, ParentID: 150000, Count: 60000}},
+			size:     65536,
+			expected: 210000,
+		},
+		{
+			used: []IDMap{{ID: 0, ParentID: 100000, Count: 65536}},
+			size: 200000,
+			err:  ErrNoSubIDRange,
+		},
+	}
+
+	for _, test := range tests {
+		start, err := FindFreeSubIDRange(subIDs, test.used, test.size)
+		if !errors.Is(err, test.err) {
+			t.Errorf("used %+v: expected error %v, got %v", test.used, test.err, err)
+			continue
+		}
+		if err == nil && start != test.expected {
+			t.Errorf("used %+v: expected %d, got %d", test.used, test.expected, start)
+		}
+	}
+}
@@ -107,6 +107,15 @@ func main() {
 			Name:  "systemd-cgroup",
 			Usage: "enable systemd cgroup support, expects cgroupsPath to be of form \"slice:prefix:name\" for e.g. \"system.slice:runc:434234\"",
 		},
+		cli.StringFlag{
+			Name:  "systemd-connection",
+			Value: "auto",
+			Usage: "how to connect to systemd with --systemd-cgroup ('dbus', 'private' for its private socket, or 'auto')",
+		},
+		cli.BoolFlag{
+			Name:  "systemd-fallback",
+			Usage: "with --systemd-cgroup, fall back to cgroupfs if systemd can not be used",
+		},
 		cli.StringFlag{
 			Name:  "rootless",
 			Value: "auto",
@@ -117,8 +126,10 @@ func main() {
 		checkpointCommand,
 		createCommand,
 		deleteCommand,
+		deviceCommand,
 		eventsCommand,
 		execCommand,
+		featuresCommand,
 		initCommand,
 		killCommand,
 		listCommand,
@@ -130,6 +141,7 @@ func main() {
 		specCommand,
 		startCommand,
 		stateCommand,
+		systemdUnitCommand,
 		updateCommand,
 	}
 	app.Before = func(context *cli.Context) error {
@@ -4,11 +4,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
@@ -61,7 +63,9 @@ container on your host.
 Alternatively, you can start a rootless container, which has the ability to run
 without root privileges. For this to work, the specification file needs to be
 adjusted accordingly. You can pass the parameter --rootless to this command to
-generate a proper rootless spec file.
+generate a proper rootless spec file. By default, only the current user is
+mapped into the container (as root); add --subids to also map the user's
+subordinate ids from /etc/subuid and /etc/subgid.
 
 Note that --rootless is not needed when you execute runc as the root in a user namespace
 created by an unprivileged user.
@@ -76,16 +80,34 @@ created by an unprivileged user.
 			Name:  "rootless",
 			Usage: "generate a configuration for a rootless container",
 		},
+		cli.BoolFlag{
+			Name:  "subids",
+			Usage: "with --rootless, also map the current user's subordinate uids and gids (requires newuidmap and newgidmap)",
+		},
+		cli.BoolFlag{
+			Name:  "validate",
+			Usage: "validate the existing specification file, and print how its resources are applied on this host, instead of creating a new one",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
+		if context.Bool("validate") {
+			return validateSpec(context)
+		}
 		spec := specconv.Example()
 
 		rootless := context.Bool("rootless")
 		if rootless {
 			specconv.ToRootless(spec)
+			if context.Bool("subids") {
+				if err := setSubIDMappings(spec); err != nil {
+					return err
+				}
+			}
+		} else if context.Bool("subids") {
+			return errors.New("--subids requires --rootless")
 		}
 
 		checkNoFile := func(name string) error {
@@ -115,6 +137,44 @@ created by an unprivileged user.
 	},
 }
 
+// validateSpec validates the specification file of the bundle, as converted
+// to the container configuration, and prints how its resources are applied
+// on this host.
+func validateSpec(context *cli.Context) error {
+	if context.Bool("rootless") || context.Bool("subids") {
+		return errors.New("--validate can not be used with --rootless or --subids")
+	}
+	if bundle := context.String("bundle"); bundle != "" {
+		if err := os.Chdir(bundle); err != nil {
+			return err
+		}
+	}
+	spec, err := loadSpec(specConfig)
+	if err != nil {
+		return err
+	}
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		return err
+	}
+	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
+		CgroupName:       "validate",
+		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
+		Spec:             spec,
+		RootlessEUID:     os.Geteuid() != 0,
+		RootlessCgroups:  rootlessCg,
+	})
+	if err != nil {
+		return err
+	}
+	if err := validate.New().Validate(config); err != nil {
+		return err
+	}
+	fmt.Printf("%s is valid\n", specConfig)
+	printResourceTranslations(os.Stdout, config.Cgroups.Resources)
+	return nil
+}
+
 // loadSpec loads the specification from the provided path.
 func loadSpec(cPath string) (spec *specs.Spec, err error) {
 	cf, err := os.Open(cPath)
@@ -0,0 +1,168 @@
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/user"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+)
+
+// checkMappingTools makes sure newuidmap and newgidmap are available, as an
+// unprivileged user can only set up multi-range mappings through them.
+func checkMappingTools() error {
+	for _, tool := range []string{"newuidmap", "newgidmap"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("subordinate id mappings require %s: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+func toSpecIDMappings(mappings []user.IDMap) []specs.LinuxIDMapping {
+	out := make([]specs.LinuxIDMapping, 0, len(mappings))
+	for _, m := range mappings {
+		out = append(out, specs.LinuxIDMapping{
+			ContainerID: uint32(m.ID),
+			HostID:      uint32(m.ParentID),
+			Size:        uint32(m.Count),
+		})
+	}
+	return out
+}
+
+// setSubIDMappings replaces the ID mappings of a rootless spec with ones that
+// map root to the current user and the remaining container IDs to all of the
+// current user's subordinate IDs.
+func setSubIDMappings(spec *specs.Spec) error {
+	if err := checkMappingTools(); err != nil {
+		return err
+	}
+	subUIDs, err := user.CurrentUserSubUIDs()
+	if err != nil {
+		return fmt.Errorf("unable to read subordinate uids: %w", err)
+	}
+	subGIDs, err := user.CurrentUserSubGIDs()
+	if err != nil {
+		return fmt.Errorf("unable to read subordinate gids: %w", err)
+	}
+	if len(subUIDs) == 0 || len(subGIDs) == 0 {
+		return errors.New("no subordinate ids are allocated to the current user")
+	}
+	spec.Linux.UIDMappings = toSpecIDMappings(user.SubIDMappings(int64(os.Geteuid()), subUIDs))
+	spec.Linux.GIDMappings = toSpecIDMappings(user.SubIDMappings(int64(os.Getegid()), subGIDs))
+	return nil
+}
+
+// lockRoot takes an exclusive lock on the state root directory, so that
+// concurrent runc invocations do not allocate the same subordinate IDs. The
+// returned function releases the lock, and may be called more than once.
+func lockRoot(context *cli.Context) (func(), error) {
+	root, err := filepath.Abs(context.GlobalString("root"))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+	fd, err := unix.Open(root, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	if err := unix.Flock(fd, unix.LOCK_EX); err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "flock", Path: root, Err: err}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { unix.Close(fd) })
+	}, nil
+}
+
+// usedIDMappings returns the host uid and gid ranges mapped by all the
+// containers known to the factory.
+func usedIDMappings(context *cli.Context, factory libcontainer.Factory) (uids, gids []user.IDMap, _ error) {
+	root, err := filepath.Abs(context.GlobalString("root"))
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	toIDMap := func(mappings []configs.IDMap) []user.IDMap {
+		var out []user.IDMap
+		for _, m := range mappings {
+			out = append(out, user.IDMap{ID: int64(m.ContainerID), ParentID: int64(m.HostID), Count: int64(m.Size)})
+		}
+		return out
+	}
+	for _, item := range list {
+		if !item.IsDir() {
+			continue
+		}
+		container, err := factory.Load(item.Name())
+		if err != nil {
+			continue
+		}
+		config := container.Config()
+		uids = append(uids, toIDMap(config.UidMappings)...)
+		gids = append(gids, toIDMap(config.GidMappings)...)
+	}
+	return uids, gids, nil
+}
+
+// setAutoIDMappings maps the size container IDs starting from 0 to a slice
+// of the current user's subordinate IDs which is not used by any other
+// container. The chosen mappings end up in the container's state as part of
+// its config. The root directory must be locked by the caller.
+func setAutoIDMappings(context *cli.Context, factory libcontainer.Factory, spec *specs.Spec, size int64) error {
+	var userns *specs.LinuxNamespace
+	if spec.Linux != nil {
+		for i, ns := range spec.Linux.Namespaces {
+			if ns.Type == specs.UserNamespace {
+				userns = &spec.Linux.Namespaces[i]
+			}
+		}
+	}
+	if userns == nil {
+		return errors.New("automatic id mappings require a user namespace")
+	}
+	if userns.Path != "" {
+		return errors.New("automatic id mappings cannot be used when joining an existing user namespace")
+	}
+	subUIDs, err := user.CurrentUserSubUIDs()
+	if err != nil {
+		return fmt.Errorf("unable to read subordinate uids: %w", err)
+	}
+	subGIDs, err := user.CurrentUserSubGIDs()
+	if err != nil {
+		return fmt.Errorf("unable to read subordinate gids: %w", err)
+	}
+	usedUIDs, usedGIDs, err := usedIDMappings(context, factory)
+	if err != nil {
+		return err
+	}
+	uid, err := user.FindFreeSubIDRange(subUIDs, usedUIDs, size)
+	if err != nil {
+		return fmt.Errorf("unable to allocate %d uids: %w", size, err)
+	}
+	gid, err := user.FindFreeSubIDRange(subGIDs, usedGIDs, size)
+	if err != nil {
+		return fmt.Errorf("unable to allocate %d gids: %w", size, err)
+	}
+	spec.Linux.UIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: uint32(uid), Size: uint32(size)}}
+	spec.Linux.GIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: uint32(gid), Size: uint32(size)}}
+	return nil
+}
@@ -0,0 +1,77 @@
+// +build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
+	"github.com/urfave/cli"
+)
+
+var systemdUnitCommand = cli.Command{
+	Name:  "systemd-unit",
+	Usage: "display the properties of the systemd unit of a container",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The systemd-unit command displays the properties of the systemd unit
+(scope or slice) of a container created with the systemd cgroup driver
+(see --systemd-cgroup), as currently reported by systemd, in the same
+"Name=value" format as "systemctl show".`,
+	Flags: []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "property, p",
+			Usage: "only display the given property (can be specified multiple times)",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		if !context.GlobalBool("systemd-cgroup") {
+			return errors.New("systemd-unit requires the systemd cgroup driver (--systemd-cgroup)")
+		}
+		rootlessCg, err := shouldUseRootlessCgroupManager(context)
+		if err != nil {
+			return err
+		}
+		mode, err := systemd.ParseConnMode(context.GlobalString("systemd-connection"))
+		if err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		state, err := container.State()
+		if err != nil {
+			return err
+		}
+		if state.CgroupManager == "cgroupfs" {
+			return errors.New("container uses the cgroupfs cgroup driver, as systemd could not be used when it was created")
+		}
+		props, err := systemd.UnitProperties(container.Config().Cgroups, rootlessCg, mode)
+		if err != nil {
+			return err
+		}
+
+		names := context.StringSlice("property")
+		if len(names) == 0 {
+			for name := range props {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			value, ok := props[name]
+			if !ok {
+				return fmt.Errorf("unit has no property %q", name)
+			}
+			fmt.Printf("%s=%v\n", name, value)
+		}
+		return nil
+	},
+}
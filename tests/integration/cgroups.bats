@@ -282,3 +282,29 @@ function setup() {
 	[ "$status" -eq 0 ]
 	[ "$(wc -l <<<"$output")" -eq 1 ]
 }
+
+@test "runc run (systemd properties in unit file syntax)" {
+	requires systemd
+	[[ "$ROOTLESS" -ne 0 ]] && requires rootless_cgroup
+
+	set_cgroups_path
+	update_config '	  .annotations += {
+				"org.systemd.property.TasksMax": "infinity",
+				"org.systemd.property.MemoryHigh": "512M",
+				"org.systemd.property.CollectMode": "'"'inactive-or-failed'"'"
+			}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_systemd_props
+	[ "$status" -eq 0 ]
+
+	check_systemd_value "TasksMax" "infinity"
+	check_systemd_value "MemoryHigh" 536870912
+
+	runc systemd-unit -p MemoryHigh -p CollectMode test_systemd_props
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "MemoryHigh=536870912" ]
+	[ "${lines[1]}" = "CollectMode=inactive-or-failed" ]
+
+	runc systemd-unit -p NoSuchProperty test_systemd_props
+	[ "$status" -ne 0 ]
+}
@@ -116,6 +116,8 @@ function init_cgroup_paths() {
 		#   it's quite hard to test.
 		# - freezer (since kernel 5.2) we can auto-detect by looking for the
 		#   "cgroup.freeze" file a *non-root* cgroup.
+		# - kill (since kernel 5.14) is similarly detected by looking for the
+		#   "cgroup.kill" file.
 		CGROUP_SUBSYSTEMS=$(
 			cat "$controllers"
 			echo devices
@@ -126,6 +128,10 @@ function init_cgroup_paths() {
 		if [ -n "$(find "$CGROUP_BASE_PATH" -type f -name "cgroup.freeze" -print -quit)" ]; then
 			CGROUP_SUBSYSTEMS+=" freezer"
 		fi
+		# ...and cgroup.kill files.
+		if [ -n "$(find "$CGROUP_BASE_PATH" -type f -name "cgroup.kill" -print -quit)" ]; then
+			CGROUP_SUBSYSTEMS+=" kill"
+		fi
 	else
 		CGROUP_UNIFIED=no
 		CGROUP_SUBSYSTEMS=$(awk '!/^#/ {print $1}' /proc/cgroups)
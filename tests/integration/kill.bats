@@ -29,3 +29,63 @@ function teardown() {
 	runc delete test_busybox
 	[ "$status" -eq 0 ]
 }
+
+# Spawns many processes in the container, and checks that they are
+# all gone after the container is killed.
+function test_kill_all() {
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	__runc exec -d test_busybox sh -c 'for i in $(seq 100); do sleep 1d & done; wait'
+	retry 10 1 eval "[ \$(__runc ps -f json test_busybox | jq length) -gt 100 ]"
+
+	runc kill --all test_busybox KILL
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_busybox stopped
+
+	runc ps -f json test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(echo "$output" | jq length)" -eq 0 ]
+
+	runc delete test_busybox
+	[ "$status" -eq 0 ]
+}
+
+@test "kill --all KILL [cgroup.kill]" {
+	requires root cgroups_kill
+	test_kill_all
+}
+
+@test "kill --all KILL [freezer]" {
+	requires root
+	# Without cgroup.kill (cgroup v1, or kernel < 5.14), the cgroup is
+	# frozen while its processes are signalled.
+	init_cgroup_paths
+	if [[ "$CGROUP_SUBSYSTEMS" == *kill* ]]; then
+		skip "test requires no cgroup.kill"
+	fi
+	test_kill_all
+}
+
+@test "delete --force with many processes [cgroup.kill]" {
+	requires root cgroups_kill
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	__runc exec -d test_busybox sh -c 'for i in $(seq 100); do sleep 1d & done; wait'
+	retry 10 1 eval "[ \$(__runc ps -f json test_busybox | jq length) -gt 100 ]"
+
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+	output=$(find /sys/fs/cgroup -wholename '*test_busybox*' -type d)
+	[ "$output" = "" ] || fail "cgroup not cleaned up correctly: $output"
+}
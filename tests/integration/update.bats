@@ -660,3 +660,25 @@ EOF
 	runc resume test_update
 	[ "$status" -eq 0 ]
 }
+
+@test "update --explain" {
+	[[ "$ROOTLESS" -ne 0 ]] && requires rootless_cgroup
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc update --explain --cpu-share 200 --pids-limit 30 test_update
+	[ "$status" -eq 0 ]
+	if [ "$CGROUP_UNIFIED" = "yes" ]; then
+		[[ "$output" == *"resources on cgroup v2:"* ]]
+		[[ "$output" == *"CpuShares=200: cpu.weight=8 "* ]]
+	else
+		[[ "$output" == *"resources on cgroup v1:"* ]]
+		[[ "$output" == *"CpuShares=200: cpu.shares=200"* ]]
+	fi
+	[[ "$output" == *"PidsLimit=30: pids.max=30"* ]]
+
+	# Nothing is changed.
+	check_cpu_shares 100
+	check_cgroup_value "pids.max" 20
+}
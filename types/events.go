@@ -22,9 +22,12 @@ type Stats struct {
 }
 
 type Hugetlb struct {
-	Usage   uint64 `json:"usage,omitempty"`
-	Max     uint64 `json:"max,omitempty"`
-	Failcnt uint64 `json:"failcnt"`
+	Usage       uint64 `json:"usage,omitempty"`
+	Max         uint64 `json:"max,omitempty"`
+	Failcnt     uint64 `json:"failcnt"`
+	RsvdUsage   uint64 `json:"rsvd_usage,omitempty"`
+	RsvdMax     uint64 `json:"rsvd_max,omitempty"`
+	RsvdFailcnt uint64 `json:"rsvd_failcnt,omitempty"`
 }
 
 type BlkioEntry struct {
@@ -43,6 +46,21 @@ type Blkio struct {
 	IoMergedRecursive       []BlkioEntry `json:"ioMergedRecursive,omitempty"`
 	IoTimeRecursive         []BlkioEntry `json:"ioTimeRecursive,omitempty"`
 	SectorsRecursive        []BlkioEntry `json:"sectorsRecursive,omitempty"`
+
+	// cgroup v2 only
+	IoStat []IoStatEntry `json:"ioStat,omitempty"`
+}
+
+// IoStatEntry holds the cgroup v2 io.stat values of a device.
+type IoStatEntry struct {
+	Major  uint64 `json:"major,omitempty"`
+	Minor  uint64 `json:"minor,omitempty"`
+	Rbytes uint64 `json:"rbytes"`
+	Wbytes uint64 `json:"wbytes"`
+	Rios   uint64 `json:"rios"`
+	Wios   uint64 `json:"wios"`
+	Dbytes uint64 `json:"dbytes"`
+	Dios   uint64 `json:"dios"`
 }
 
 type Pids struct {
@@ -54,6 +72,8 @@ type Throttling struct {
 	Periods          uint64 `json:"periods,omitempty"`
 	ThrottledPeriods uint64 `json:"throttledPeriods,omitempty"`
 	ThrottledTime    uint64 `json:"throttledTime,omitempty"`
+	Bursts           uint64 `json:"bursts,omitempty"`
+	BurstTime        uint64 `json:"burstTime,omitempty"`
 }
 
 type CpuUsage struct {
@@ -138,6 +158,15 @@ type IntelRdt struct {
 
 	// The cache monitoring technology statistics from NUMA nodes in 'container_id' group
 	CMTStats *[]intelrdt.CMTNumaNodeStats `json:"cmt_stats,omitempty"`
+
+	// The read-only information of all the cache resources, by resource name
+	CacheInfo map[string]*L3CacheInfo `json:"cache_info,omitempty"`
+
+	// The read-only schemata in root, by resource name and domain id
+	SchemataRoot map[string]map[string]string `json:"schemata_root,omitempty"`
+
+	// The schemata in 'container_id' group, by resource name and domain id
+	Schemata map[string]map[string]string `json:"schemata,omitempty"`
 }
 
 type NetworkInterface struct {
@@ -6,15 +6,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/sirupsen/logrus"
 
 	"github.com/docker/go-units"
+	cgroupdevices "github.com/opencontainers/runc/libcontainer/cgroups/devices"
+	"github.com/opencontainers/runc/libcontainer/cgroups/ebpf/devicefilter"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/devices"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
 )
@@ -114,6 +121,42 @@ other options are ignored.
 			Name:  "memory-swap",
 			Usage: "Total memory usage (memory + swap); set '-1' to enable unlimited swap",
 		},
+		cli.StringFlag{
+			Name:  "memory-high",
+			Usage: "Memory usage throttle limit (in bytes); set '-1' to remove the limit, '0' is invalid (cgroup v2 only)",
+		},
+		cli.StringFlag{
+			Name:  "memory-low",
+			Usage: "Best-effort memory protection (in bytes)",
+		},
+		cli.StringFlag{
+			Name:  "memory-min",
+			Usage: "Hard memory protection (in bytes) (cgroup v2 only)",
+		},
+		cli.StringFlag{
+			Name:  "memory-swap-max",
+			Usage: "Swap usage limit, not including memory (in bytes); set '-1' to enable unlimited swap",
+		},
+		cli.StringFlag{
+			Name:  "memory-oom-group",
+			Usage: "Kill all the container's processes together on OOM ('true' or 'false') (cgroup v2 only)",
+		},
+		cli.StringFlag{
+			Name:  "cpu-burst",
+			Usage: "CPU time (in usecs) unused in previous periods which can be used in addition to the CFS quota",
+		},
+		cli.StringFlag{
+			Name:  "cpu-idle",
+			Usage: "Set to 1 to schedule the container's processes with the lowest priority, or 0",
+		},
+		cli.StringFlag{
+			Name:  "cpu-uclamp-min",
+			Usage: "Minimum CPU utilization clamp, as a percentage (such as '12.34'), or 'max'",
+		},
+		cli.StringFlag{
+			Name:  "cpu-uclamp-max",
+			Usage: "Maximum CPU utilization clamp, as a percentage (such as '12.34'), or 'max'",
+		},
 		cli.IntFlag{
 			Name:  "pids-limit",
 			Usage: "Maximum number of pids allowed in the container",
@@ -126,6 +169,14 @@ other options are ignored.
 			Name:  "mem-bw-schema",
 			Usage: "The string of Intel RDT/MBA memory bandwidth schema",
 		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "print the resource changes instead of applying them",
+		},
+		cli.BoolFlag{
+			Name:  "explain",
+			Usage: "print how the resulting resources are applied on this host's cgroup version, instead of applying them",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -162,6 +213,8 @@ other options are ignored.
 		}
 
 		config := container.Config()
+		// Keep a copy of the current resources for --dry-run.
+		oldResources := *config.Cgroups.Resources
 
 		if in := context.String("resources"); in != "" {
 			var (
@@ -251,6 +304,63 @@ other options are ignored.
 			}
 
 			r.Pids.Limit = int64(context.Int("pids-limit"))
+
+			// These have no equivalent in the runtime spec, other than
+			// the unified map, so are set directly in the config.
+			res := config.Cgroups.Resources
+			for _, pair := range []struct {
+				opt  string
+				dest **int64
+			}{
+				{"memory-high", &res.MemoryHigh},
+				{"memory-low", &res.MemoryLow},
+				{"memory-min", &res.MemoryMin},
+				{"memory-swap-max", &res.MemorySwapMax},
+			} {
+				if val := context.String(pair.opt); val != "" {
+					var v int64
+
+					if val != "-1" {
+						v, err = units.RAMInBytes(val)
+						if err != nil {
+							return fmt.Errorf("invalid value for %s: %w", pair.opt, err)
+						}
+					} else {
+						v = -1
+					}
+					*pair.dest = &v
+				}
+			}
+			if res.MemoryHigh != nil && *res.MemoryHigh == 0 {
+				return errors.New("invalid value for memory-high: 0 (use -1 to remove the limit)")
+			}
+			if val := context.String("cpu-burst"); val != "" {
+				v, err := strconv.ParseUint(val, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid value for cpu-burst: %w", err)
+				}
+				res.CpuBurst = &v
+			}
+			if val := context.String("cpu-idle"); val != "" {
+				v, err := strconv.ParseInt(val, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid value for cpu-idle: %w", err)
+				}
+				res.CpuIdle = &v
+			}
+			if val := context.String("cpu-uclamp-min"); val != "" {
+				res.CpuUclampMin = val
+			}
+			if val := context.String("cpu-uclamp-max"); val != "" {
+				res.CpuUclampMax = val
+			}
+			if val := context.String("memory-oom-group"); val != "" {
+				v, err := strconv.ParseBool(val)
+				if err != nil {
+					return fmt.Errorf("invalid value for memory-oom-group: %w", err)
+				}
+				res.MemoryOomGroup = &v
+			}
 		}
 
 		if *r.Memory.Kernel != 0 || *r.Memory.KernelTCP != 0 {
@@ -299,6 +409,12 @@ other options are ignored.
 		config.Cgroups.Resources.MemorySwap = *r.Memory.Swap
 		config.Cgroups.Resources.PidsLimit = r.Pids.Limit
 		config.Cgroups.Resources.Unified = r.Unified
+		if err := specconv.SetMemoryFromUnified(config.Cgroups.Resources); err != nil {
+			return err
+		}
+		if err := specconv.SetCPUFromUnified(config.Cgroups.Resources); err != nil {
+			return err
+		}
 
 		// Update Intel RDT
 		l3CacheSchema := context.String("l3-cache-schema")
@@ -311,6 +427,27 @@ other options are ignored.
 			return errors.New("Intel RDT/MBA: memory bandwidth schema is not enabled")
 		}
 
+		explain := context.Bool("explain")
+		if explain {
+			printResourceTranslations(os.Stdout, config.Cgroups.Resources)
+		}
+
+		if context.Bool("dry-run") {
+			var oldIntelRdt configs.IntelRdt
+			if config.IntelRdt != nil {
+				oldIntelRdt = *config.IntelRdt
+			}
+			newIntelRdt := oldIntelRdt
+			if l3CacheSchema != "" || memBwSchema != "" {
+				newIntelRdt.L3CacheSchema = l3CacheSchema
+				newIntelRdt.MemBwSchema = memBwSchema
+			}
+			return printUpdateDiff(os.Stdout, &oldResources, config.Cgroups.Resources, r.Devices, &oldIntelRdt, &newIntelRdt)
+		}
+		if explain {
+			return nil
+		}
+
 		if l3CacheSchema != "" || memBwSchema != "" {
 			// If intelRdt is not specified in original configuration, we just don't
 			// Apply() to create intelRdt group or attach tasks for this container.
@@ -341,3 +478,131 @@ other options are ignored.
 		return container.Set(config)
 	},
 }
+
+// printUpdateDiff writes a human-readable description of the changes "runc
+// update" would make to the container's resources, including the device rule
+// transitions computed by the devices emulator for the requested devices.
+func printUpdateDiff(w io.Writer, oldRes, newRes *configs.Resources, devs []specs.LinuxDeviceCgroup, oldRdt, newRdt *configs.IntelRdt) error {
+	changes := 0
+	diff := func(name string, oldVal, newVal interface{}) error {
+		if reflect.DeepEqual(oldVal, newVal) {
+			return nil
+		}
+		o, err := json.Marshal(oldVal)
+		if err != nil {
+			return err
+		}
+		n, err := json.Marshal(newVal)
+		if err != nil {
+			return err
+		}
+		changes++
+		_, err = fmt.Fprintf(w, "%s: %s -> %s\n", name, o, n)
+		return err
+	}
+
+	oldV, newV := reflect.ValueOf(*oldRes), reflect.ValueOf(*newRes)
+	for i := 0; i < oldV.NumField(); i++ {
+		field := oldV.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		// Devices are handled separately below.
+		if name == "" || name == "-" || name == "devices" {
+			continue
+		}
+		if err := diff(name, oldV.Field(i).Interface(), newV.Field(i).Interface()); err != nil {
+			return err
+		}
+	}
+	if err := diff("intel_rdt.l3_cache_schema", oldRdt.L3CacheSchema, newRdt.L3CacheSchema); err != nil {
+		return err
+	}
+	if err := diff("intel_rdt.memBwSchema", oldRdt.MemBwSchema, newRdt.MemBwSchema); err != nil {
+		return err
+	}
+	if changes == 0 {
+		fmt.Fprintln(w, "no resource changes")
+	}
+
+	rules := oldRes.Devices
+	if len(devs) > 0 {
+		requested, err := specconv.CreateDeviceRules(devs)
+		if err != nil {
+			return err
+		}
+		for _, d := range specconv.AllowedDevices {
+			requested = append(requested, &d.Rule)
+		}
+		source, err := cgroupdevices.EmulatorFromRules(oldRes.Devices)
+		if err != nil {
+			return err
+		}
+		target, err := cgroupdevices.EmulatorFromRules(requested)
+		if err != nil {
+			return err
+		}
+		transition, err := source.Transition(target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "devices (not applied by runc update):")
+		if len(transition) == 0 {
+			fmt.Fprintln(w, "\tno changes")
+		}
+		for _, rule := range transition {
+			action := "deny"
+			if rule.Allow {
+				action = "allow"
+			}
+			fmt.Fprintf(w, "\t%s %s\n", action, rule.CgroupString())
+		}
+		rules = requested
+	}
+
+	if cgroups.IsCgroup2UnifiedMode() {
+		insts, _, err := devicefilter.DeviceFilter(rules)
+		if err != nil {
+			return err
+		}
+		emu, err := cgroupdevices.EmulatorFromRules(rules)
+		if err != nil {
+			return err
+		}
+		defaultAction := "deny"
+		if emu.IsBlacklist() {
+			defaultAction = "allow"
+		}
+		clean, err := emu.Rules()
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, rule := range clean {
+			if rule.Type != devices.WildcardDevice {
+				n++
+			}
+		}
+		fmt.Fprintf(w, "device filter: %d eBPF instructions, %d rules, default %s\n", len(insts), n, defaultAction)
+	}
+	return nil
+}
+
+// printResourceTranslations writes how the resources r are applied on this
+// host's cgroup version, warning about the lossy conversions.
+func printResourceTranslations(w io.Writer, r *configs.Resources) {
+	unified := cgroups.IsCgroup2UnifiedMode()
+	version := "v1"
+	if unified {
+		version = "v2"
+	}
+	fmt.Fprintf(w, "resources on cgroup %s:\n", version)
+	ts := cgroups.TranslateResources(r, unified)
+	if len(ts) == 0 {
+		fmt.Fprintln(w, "\tno resources set")
+	}
+	for _, t := range ts {
+		fmt.Fprintf(w, "\t%s\n", t)
+		if t.Lossy {
+			logrus.Warnf("%s is not converted exactly for cgroup %s: %s", t.Field, version, t.Note)
+		}
+	}
+}
@@ -51,8 +51,21 @@ func logrusToStderr() bool {
 }
 
 // fatal prints the error's details if it is a libcontainer specific error type
-// then exits the program with an exit status of 1.
+// then exits the program with an exit status of 1. With the json log format,
+// the error is printed as a json object, along with its class, and the exit
+// status depends on the class (see errorCodes).
 func fatal(err error) {
+	if _, ok := logrus.StandardLogger().Formatter.(*logrus.JSONFormatter); ok {
+		code := getErrorCode(err)
+		logrus.WithField("code", code.name).Error(err)
+		if !logrusToStderr() {
+			l := logrus.New()
+			l.SetFormatter(logrus.StandardLogger().Formatter)
+			l.WithField("code", code.name).Error(err)
+		}
+		os.Exit(code.exitCode)
+	}
+
 	// make sure the error is written to the logger
 	logrus.Error(err)
 	if !logrusToStderr() {
@@ -45,10 +45,17 @@ func loadFactory(context *cli.Context) (libcontainer.Factory, error) {
 	if rootlessCg {
 		cgroupManager = libcontainer.RootlessCgroupfs
 	}
+	var systemdConn func(*libcontainer.LinuxFactory) error
 	if context.GlobalBool("systemd-cgroup") {
-		if !systemd.IsRunningSystemd() {
+		mode, err := systemd.ParseConnMode(context.GlobalString("systemd-connection"))
+		if err != nil {
+			return nil, err
+		}
+		fallback := context.GlobalBool("systemd-fallback")
+		if !fallback && !systemd.IsRunningSystemd() {
 			return nil, errors.New("systemd cgroup flag passed, but systemd support for managing cgroups is not available")
 		}
+		systemdConn = libcontainer.SystemdConnection(mode, fallback)
 		cgroupManager = libcontainer.SystemdCgroups
 		if rootlessCg {
 			cgroupManager = libcontainer.RootlessSystemdCgroups
@@ -69,7 +76,7 @@ func loadFactory(context *cli.Context) (libcontainer.Factory, error) {
 		newgidmap = ""
 	}
 
-	return libcontainer.New(abs, cgroupManager, intelRdtManager,
+	return libcontainer.New(abs, systemdConn, cgroupManager, intelRdtManager,
 		libcontainer.CriuPath(context.GlobalString("criu")),
 		libcontainer.NewuidmapPath(newuidmap),
 		libcontainer.NewgidmapPath(newgidmap))
@@ -226,6 +233,15 @@ func createContainer(context *cli.Context, id string, spec *specs.Spec) (libcont
 	if err != nil {
 		return nil, err
 	}
+	factory, err := loadFactory(context)
+	if err != nil {
+		return nil, err
+	}
+	if size := context.Int("auto-userns"); size > 0 {
+		if err := setAutoIDMappings(context, factory, spec, int64(size)); err != nil {
+			return nil, err
+		}
+	}
 	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
 		CgroupName:       id,
 		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
@@ -234,15 +250,12 @@ func createContainer(context *cli.Context, id string, spec *specs.Spec) (libcont
 		Spec:             spec,
 		RootlessEUID:     os.Geteuid() != 0,
 		RootlessCgroups:  rootlessCg,
+		DevicesAudit:     context.Bool("device-audit"),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	factory, err := loadFactory(context)
-	if err != nil {
-		return nil, err
-	}
 	return factory.Create(id, config)
 }
 
@@ -260,6 +273,7 @@ type runner struct {
 	notifySocket    *notifySocket
 	criuOpts        *libcontainer.CriuOpts
 	logLevel        string
+	unlockRoot      func()
 }
 
 func (r *runner) run(config *specs.Process) (int, error) {
@@ -320,6 +334,11 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	if r.unlockRoot != nil {
+		// The container's state has been saved, so other runc
+		// invocations can now see its id mappings.
+		r.unlockRoot()
+	}
 	if err = tty.waitConsole(); err != nil {
 		r.terminate(process)
 		return -1, err
@@ -410,6 +429,18 @@ func startContainer(context *cli.Context, spec *specs.Spec, action CtAct, criuOp
 		}
 	}
 
+	// With automatic id mappings, keep the root directory locked until the
+	// container's state (which records the chosen mappings) has been saved.
+	var unlockRoot func()
+	if context.Int("auto-userns") > 0 {
+		var err error
+		unlockRoot, err = lockRoot(context)
+		if err != nil {
+			return -1, err
+		}
+		defer unlockRoot()
+	}
+
 	container, err := createContainer(context, id, spec)
 	if err != nil {
 		return -1, err
@@ -451,6 +482,7 @@ func startContainer(context *cli.Context, spec *specs.Spec, action CtAct, criuOp
 		criuOpts:        criuOpts,
 		init:            true,
 		logLevel:        logLevel,
+		unlockRoot:      unlockRoot,
 	}
 	return r.run(spec.Process)
 }